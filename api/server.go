@@ -0,0 +1,118 @@
+// Package api serves the Crawler gRPC service defined in urusai.proto,
+// which lets other programs start crawls and follow them page by page.
+// Regenerate urusai.pb.go and urusai_grpc.pb.go after editing the proto
+// with protoc-gen-go and protoc-gen-go-grpc, paths=source_relative.
+package api
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/calpa/urusai/config"
+	"github.com/calpa/urusai/crawler"
+)
+
+// eventBuffer is how many events may wait for a slow client before the
+// crawl blocks on it.
+const eventBuffer = 64
+
+// Server implements CrawlerServer on top of crawler.Crawler.
+type Server struct {
+	UnimplementedCrawlerServer
+
+	cfg   *config.Config
+	setup func(*crawler.Crawler)
+}
+
+// NewServer returns a Server whose crawls run with cfg. setup, if not
+// nil, is applied to each new crawler before it starts, for settings
+// that are not part of the config such as a transport.
+func NewServer(cfg *config.Config, setup func(*crawler.Crawler)) *Server {
+	return &Server{cfg: cfg, setup: setup}
+}
+
+// StartCrawl runs a crawl for the call and streams its page events. The
+// call ends when the crawl does, with an Aborted status if it failed
+// rather than ran out of time, or when the client cancels it.
+func (s *Server) StartCrawl(req *StartCrawlRequest, stream Crawler_StartCrawlServer) error {
+	cfg := *s.cfg
+	if len(req.GetRootUrls()) > 0 {
+		cfg.RootURLs = req.GetRootUrls()
+	}
+	if req.GetTimeoutSeconds() > 0 {
+		cfg.Timeout = int(req.GetTimeoutSeconds())
+	}
+	if err := cfg.Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	c, err := crawler.NewCrawler(&cfg)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if s.setup != nil {
+		s.setup(c)
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	events := make(chan crawler.Event, eventBuffer)
+	c.SetEventHandler(func(e crawler.Event) {
+		select {
+		case events <- e:
+		case <-ctx.Done():
+		}
+	})
+	done := make(chan error, 1)
+	go func() { done <- c.Crawl(ctx) }()
+
+	for {
+		select {
+		case e := <-events:
+			if err := stream.Send(toEvent(e)); err != nil {
+				cancel()
+				<-done
+				return err
+			}
+		case err := <-done:
+			// Crawl has returned, so no more events arrive; flush those
+			// still buffered.
+			for len(events) > 0 {
+				if err := stream.Send(toEvent(<-events)); err != nil {
+					return err
+				}
+			}
+			return crawlStatus(stream.Context(), err)
+		}
+	}
+}
+
+// crawlStatus turns the error that ended a crawl into the call's status.
+func crawlStatus(ctx context.Context, err error) error {
+	switch {
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	case err == nil, errors.Is(err, crawler.ErrTimeLimit):
+		return nil
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		return status.Error(codes.Aborted, err.Error())
+	}
+}
+
+func toEvent(e crawler.Event) *Event {
+	ev := &Event{
+		Url:     e.URL,
+		Status:  int32(e.Status),
+		Latency: durationpb.New(e.Latency),
+		Depth:   int32(e.Depth),
+	}
+	if e.Err != nil {
+		ev.Error = e.Err.Error()
+	}
+	return ev
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/calpa/urusai/config"
+)
+
+// dialServer serves s over an in-process connection and returns a
+// client for it.
+func dialServer(t *testing.T, s *Server) CrawlerClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	RegisterCrawlerServer(gs, s)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewCrawlerClient(conn)
+}
+
+func TestStartCrawlStreamsEvents(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/a">a</a>`)
+		case "/a":
+			fmt.Fprint(w, "leaf")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{
+		MaxDepth:    3,
+		RootURLs:    []string{"https://unused.example"},
+		UserAgents:  []string{"urusai-test"},
+		ExitOnDrain: 1,
+	}
+	client := dialServer(t, NewServer(cfg, nil))
+	stream, err := client.StartCrawl(context.Background(), &StartCrawlRequest{RootUrls: []string{srv.URL + "/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []*Event
+	for {
+		e, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Expected the stream to end cleanly, got %v", err)
+		}
+		events = append(events, e)
+	}
+
+	// The root is fetched again by the iteration that finds nothing new.
+	want := []struct {
+		path  string
+		depth int32
+	}{{"/", 0}, {"/a", 1}}
+	if len(events) < len(want) {
+		t.Fatalf("Expected at least %d events, got %v", len(want), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.GetUrl() != srv.URL+w.path || e.GetStatus() != http.StatusOK || e.GetDepth() != w.depth || e.GetError() != "" {
+			t.Errorf("Expected event %d for %s (200, depth %d), got %v", i, w.path, w.depth, e)
+		}
+		if e.GetLatency().AsDuration() <= 0 {
+			t.Errorf("Expected a latency for %s, got %v", w.path, e.GetLatency())
+		}
+	}
+}
+
+func TestStartCrawlRequiresRoots(t *testing.T) {
+	cfg := &config.Config{MaxDepth: 3, UserAgents: []string{"urusai-test"}}
+	client := dialServer(t, NewServer(cfg, nil))
+	stream, err := client.StartCrawl(context.Background(), &StartCrawlRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without root URLs, got %v", err)
+	}
+}
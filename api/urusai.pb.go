@@ -0,0 +1,231 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: urusai.proto
+
+package api
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StartCrawlRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Root URLs to crawl in place of the config's root_urls. Empty keeps
+	// the config's.
+	RootUrls []string `protobuf:"bytes,1,rep,name=root_urls,json=rootUrls,proto3" json:"root_urls,omitempty"`
+	// Overall crawl timeout in seconds, in place of the config's timeout.
+	// 0 keeps the config's.
+	TimeoutSeconds int32 `protobuf:"varint,2,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *StartCrawlRequest) Reset() {
+	*x = StartCrawlRequest{}
+	mi := &file_urusai_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartCrawlRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartCrawlRequest) ProtoMessage() {}
+
+func (x *StartCrawlRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_urusai_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartCrawlRequest.ProtoReflect.Descriptor instead.
+func (*StartCrawlRequest) Descriptor() ([]byte, []int) {
+	return file_urusai_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StartCrawlRequest) GetRootUrls() []string {
+	if x != nil {
+		return x.RootUrls
+	}
+	return nil
+}
+
+func (x *StartCrawlRequest) GetTimeoutSeconds() int32 {
+	if x != nil {
+		return x.TimeoutSeconds
+	}
+	return 0
+}
+
+// Event describes one page visit.
+type Event struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Url   string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	// HTTP status; 0 when no response arrived.
+	Status int32 `protobuf:"varint,2,opt,name=status,proto3" json:"status,omitempty"`
+	// Time to fetch the page, without its assets.
+	Latency *durationpb.Duration `protobuf:"bytes,3,opt,name=latency,proto3" json:"latency,omitempty"`
+	// Link hops from the root, which is 0.
+	Depth int32 `protobuf:"varint,4,opt,name=depth,proto3" json:"depth,omitempty"`
+	// Why the fetch failed; empty on success.
+	Error         string `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_urusai_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_urusai_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_urusai_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Event) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Event) GetStatus() int32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *Event) GetLatency() *durationpb.Duration {
+	if x != nil {
+		return x.Latency
+	}
+	return nil
+}
+
+func (x *Event) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *Event) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_urusai_proto protoreflect.FileDescriptor
+
+const file_urusai_proto_rawDesc = "" +
+	"\n" +
+	"\furusai.proto\x12\turusai.v1\x1a\x1egoogle/protobuf/duration.proto\"Y\n" +
+	"\x11StartCrawlRequest\x12\x1b\n" +
+	"\troot_urls\x18\x01 \x03(\tR\brootUrls\x12'\n" +
+	"\x0ftimeout_seconds\x18\x02 \x01(\x05R\x0etimeoutSeconds\"\x92\x01\n" +
+	"\x05Event\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\x05R\x06status\x123\n" +
+	"\alatency\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\alatency\x12\x14\n" +
+	"\x05depth\x18\x04 \x01(\x05R\x05depth\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error2I\n" +
+	"\aCrawler\x12>\n" +
+	"\n" +
+	"StartCrawl\x12\x1c.urusai.v1.StartCrawlRequest\x1a\x10.urusai.v1.Event0\x01B\x1dZ\x1bgithub.com/calpa/urusai/apib\x06proto3"
+
+var (
+	file_urusai_proto_rawDescOnce sync.Once
+	file_urusai_proto_rawDescData []byte
+)
+
+func file_urusai_proto_rawDescGZIP() []byte {
+	file_urusai_proto_rawDescOnce.Do(func() {
+		file_urusai_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_urusai_proto_rawDesc), len(file_urusai_proto_rawDesc)))
+	})
+	return file_urusai_proto_rawDescData
+}
+
+var file_urusai_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_urusai_proto_goTypes = []any{
+	(*StartCrawlRequest)(nil),   // 0: urusai.v1.StartCrawlRequest
+	(*Event)(nil),               // 1: urusai.v1.Event
+	(*durationpb.Duration)(nil), // 2: google.protobuf.Duration
+}
+var file_urusai_proto_depIdxs = []int32{
+	2, // 0: urusai.v1.Event.latency:type_name -> google.protobuf.Duration
+	0, // 1: urusai.v1.Crawler.StartCrawl:input_type -> urusai.v1.StartCrawlRequest
+	1, // 2: urusai.v1.Crawler.StartCrawl:output_type -> urusai.v1.Event
+	2, // [2:3] is the sub-list for method output_type
+	1, // [1:2] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_urusai_proto_init() }
+func file_urusai_proto_init() {
+	if File_urusai_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_urusai_proto_rawDesc), len(file_urusai_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_urusai_proto_goTypes,
+		DependencyIndexes: file_urusai_proto_depIdxs,
+		MessageInfos:      file_urusai_proto_msgTypes,
+	}.Build()
+	File_urusai_proto = out.File
+	file_urusai_proto_goTypes = nil
+	file_urusai_proto_depIdxs = nil
+}
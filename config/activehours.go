@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultIdleFactor is the think-time multiplier applied outside active
+// hours when ActiveHours.IdleFactor is unset.
+const DefaultIdleFactor = 10
+
+// ActiveHours is a daily window of normal activity in local time.
+// Outside it the crawler keeps running but pauses IdleFactor times longer
+// between fetches.
+type ActiveHours struct {
+	Start      string   `json:"start"`       // "HH:MM"
+	End        string   `json:"end"`         // "HH:MM", before Start to span midnight
+	Days       []string `json:"days"`        // "mon".."sun" the window starts on; empty is every day
+	IdleFactor float64  `json:"idle_factor"` // default 10
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseHourMinute returns the offset of an "HH:MM" time from midnight.
+func parseHourMinute(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (a *ActiveHours) validate() error {
+	if _, err := parseHourMinute(a.Start); err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+	if _, err := parseHourMinute(a.End); err != nil {
+		return fmt.Errorf("end: %w", err)
+	}
+	for _, d := range a.Days {
+		if _, ok := weekdays[strings.ToLower(d)]; !ok {
+			return fmt.Errorf("days: unknown day %q", d)
+		}
+	}
+	if a.IdleFactor != 0 && a.IdleFactor < 1 {
+		return fmt.Errorf("idle_factor: must be at least 1, got %g", a.IdleFactor)
+	}
+	return nil
+}
+
+// Active reports whether t falls inside the window. A window spanning
+// midnight belongs to the day it starts on. Equal Start and End cover
+// the whole day. Unparseable times are never active; Validate rejects
+// them at load.
+func (a *ActiveHours) Active(t time.Time) bool {
+	start, err1 := parseHourMinute(a.Start)
+	end, err2 := parseHourMinute(a.End)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+	day := t.Weekday()
+
+	switch {
+	case start == end:
+	case start < end:
+		if offset < start || offset >= end {
+			return false
+		}
+	case offset >= start:
+		// Evening part of an overnight window.
+	case offset < end:
+		// Morning part: it started yesterday.
+		day = (day + 6) % 7
+	default:
+		return false
+	}
+	return a.onDay(day)
+}
+
+func (a *ActiveHours) onDay(day time.Weekday) bool {
+	if len(a.Days) == 0 {
+		return true
+	}
+	for _, d := range a.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Factor returns the think-time multiplier for t: 1 inside the window
+// and IdleFactor outside it.
+func (a *ActiveHours) Factor(t time.Time) float64 {
+	if a.Active(t) {
+		return 1
+	}
+	if a.IdleFactor == 0 {
+		return DefaultIdleFactor
+	}
+	return a.IdleFactor
+}
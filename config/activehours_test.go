@@ -0,0 +1,54 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActiveHours(t *testing.T) {
+	// 2024-05-06 is a Monday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, time.Local)
+	}
+	testCases := []struct {
+		name  string
+		hours ActiveHours
+		t     time.Time
+		want  bool
+	}{
+		{"Inside", ActiveHours{Start: "09:00", End: "17:30"}, at(6, 12, 0), true},
+		{"At start", ActiveHours{Start: "09:00", End: "17:30"}, at(6, 9, 0), true},
+		{"At end", ActiveHours{Start: "09:00", End: "17:30"}, at(6, 17, 30), false},
+		{"Before", ActiveHours{Start: "09:00", End: "17:30"}, at(6, 3, 0), false},
+		{"Overnight evening", ActiveHours{Start: "22:00", End: "06:00"}, at(6, 23, 0), true},
+		{"Overnight morning", ActiveHours{Start: "22:00", End: "06:00"}, at(6, 5, 59), true},
+		{"Overnight gap", ActiveHours{Start: "22:00", End: "06:00"}, at(6, 12, 0), false},
+		{"Weekday", ActiveHours{Start: "09:00", End: "17:00", Days: []string{"mon"}}, at(6, 10, 0), true},
+		{"Weekend", ActiveHours{Start: "09:00", End: "17:00", Days: []string{"mon"}}, at(5, 10, 0), false},
+		{"Overnight from Friday", ActiveHours{Start: "22:00", End: "02:00", Days: []string{"Fri"}}, at(11, 1, 0), true},
+		{"Overnight from Saturday", ActiveHours{Start: "22:00", End: "02:00", Days: []string{"Fri"}}, at(12, 1, 0), false},
+		{"All day", ActiveHours{Start: "00:00", End: "00:00"}, at(6, 4, 0), true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.hours.Active(tc.t); got != tc.want {
+				t.Errorf("Expected active=%v at %v, got %v", tc.want, tc.t, got)
+			}
+		})
+	}
+}
+
+func TestActiveHoursFactor(t *testing.T) {
+	a := &ActiveHours{Start: "09:00", End: "17:00"}
+	night := time.Date(2024, 5, 6, 2, 0, 0, 0, time.Local)
+	if got := a.Factor(night); got != DefaultIdleFactor {
+		t.Errorf("Expected default idle factor %d, got %g", DefaultIdleFactor, got)
+	}
+	a.IdleFactor = 4
+	if got := a.Factor(night); got != 4 {
+		t.Errorf("Expected idle factor 4, got %g", got)
+	}
+	if got := a.Factor(night.Add(8 * time.Hour)); got != 1 {
+		t.Errorf("Expected factor 1 inside active hours, got %g", got)
+	}
+}
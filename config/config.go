@@ -3,7 +3,11 @@ package config
 import (
 	"embed"
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
 )
 
 //go:embed default_config.json
@@ -16,21 +20,482 @@ type Config struct {
 	MaxSleep        int      `json:"max_sleep"`
 	Timeout         int      `json:"timeout"`
 	RootURLs        []string `json:"root_urls"`
+	RootTemplates   []string `json:"root_templates"` // expanded into RootURLs at load
 	BlacklistedURLs []string `json:"blacklisted_urls"`
 	UserAgents      []string `json:"user_agents"`
+
+	// BlacklistWeights turns URL substrings into soft blacklist entries:
+	// a queued link containing one is dropped with the given probability,
+	// 0 to 1. Entries also in BlacklistedURLs take their weight from here;
+	// the others in BlacklistedURLs keep blocking outright.
+	BlacklistWeights map[string]float64 `json:"blacklist_weights"`
+
+	// MaxHostLatency drops links to hosts whose rolling average fetch
+	// latency exceeds it, in milliseconds. 0 disables the check.
+	MaxHostLatency    int `json:"max_host_latency"`
+	HostLatencyWindow int `json:"host_latency_window"` // samples averaged per host
+
+	// MaxInFlightBytes caps the response body bytes held by concurrent
+	// fetches. 0 disables the guard.
+	MaxInFlightBytes int64 `json:"max_in_flight_bytes"`
+
+	// HTTPSRatio is the probability that a protocol-relative ("//host")
+	// link resolves to https. When unset the page's own scheme is used.
+	HTTPSRatio *float64 `json:"https_ratio"`
+
+	// UpgradeToHTTPS fetches http:// links over https first, falling
+	// back to http on hosts that do not answer over TLS.
+	UpgradeToHTTPS bool `json:"upgrade_to_https"`
+
+	// Per-phase transport timeouts in milliseconds; 0 keeps Go's defaults.
+	DialTimeout           int `json:"dial_timeout"`
+	TLSHandshakeTimeout   int `json:"tls_handshake_timeout"`
+	ResponseHeaderTimeout int `json:"response_header_timeout"`
+	ExpectContinueTimeout int `json:"expect_continue_timeout"`
+
+	// RequestTimeout bounds a whole request in milliseconds, from dial
+	// to the last body byte, so a server that sends headers and then
+	// stalls cannot hold a fetch any longer. 0 keeps the 5 s default.
+	RequestTimeout int `json:"request_timeout"`
+
+	// DialNetwork restricts connections to "tcp4" (IPv4) or "tcp6"
+	// (IPv6). Empty or "tcp" uses either.
+	DialNetwork string `json:"dial_network"`
+
+	// MaxConcurrentDNS bounds the DNS lookups in flight at once. 0 is
+	// unlimited.
+	MaxConcurrentDNS int `json:"max_concurrent_dns"`
+
+	// HostAliases maps host names to the IP addresses to connect to in
+	// their place, like /etc/hosts. Host headers and TLS server names
+	// keep the original name.
+	HostAliases map[string]string `json:"host_aliases"`
+
+	// ForceHTTPVersion pins the protocol: "1.0", "1.1" or "2".
+	// Empty auto-negotiates.
+	ForceHTTPVersion string `json:"force_http_version"`
+
+	// MinLinksToDescend ends a branch at pages yielding fewer new links,
+	// so the walk favours substantive pages. 0 always descends.
+	MinLinksToDescend int `json:"min_links_to_descend"`
+
+	// FrontierMode crawls each root breadth first: every link of one
+	// depth is visited before any link they lead to, instead of walking
+	// a single random branch.
+	FrontierMode bool `json:"frontier_mode"`
+
+	// MaxWorkers visits queued links in parallel, on one worker per four
+	// links waiting up to this many, so workers are added as links pile
+	// up and retire as the queue drains. A branch then visits every
+	// queued link within MaxDepth hops rather than following one, and
+	// FrontierMode runs each level this way. 0 or 1 visits links one at
+	// a time.
+	MaxWorkers int `json:"max_workers"`
+
+	// ResetDepthOnHostChange restarts a branch's depth count at
+	// HostChangeDepth whenever it follows a link to another host, so
+	// hosts discovered deep in a branch are explored too.
+	ResetDepthOnHostChange bool `json:"reset_depth_on_host_change"`
+	HostChangeDepth        int  `json:"host_change_depth"`
+
+	// MaxRecursion caps how many pages one branch walk visits, however
+	// its depth is counted, as a guard against runaway recursion. 0 uses
+	// the default of 10000.
+	MaxRecursion int `json:"max_recursion"`
+
+	// MaxBranchDuration abandons a branch walk once this many
+	// milliseconds have passed since its root was picked, so a chain of
+	// slow pages cannot hold the crawl. 0 disables the limit.
+	MaxBranchDuration int `json:"max_branch_duration"`
+
+	// DescendProbability is the chance of following a branch one level
+	// deeper after each page, giving geometrically distributed depths
+	// below MaxDepth. When unset branches always run to MaxDepth.
+	DescendProbability *float64 `json:"descend_probability"`
+
+	// LinkSampleRate keeps each acceptable link of a page with this
+	// probability, so only a random share of them is queued. When unset
+	// every link is kept.
+	LinkSampleRate *float64 `json:"link_sample_rate"`
+
+	// StartupSplay delays the first fetch by a random duration of up to
+	// this many milliseconds to desynchronise fleets started together.
+	StartupSplay int `json:"startup_splay"`
+
+	// Seed seeds every random choice of the crawler. 0 picks a fresh
+	// seed for each run.
+	Seed int64 `json:"seed"`
+
+	// DeterministicOrder sorts each page's links before queueing them and
+	// loads assets one at a time, so runs with the same Seed, config and
+	// responses make the same requests in the same order.
+	DeterministicOrder bool `json:"deterministic_order"`
+
+	// WarmupConnections sends one HEAD request to each root host before
+	// the crawl starts, so measurements begin on established connections.
+	WarmupConnections bool `json:"warmup_connections"`
+
+	// ProbeRootsOnStartup checks each root once before the crawl and
+	// drops those that are unreachable or answer with an error status.
+	// With ProbeRootsFailFast, finding none alive ends the run instead.
+	ProbeRootsOnStartup bool `json:"probe_roots_on_startup"`
+	ProbeRootsFailFast  bool `json:"probe_roots_fail_fast"`
+
+	// BrowserProfile sends a realistic header bundle for "chrome",
+	// "firefox" or "safari" alongside a matching user agent.
+	BrowserProfile string `json:"browser_profile"`
+
+	// RandomizeUAVersion treats user agents as templates, replacing each
+	// "{rand}" with a random build number and each "{min-max}" with a
+	// random number in that range whenever one is picked.
+	RandomizeUAVersion bool `json:"randomize_ua_version"`
+
+	// RandomizeHeaderCase spells each request header name with a casing
+	// drawn once per run, such as "uSer-AgeNt", instead of Go's canonical
+	// form. HTTP/2 lowercases header names, so it only shows over HTTP/1.
+	RandomizeHeaderCase bool `json:"randomize_header_case"`
+
+	// MaxURLLength rejects links and redirect targets longer than this
+	// many bytes. 0 uses the default of 2048.
+	MaxURLLength int `json:"max_url_length"`
+
+	// MaxQueueSize bounds the link queue; once full, the oldest queued
+	// links are dropped to make room for new ones. 0 is unbounded.
+	MaxQueueSize int `json:"max_queue_size"`
+
+	// MaxLinkAge drops queued links that have waited longer than this
+	// many milliseconds when they come up to be visited, so a long walk
+	// follows what it found recently. 0 keeps links until visited.
+	MaxLinkAge int `json:"max_link_age"`
+
+	// MinRevisitInterval is a floor, in milliseconds, between two
+	// fetches of the same URL, whatever the dedup settings or backend
+	// let through: links fetched more recently are not queued, and a
+	// root picked again waits. 0 disables it.
+	MinRevisitInterval int `json:"min_revisit_interval"`
+
+	// Client certificate and key (PEM) for mutual TLS, and an optional
+	// PEM bundle replacing the system roots for server verification.
+	ClientCertFile string `json:"client_cert_file"`
+	ClientKeyFile  string `json:"client_key_file"`
+	CACertFile     string `json:"ca_cert_file"`
+
+	// CookieFile names a Netscape cookies.txt, such as one exported from
+	// a browser, whose cookies are sent to matching hosts. Cookies the
+	// sites set in reply are then kept for the rest of the run.
+	CookieFile string `json:"cookie_file"`
+
+	// TLSFingerprint selects a browser-like TLS ClientHello: "chrome",
+	// "firefox" or "random". Empty uses Go's own handshake. The
+	// handshake is made with uTLS and offers only HTTP/1.1.
+	TLSFingerprint string `json:"tls_fingerprint"`
+
+	// MaxErrors aborts the crawl with an error once more than this many
+	// requests have failed. 0 never aborts.
+	MaxErrors int `json:"max_errors"`
+
+	// MaxTotalBytes aborts the crawl with an error once the response
+	// bodies fetched add up to this many bytes. 0 never aborts.
+	MaxTotalBytes int64 `json:"max_total_bytes"`
+
+	// MaxHosts stops queueing links to new hosts once this many distinct
+	// hosts have answered; known hosts are still followed. 0 is unlimited.
+	MaxHosts int `json:"max_hosts"`
+
+	// PreferNewHosts picks the next link among those to hosts that have
+	// not answered yet whenever there are any, for broad host coverage.
+	PreferNewHosts bool `json:"prefer_new_hosts"`
+
+	// DiscoveredHostsFile receives the sorted list of every host that
+	// answered a request, written when the crawl ends.
+	DiscoveredHostsFile string `json:"discovered_hosts_file"`
+
+	// GraphFile receives the link graph of the crawl in Graphviz DOT,
+	// an edge from every fetched page to each link it yielded, written
+	// when the crawl ends. MaxGraphNodes caps its URLs, default 500.
+	GraphFile     string `json:"graph_file"`
+	MaxGraphNodes int    `json:"max_graph_nodes"`
+
+	// AdaptiveRate enables per-host AIMD pacing when set.
+	AdaptiveRate *AdaptiveRate `json:"adaptive_rate"`
+
+	// GlobalRequestsPerSecond spaces all requests, across every host, at
+	// most this many per second. 0 is unlimited.
+	GlobalRequestsPerSecond float64 `json:"global_requests_per_second"`
+
+	// RampUpDuration raises GlobalRequestsPerSecond linearly from a tenth
+	// to its full value over this many milliseconds from the start of the
+	// crawl. 0 starts at full rate.
+	RampUpDuration int `json:"ramp_up_duration"`
+
+	// PathPrefixes restricts queued links to URLs whose path starts with
+	// one of the prefixes. HostPathPrefixes overrides it for the hosts it
+	// lists. Both empty allow every path.
+	PathPrefixes     []string            `json:"path_prefixes"`
+	HostPathPrefixes map[string][]string `json:"host_path_prefixes"`
+
+	// MaxPathDepth rejects links whose path has more than this many
+	// segments beyond their host's root, so /a/b passes at 2 under a
+	// root of / but /a/b/c does not. Unlike MaxDepth it ignores how many
+	// hops led there. 0 disables the limit.
+	MaxPathDepth int `json:"max_path_depth"`
+
+	// HostProfiles overrides the think time and caps the request rate
+	// for the hosts it lists, keyed by host or host:port.
+	HostProfiles map[string]HostProfile `json:"host_profiles"`
+
+	// DelaySamplesFile lists recorded think times, one per line in Go
+	// duration syntax such as "850ms", and each pause after a page is
+	// drawn from them rather than from MinSleep and MaxSleep. Host
+	// profiles with their own sleeps keep them.
+	DelaySamplesFile string `json:"delay_samples_file"`
+
+	// HostOverrides adds headers and cookies to requests for the hosts it
+	// lists, keyed by host or host:port. Values are never logged.
+	HostOverrides map[string]HostOverride `json:"host_overrides"`
+
+	// Auth holds credentials per host or host:port, sent only once the
+	// host answers 401 with a challenge they fit: Basic for Username and
+	// Password, Bearer for Token. Values are never logged.
+	Auth map[string]HostAuth `json:"auth"`
+
+	// IgnoreQueryParams drops query strings from URLs before dedup, so
+	// links differing only in their parameters are fetched once, except
+	// for the parameters named in SignificantQueryParams.
+	IgnoreQueryParams      bool     `json:"ignore_query_params"`
+	SignificantQueryParams []string `json:"significant_query_params"`
+
+	// URLRewrites are applied in order to every outgoing request URL;
+	// the queue and dedup keep the URL as discovered.
+	URLRewrites []URLRewrite `json:"url_rewrites"`
+
+	// CacheBust is the share of requests, 0 to 1, sent with a random
+	// "_" query parameter to bypass caches. Dedup uses the plain URL.
+	CacheBust float64 `json:"cache_bust"`
+
+	// FaultInjection is the share of fetches, 0 to 1, sabotaged on
+	// purpose to exercise error handling and monitoring. A sabotaged
+	// fetch fails without touching the network or, when FaultDelay is
+	// set, is equally likely to be held for up to FaultDelay
+	// milliseconds before going ahead. Off unless set.
+	FaultInjection float64 `json:"fault_injection"`
+	FaultDelay     int     `json:"fault_delay"`
+
+	// SessionDuration and PauseBetweenSessions, in milliseconds, split
+	// the crawl into sessions of activity separated by idle pauses, like
+	// a person browsing. Either 0 crawls continuously.
+	SessionDuration      int `json:"session_duration"`
+	PauseBetweenSessions int `json:"pause_between_sessions"`
+
+	// ActiveHours slows the crawl outside a daily window of local time.
+	ActiveHours *ActiveHours `json:"active_hours"`
+
+	// RealisticAssetTiming fetches the images, scripts and stylesheets of
+	// every HTML page in a short concurrent burst before the think-time
+	// pause, as a browser would.
+	RealisticAssetTiming bool `json:"realistic_asset_timing"`
+
+	// FollowAlternates also queues the variants a page lists with
+	// <link rel="alternate">, such as its hreflang translations.
+	FollowAlternates bool `json:"follow_alternates"`
+
+	// ScanDataAttributes also queues the URLs that elements carry in
+	// data-href or data-url, or navigate to from an onclick handler
+	// (location.href = '...'), for sites that script their navigation.
+	ScanDataAttributes bool `json:"scan_data_attributes"`
+
+	// ObeyNofollow skips every link of a page carrying
+	// <meta name="robots" content="nofollow"> and, elsewhere, each
+	// anchor marked rel="nofollow".
+	ObeyNofollow bool `json:"obey_nofollow"`
+
+	// BlockMixedContent skips http links and assets found on https
+	// pages, as browsers block mixed content.
+	BlockMixedContent bool `json:"block_mixed_content"`
+
+	// StreamLinks queues the links of an HTML page as the tokenizer
+	// reaches them while the body downloads, instead of once it is
+	// complete. The body cap still bounds what is parsed.
+	StreamLinks bool `json:"stream_links"`
+
+	// RenderJS loads each HTML page in headless Chrome as well and takes
+	// its links from the rendered DOM, for sites that build their links
+	// with JavaScript. ChromePath names the browser binary, looked up on
+	// PATH when empty; RenderBudget is how many milliseconds of page time
+	// scripts get to run before the DOM is read, default 5000. Chrome
+	// fetches the page again itself, outside the crawler's transport and
+	// pacing.
+	RenderJS     bool   `json:"render_js"`
+	ChromePath   string `json:"chrome_path"`
+	RenderBudget int    `json:"render_budget"`
+
+	// AllowFileURLs lets roots and links use file:// URLs, read from the
+	// local filesystem. Any page can then link to local files.
+	AllowFileURLs bool `json:"allow_file_urls"`
+
+	// DedupByContent skips link extraction for pages whose body is
+	// identical to one already parsed, e.g. one page under many query
+	// strings.
+	DedupByContent bool `json:"dedup_by_content"`
+
+	// FollowJSONLinks extracts links from JSON responses using the HAL
+	// (_links) and JSON:API (links) conventions.
+	FollowJSONLinks bool `json:"follow_json_links"`
+
+	// IdleTimeout aborts the crawl when no fetch has succeeded for this
+	// many milliseconds. It runs alongside Timeout. 0 disables it.
+	IdleTimeout int `json:"idle_timeout"`
+
+	// StopAt, an RFC 3339 time, ends the crawl at that wall-clock
+	// instant so a fleet of instances can stop together. It runs
+	// alongside Timeout; whichever comes first ends the crawl.
+	StopAt string `json:"stop_at"`
+
+	// Schedule, a cron expression such as "0 9 * * 1-5", keeps the
+	// process running and starts a fresh crawl each time it fires, each
+	// one bounded by Timeout. Firings during a crawl are skipped.
+	Schedule string `json:"schedule"`
+
+	// GRPCAddr ("host:port") turns the process into a service: instead of
+	// crawling it listens there for gRPC StartCrawl calls, each running a
+	// fresh crawl with this config and streaming its page events back.
+	GRPCAddr string `json:"grpc_addr"`
+
+	// ExitOnDrain ends the crawl cleanly after this many consecutive
+	// iterations visit no new URL. 0 crawls until the timeout.
+	ExitOnDrain int `json:"exit_on_drain"`
+
+	// RootsPerIteration is how many distinct random roots each
+	// iteration fetches, merging their links into one queue before the
+	// branch starts. 0 or 1 fetches a single root.
+	RootsPerIteration int `json:"roots_per_iteration"`
+
+	// StatsDAddr ("host:port") receives request, error and latency
+	// metrics over UDP. StatsDPrefix defaults to "urusai."; StatsDTags
+	// ("key:value") are attached in DogStatsD form.
+	StatsDAddr   string   `json:"statsd_addr"`
+	StatsDPrefix string   `json:"statsd_prefix"`
+	StatsDTags   []string `json:"statsd_tags"`
+
+	// LogFile additionally writes logs to this size-rotated file.
+	LogFile       string `json:"log_file"`
+	LogMaxSize    int    `json:"log_max_size"`    // megabytes before rotating, default 100
+	LogMaxBackups int    `json:"log_max_backups"` // rotated files kept, 0 = all
+	LogMaxAge     int    `json:"log_max_age"`     // days rotated files are kept, 0 = forever
+
+	// LogHeaders logs the headers of every request and response when
+	// running at debug level, with credentials redacted.
+	LogHeaders bool `json:"log_headers"`
+
+	// VisitedBackend stores the visited set: "memory" (the default) or
+	// "redis", which shares it between instances through Redis.
+	VisitedBackend string `json:"visited_backend"`
+	Redis          *Redis `json:"redis"`
+
+	// VisitedFilter replaces the exact in-memory visited set with a bloom
+	// filter of fixed size. Some unvisited URLs are then skipped as seen.
+	VisitedFilter *VisitedFilter `json:"visited_filter"`
+
+	// QueueBackend holds the queue of links waiting to be visited:
+	// "memory" (the default) or "redis", which shares it between
+	// instances through Redis so they split one crawl between them.
+	QueueBackend string `json:"queue_backend"`
+
+	// Profiles holds named partial configs, each merged over the
+	// top-level fields when selected with LoadProfile.
+	Profiles map[string]json.RawMessage `json:"profiles,omitempty"`
+}
+
+// AdaptiveRate tunes the per-host AIMD rate controller. Every fast,
+// successful response adds Increase to a host's rate; responses slower
+// than TargetLatency, requests without a response, 429 and 503 multiply
+// it by Decrease.
+// Zero fields take the crawler's defaults.
+type AdaptiveRate struct {
+	MinRate       float64 `json:"min_rate"`       // requests per second
+	MaxRate       float64 `json:"max_rate"`       // requests per second
+	Increase      float64 `json:"increase"`       // additive step, requests per second
+	Decrease      float64 `json:"decrease"`       // multiplicative factor in (0, 1)
+	TargetLatency int     `json:"target_latency"` // milliseconds
+}
+
+// Redis locates the Redis server holding a shared visited set or link
+// queue.
+type Redis struct {
+	Addr     string `json:"addr"`      // host:port
+	Password string `json:"password"`  // sent with AUTH when set
+	DB       int    `json:"db"`        // database selected after connecting
+	Key      string `json:"key"`       // set key, default "urusai:visited"
+	QueueKey string `json:"queue_key"` // queue set key, default "urusai:queue"
+	Timeout  int    `json:"timeout"`   // per-command milliseconds, default 1000
+}
+
+// VisitedFilter sizes a bloom-filter visited set.
+type VisitedFilter struct {
+	ExpectedItems     int     `json:"expected_items"`      // URLs the filter is sized for
+	FalsePositiveRate float64 `json:"false_positive_rate"` // in (0, 1) at ExpectedItems
+}
+
+// HostProfile paces one host. Unset sleeps keep the global MinSleep and
+// MaxSleep; RequestsPerSecond 0 leaves the rate unlimited.
+type HostProfile struct {
+	MinSleep          *int    `json:"min_sleep"`
+	MaxSleep          *int    `json:"max_sleep"`
+	RequestsPerSecond float64 `json:"requests_per_second"`
+}
+
+// HostOverride carries the headers and cookies sent to one host. A
+// header named here replaces the global value of the same name.
+type HostOverride struct {
+	Headers map[string]string `json:"headers"`
+	Cookies map[string]string `json:"cookies"`
+}
+
+// HostAuth holds the credentials for one host: Username and Password
+// answer Basic challenges, Token answers Bearer ones.
+type HostAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Token    string `json:"token"`
+}
+
+// URLRewrite replaces matches of the regular expression Pattern with
+// Replace, which may reference capture groups as $1 or ${name}.
+type URLRewrite struct {
+	Pattern string `json:"pattern"`
+	Replace string `json:"replace"`
 }
 
 // LoadFromFile loads configuration from a JSON file
 func LoadFromFile(filePath string) (*Config, error) {
+	return LoadProfile(filePath, "")
+}
+
+// LoadProfile loads configuration from a JSON file and applies the named
+// profile from its "profiles" section. An empty name selects the
+// "default" profile when there is one, or the top-level fields otherwise.
+// overrides are applied as Parse does.
+func LoadProfile(filePath, profile string, overrides ...func(*Config)) (*Config, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	decoder := json.NewDecoder(file)
+	return Parse(file, profile, overrides...)
+}
+
+// Parse decodes a JSON configuration from r, applies the named profile
+// as LoadProfile does, expands root templates and validates the result.
+// overrides run just before validation, so settings taken from elsewhere,
+// such as command-line flags, are checked along with the file's.
+func Parse(r io.Reader, profile string, overrides ...func(*Config)) (*Config, error) {
 	config := &Config{}
-	err = decoder.Decode(config)
+	if err := json.NewDecoder(r).Decode(config); err != nil {
+		return nil, err
+	}
+
+	config, err := config.withProfile(profile)
 	if err != nil {
 		return nil, err
 	}
@@ -40,6 +505,18 @@ func LoadFromFile(filePath string) (*Config, error) {
 		config.Timeout = 0
 	}
 
+	if err := config.expandRootTemplates(); err != nil {
+		return nil, err
+	}
+
+	for _, override := range overrides {
+		override(config)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
@@ -56,5 +533,44 @@ func LoadDefaultConfig() (*Config, error) {
 		return nil, err
 	}
 
+	if err := config.expandRootTemplates(); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
+
+// DefaultProfile is selected when no profile name is given and the
+// config file defines a profile with this name.
+const DefaultProfile = "default"
+
+// withProfile returns the configuration produced by merging the named
+// profile over the top-level fields. Fields the profile omits keep their
+// top-level values.
+func (c *Config) withProfile(name string) (*Config, error) {
+	if name == "" {
+		if _, ok := c.Profiles[DefaultProfile]; !ok {
+			c.Profiles = nil
+			return c, nil
+		}
+		name = DefaultProfile
+	}
+
+	raw, ok := c.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(c.Profiles))
+		for n := range c.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(names, ", "))
+	}
+
+	merged := *c
+	merged.Profiles = nil
+	if err := json.Unmarshal(raw, &merged); err != nil {
+		return nil, fmt.Errorf("profile %q: %w", name, err)
+	}
+	merged.Profiles = nil
+	return &merged, nil
+}
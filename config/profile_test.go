@@ -0,0 +1,91 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const profilesJSON = `{
+	"max_depth": 10,
+	"min_sleep": 3,
+	"max_sleep": 6,
+	"root_urls": ["https://top.example"],
+	"user_agents": ["top-agent"],
+	"profiles": {
+		"aggressive": {"max_depth": 50, "min_sleep": 0, "max_sleep": 1},
+		"gentle": {"max_sleep": 60, "root_urls": ["https://gentle.example"]}
+	}
+}`
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadProfile(t *testing.T) {
+	path := writeConfig(t, profilesJSON)
+
+	testCases := []struct {
+		profile  string
+		depth    int
+		minSleep int
+		maxSleep int
+		roots    []string
+	}{
+		{"", 10, 3, 6, []string{"https://top.example"}},
+		{"aggressive", 50, 0, 1, []string{"https://top.example"}},
+		{"gentle", 10, 3, 60, []string{"https://gentle.example"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.profile, func(t *testing.T) {
+			cfg, err := LoadProfile(path, tc.profile)
+			if err != nil {
+				t.Fatalf("LoadProfile(%q): %v", tc.profile, err)
+			}
+			if cfg.MaxDepth != tc.depth || cfg.MinSleep != tc.minSleep || cfg.MaxSleep != tc.maxSleep {
+				t.Errorf("Expected depth/min/max %d/%d/%d, got %d/%d/%d",
+					tc.depth, tc.minSleep, tc.maxSleep, cfg.MaxDepth, cfg.MinSleep, cfg.MaxSleep)
+			}
+			if !reflect.DeepEqual(cfg.RootURLs, tc.roots) {
+				t.Errorf("Expected roots %v, got %v", tc.roots, cfg.RootURLs)
+			}
+			if !reflect.DeepEqual(cfg.UserAgents, []string{"top-agent"}) {
+				t.Errorf("Expected user agents inherited from top level, got %v", cfg.UserAgents)
+			}
+			if cfg.Profiles != nil {
+				t.Error("Expected profiles to be cleared from the merged config")
+			}
+		})
+	}
+}
+
+func TestLoadProfileDefault(t *testing.T) {
+	path := writeConfig(t, `{
+		"max_depth": 10,
+		"root_urls": ["https://top.example"],
+		"user_agents": ["ua"],
+		"profiles": {"default": {"max_depth": 2}}
+	}`)
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxDepth != 2 {
+		t.Errorf("Expected default profile to apply, got MaxDepth %d", cfg.MaxDepth)
+	}
+}
+
+func TestLoadProfileUnknown(t *testing.T) {
+	path := writeConfig(t, profilesJSON)
+	if _, err := LoadProfile(path, "missing"); err == nil {
+		t.Error("Expected error for unknown profile")
+	}
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Provider is a source of configuration. The file and URL providers ship
+// with urusai; other backends, such as a KV store, can be plugged in by
+// implementing Load, typically by fetching the JSON document and passing
+// it to Parse.
+type Provider interface {
+	Load(ctx context.Context) (*Config, error)
+}
+
+// Watcher is implemented by providers that can report configuration
+// changes. Watch calls onChange with every new configuration, or the
+// error that prevented loading it, until ctx ends.
+type Watcher interface {
+	Watch(ctx context.Context, onChange func(*Config, error)) error
+}
+
+// maxRemoteConfigBytes caps the size of a configuration fetched by
+// URLProvider.
+const maxRemoteConfigBytes = 1 << 20
+
+// defaultPollInterval is how often FileProvider.Watch checks the file
+// when PollInterval is unset.
+const defaultPollInterval = 5 * time.Second
+
+// NewProvider returns a URLProvider for http and https sources and a
+// FileProvider otherwise. overrides are applied to every config loaded.
+func NewProvider(source, profile string, overrides ...func(*Config)) Provider {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return &URLProvider{URL: source, Profile: profile, Overrides: overrides}
+	}
+	return &FileProvider{Path: source, Profile: profile, Overrides: overrides}
+}
+
+// FileProvider loads a JSON configuration file, applying Profile as
+// LoadProfile does.
+type FileProvider struct {
+	Path         string
+	Profile      string
+	PollInterval time.Duration   // Watch polling period, default 5s
+	Overrides    []func(*Config) // applied before validation, as Parse does
+}
+
+// Load implements Provider.
+func (p *FileProvider) Load(_ context.Context) (*Config, error) {
+	return LoadProfile(p.Path, p.Profile, p.Overrides...)
+}
+
+// Watch implements Watcher by polling the file's modification time and
+// size. It returns nil when ctx ends.
+func (p *FileProvider) Watch(ctx context.Context, onChange func(*Config, error)) error {
+	interval := p.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	last, err := os.Stat(p.Path)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		info, err := os.Stat(p.Path)
+		if err != nil {
+			onChange(nil, err)
+			continue
+		}
+		if info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+		onChange(p.Load(ctx))
+	}
+}
+
+// URLProvider fetches a JSON configuration over HTTP(S), applying
+// Profile as LoadProfile does.
+type URLProvider struct {
+	URL       string
+	Profile   string
+	Client    *http.Client    // http.DefaultClient when nil
+	Overrides []func(*Config) // applied before validation, as Parse does
+}
+
+// Load implements Provider.
+func (p *URLProvider) Load(ctx context.Context) (*Config, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch config %s: %s", p.URL, resp.Status)
+	}
+	return Parse(io.LimitReader(resp.Body, maxRemoteConfigBytes), p.Profile, p.Overrides...)
+}
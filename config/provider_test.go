@@ -0,0 +1,109 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNewProvider(t *testing.T) {
+	if _, ok := NewProvider("https://cfg.example/urusai.json", "").(*URLProvider); !ok {
+		t.Error("Expected a URLProvider for an https source")
+	}
+	if _, ok := NewProvider("config.json", "").(*FileProvider); !ok {
+		t.Error("Expected a FileProvider for a path")
+	}
+}
+
+func TestFileProviderLoad(t *testing.T) {
+	p := NewProvider(writeConfig(t, profilesJSON), "aggressive")
+	cfg, err := p.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxDepth != 50 {
+		t.Errorf("Expected profile max_depth 50, got %d", cfg.MaxDepth)
+	}
+}
+
+func TestProviderOverridesBeforeValidation(t *testing.T) {
+	path := writeConfig(t, `{"root_urls": ["https://a.example"], "user_agents": ["ua"], "schedule": "0 * * * *"}`)
+	if _, err := NewProvider(path, "").Load(context.Background()); err == nil {
+		t.Fatal("Expected schedule without timeout to be rejected")
+	}
+
+	withTimeout := func(c *Config) { c.Timeout = 60 }
+	cfg, err := NewProvider(path, "", withTimeout).Load(context.Background())
+	if err != nil {
+		t.Fatalf("Expected the override to satisfy schedule, got %v", err)
+	}
+	if cfg.Timeout != 60 {
+		t.Errorf("Expected the overridden timeout 60, got %d", cfg.Timeout)
+	}
+}
+
+func TestURLProviderLoad(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/urusai.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(profilesJSON))
+	}))
+	defer srv.Close()
+
+	cfg, err := NewProvider(srv.URL+"/urusai.json", "gentle").Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxSleep != 60 || cfg.RootURLs[0] != "https://gentle.example" {
+		t.Errorf("Expected the gentle profile, got max_sleep %d and roots %v", cfg.MaxSleep, cfg.RootURLs)
+	}
+
+	if _, err := NewProvider(srv.URL+"/missing.json", "").Load(context.Background()); err == nil {
+		t.Error("Expected an error for a 404 response")
+	}
+}
+
+func TestFileProviderWatch(t *testing.T) {
+	path := writeConfig(t, profilesJSON)
+	p := &FileProvider{Path: path, PollInterval: 5 * time.Millisecond}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	changes := make(chan *Config, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Watch(ctx, func(cfg *Config, err error) {
+			if err == nil {
+				select {
+				case changes <- cfg:
+				default:
+				}
+			}
+		})
+	}()
+
+	updated := `{"max_depth": 3, "root_urls": ["https://new.example"], "user_agents": ["x"]}`
+	time.Sleep(20 * time.Millisecond)
+	if err := os.WriteFile(path, []byte(updated), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case cfg := <-changes:
+		if cfg.MaxDepth != 3 {
+			t.Errorf("Expected reloaded max_depth 3, got %d", cfg.MaxDepth)
+		}
+	case <-ctx.Done():
+		t.Fatal("Expected Watch to report the rewritten file")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected Watch to return nil when ctx ends, got %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxTemplateExpansion bounds the total number of roots generated from
+// RootTemplates so a typo like {1..100000000} cannot exhaust memory.
+const MaxTemplateExpansion = 100000
+
+// ExpandTemplate expands a root template into concrete URLs.
+//
+// Each {...} group in the template is replaced by every value it
+// describes, producing the cartesian product of all groups:
+//
+//	{1..3}      numeric range: 1, 2, 3
+//	{01..10}    zero-padded range: 01, 02, ... 10
+//	{a,b,c}     list: a, b, c
+//
+// An error is returned if the template is malformed or would expand to
+// more than limit URLs.
+func ExpandTemplate(tpl string, limit int) ([]string, error) {
+	out := []string{""}
+	rest := tpl
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("template %q: unterminated {", tpl)
+		}
+		end += open
+
+		values, err := expandGroup(rest[open+1 : end])
+		if err != nil {
+			return nil, fmt.Errorf("template %q: %w", tpl, err)
+		}
+		if len(out)*len(values) > limit {
+			return nil, fmt.Errorf("template %q: expands to more than %d URLs", tpl, limit)
+		}
+
+		prefix := rest[:open]
+		next := make([]string, 0, len(out)*len(values))
+		for _, head := range out {
+			for _, v := range values {
+				next = append(next, head+prefix+v)
+			}
+		}
+		out = next
+		rest = rest[end+1:]
+	}
+
+	if len(out) > limit {
+		return nil, fmt.Errorf("template %q: expands to more than %d URLs", tpl, limit)
+	}
+	for i := range out {
+		out[i] += rest
+	}
+	return out, nil
+}
+
+// expandGroup expands the body of a single {...} group.
+func expandGroup(group string) ([]string, error) {
+	if from, to, ok := strings.Cut(group, ".."); ok {
+		lo, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("bad range start %q", from)
+		}
+		hi, err := strconv.Atoi(to)
+		if err != nil {
+			return nil, fmt.Errorf("bad range end %q", to)
+		}
+		if hi < lo {
+			return nil, fmt.Errorf("range {%s} is descending", group)
+		}
+		if hi-lo >= MaxTemplateExpansion {
+			return nil, fmt.Errorf("range {%s} exceeds %d values", group, MaxTemplateExpansion)
+		}
+
+		width := 0
+		if len(from) > 1 && from[0] == '0' {
+			width = len(from)
+		}
+		values := make([]string, 0, hi-lo+1)
+		for n := lo; n <= hi; n++ {
+			values = append(values, fmt.Sprintf("%0*d", width, n))
+		}
+		return values, nil
+	}
+
+	if group == "" {
+		return nil, fmt.Errorf("empty {} group")
+	}
+	return strings.Split(group, ","), nil
+}
+
+// expandRootTemplates appends the expansion of every RootTemplates entry
+// to RootURLs.
+func (c *Config) expandRootTemplates() error {
+	budget := MaxTemplateExpansion
+	for _, tpl := range c.RootTemplates {
+		urls, err := ExpandTemplate(tpl, budget)
+		if err != nil {
+			return err
+		}
+		budget -= len(urls)
+		c.RootURLs = append(c.RootURLs, urls...)
+	}
+	return nil
+}
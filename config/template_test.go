@@ -0,0 +1,65 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	testCases := []struct {
+		name string
+		tpl  string
+		want []string
+	}{
+		{"No groups", "http://site/", []string{"http://site/"}},
+		{"Numeric range", "http://site/item/{1..3}", []string{"http://site/item/1", "http://site/item/2", "http://site/item/3"}},
+		{"Zero padded", "http://site/{08..10}", []string{"http://site/08", "http://site/09", "http://site/10"}},
+		{"List", "http://{a,b}.site/", []string{"http://a.site/", "http://b.site/"}},
+		{"Product", "http://{a,b}.site/{1..2}", []string{"http://a.site/1", "http://a.site/2", "http://b.site/1", "http://b.site/2"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ExpandTemplate(tc.tpl, MaxTemplateExpansion)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestExpandTemplateErrors(t *testing.T) {
+	for _, tpl := range []string{
+		"http://site/{1..",
+		"http://site/{3..1}",
+		"http://site/{x..2}",
+		"http://site/{}",
+		"http://site/{1..10}/{1..10}",
+	} {
+		if _, err := ExpandTemplate(tpl, 50); err == nil {
+			t.Errorf("Expected error for template %q", tpl)
+		}
+	}
+}
+
+func TestLoadFromFileExpandsRootTemplates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"root_urls": ["http://fixed/"], "root_templates": ["http://site/{1..2}"], "user_agents": ["x"]}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	want := []string{"http://fixed/", "http://site/1", "http://site/2"}
+	if !reflect.DeepEqual(cfg.RootURLs, want) {
+		t.Errorf("Expected RootURLs %v, got %v", want, cfg.RootURLs)
+	}
+}
@@ -0,0 +1,351 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calpa/urusai/cron"
+)
+
+// Validate reports every problem found in the configuration, joined into
+// a single error, or nil when the configuration is usable.
+func (c *Config) Validate() error {
+	var errs []error
+	if len(c.RootURLs) == 0 {
+		errs = append(errs, errors.New("root_urls: at least one root URL is required"))
+	}
+	if len(c.UserAgents) == 0 {
+		errs = append(errs, errors.New("user_agents: at least one user agent is required"))
+	}
+	if c.RandomizeUAVersion {
+		for i, ua := range c.UserAgents {
+			if err := checkUATemplate(ua); err != nil {
+				errs = append(errs, fmt.Errorf("user_agents[%d]: %w", i, err))
+			}
+		}
+	}
+	if c.MaxDepth < 0 {
+		errs = append(errs, fmt.Errorf("max_depth: must not be negative, got %d", c.MaxDepth))
+	}
+	if c.MinSleep < 0 || c.MaxSleep < 0 {
+		errs = append(errs, fmt.Errorf("min_sleep/max_sleep: must not be negative, got %d/%d", c.MinSleep, c.MaxSleep))
+	}
+	if c.MaxSleep < c.MinSleep {
+		errs = append(errs, fmt.Errorf("max_sleep (%d) must not be less than min_sleep (%d)", c.MaxSleep, c.MinSleep))
+	}
+	if c.HostChangeDepth < 0 || (c.ResetDepthOnHostChange && c.MaxDepth > 0 && c.HostChangeDepth >= c.MaxDepth) {
+		errs = append(errs, fmt.Errorf("host_change_depth: must be within [0, max_depth), got %d", c.HostChangeDepth))
+	}
+	if c.MaxPathDepth < 0 {
+		errs = append(errs, fmt.Errorf("max_path_depth: must not be negative, got %d", c.MaxPathDepth))
+	}
+	if c.MaxRecursion < 0 {
+		errs = append(errs, fmt.Errorf("max_recursion: must not be negative, got %d", c.MaxRecursion))
+	}
+	if c.MaxBranchDuration < 0 {
+		errs = append(errs, fmt.Errorf("max_branch_duration: must not be negative, got %d", c.MaxBranchDuration))
+	}
+	if c.MaxWorkers < 0 {
+		errs = append(errs, fmt.Errorf("max_workers: must not be negative, got %d", c.MaxWorkers))
+	}
+	if c.MaxLinkAge < 0 {
+		errs = append(errs, fmt.Errorf("max_link_age: must not be negative, got %d", c.MaxLinkAge))
+	}
+	if c.MinRevisitInterval < 0 {
+		errs = append(errs, fmt.Errorf("min_revisit_interval: must not be negative, got %d", c.MinRevisitInterval))
+	}
+	if c.MaxGraphNodes < 0 {
+		errs = append(errs, fmt.Errorf("max_graph_nodes: must not be negative, got %d", c.MaxGraphNodes))
+	}
+	if c.MaxTotalBytes < 0 {
+		errs = append(errs, fmt.Errorf("max_total_bytes: must not be negative, got %d", c.MaxTotalBytes))
+	}
+	if c.RootsPerIteration < 0 {
+		errs = append(errs, fmt.Errorf("roots_per_iteration: must not be negative, got %d", c.RootsPerIteration))
+	}
+	if c.SessionDuration < 0 || c.PauseBetweenSessions < 0 {
+		errs = append(errs, fmt.Errorf("session_duration/pause_between_sessions: must not be negative, got %d/%d", c.SessionDuration, c.PauseBetweenSessions))
+	}
+	if p := c.DescendProbability; p != nil && (*p < 0 || *p > 1) {
+		errs = append(errs, fmt.Errorf("descend_probability: must be within [0, 1], got %g", *p))
+	}
+	if p := c.LinkSampleRate; p != nil && (*p < 0 || *p > 1) {
+		errs = append(errs, fmt.Errorf("link_sample_rate: must be within [0, 1], got %g", *p))
+	}
+	if c.GlobalRequestsPerSecond < 0 {
+		errs = append(errs, fmt.Errorf("global_requests_per_second: must not be negative, got %g", c.GlobalRequestsPerSecond))
+	}
+	if c.ProbeRootsFailFast && !c.ProbeRootsOnStartup {
+		errs = append(errs, errors.New("probe_roots_fail_fast requires probe_roots_on_startup"))
+	}
+	if c.RampUpDuration > 0 && c.GlobalRequestsPerSecond <= 0 {
+		errs = append(errs, errors.New("ramp_up_duration requires global_requests_per_second"))
+	}
+	if len(c.SignificantQueryParams) > 0 && !c.IgnoreQueryParams {
+		errs = append(errs, errors.New("significant_query_params requires ignore_query_params"))
+	}
+	for _, blk := range sortedKeys(c.BlacklistWeights) {
+		if w := c.BlacklistWeights[blk]; w < 0 || w > 1 {
+			errs = append(errs, fmt.Errorf("blacklist_weights.%s: must be within [0, 1], got %g", blk, w))
+		}
+	}
+	if c.CacheBust < 0 || c.CacheBust > 1 {
+		errs = append(errs, fmt.Errorf("cache_bust: must be within [0, 1], got %g", c.CacheBust))
+	}
+	if c.StopAt != "" {
+		if _, err := time.Parse(time.RFC3339, c.StopAt); err != nil {
+			errs = append(errs, fmt.Errorf("stop_at: %w", err))
+		}
+	}
+	if c.Schedule != "" {
+		if _, err := cron.Parse(c.Schedule); err != nil {
+			errs = append(errs, fmt.Errorf("schedule: %w", err))
+		}
+		if c.Timeout == 0 {
+			errs = append(errs, errors.New("schedule requires timeout, which bounds each scheduled crawl"))
+		}
+	}
+	if c.GRPCAddr != "" && c.Schedule != "" {
+		errs = append(errs, errors.New("grpc_addr cannot be combined with schedule"))
+	}
+	if c.FaultInjection < 0 || c.FaultInjection > 1 {
+		errs = append(errs, fmt.Errorf("fault_injection: must be within [0, 1], got %g", c.FaultInjection))
+	}
+	if c.FaultDelay < 0 {
+		errs = append(errs, fmt.Errorf("fault_delay: must not be negative, got %d", c.FaultDelay))
+	}
+	if c.FaultDelay > 0 && c.FaultInjection == 0 {
+		errs = append(errs, errors.New("fault_delay requires fault_injection"))
+	}
+	if c.RenderBudget < 0 {
+		errs = append(errs, fmt.Errorf("render_budget: must not be negative, got %d", c.RenderBudget))
+	}
+	if c.RenderJS && c.StreamLinks {
+		errs = append(errs, errors.New("render_js cannot be combined with stream_links"))
+	}
+	if c.RenderJS {
+		// Chrome fetches each page and its resources again over its own
+		// connections, so none of these would apply to what it loads.
+		for _, name := range c.renderUnsupported() {
+			errs = append(errs, fmt.Errorf("render_js cannot be combined with %s", name))
+		}
+	}
+	switch c.ForceHTTPVersion {
+	case "", "1.0", "1.1", "2":
+	default:
+		errs = append(errs, fmt.Errorf("force_http_version: must be \"1.0\", \"1.1\" or \"2\", got %q", c.ForceHTTPVersion))
+	}
+	if c.MaxConcurrentDNS < 0 {
+		errs = append(errs, fmt.Errorf("max_concurrent_dns: must not be negative, got %d", c.MaxConcurrentDNS))
+	}
+	switch c.DialNetwork {
+	case "", "tcp", "tcp4", "tcp6":
+	default:
+		errs = append(errs, fmt.Errorf("dial_network: must be \"tcp\", \"tcp4\" or \"tcp6\", got %q", c.DialNetwork))
+	}
+	switch c.BrowserProfile {
+	case "", "chrome", "firefox", "safari":
+	default:
+		errs = append(errs, fmt.Errorf("browser_profile: must be \"chrome\", \"firefox\" or \"safari\", got %q", c.BrowserProfile))
+	}
+	switch c.TLSFingerprint {
+	case "", "chrome", "firefox", "random":
+	default:
+		errs = append(errs, fmt.Errorf("tls_fingerprint: must be \"chrome\", \"firefox\" or \"random\", got %q", c.TLSFingerprint))
+	}
+	if c.TLSFingerprint != "" && c.ForceHTTPVersion == "2" {
+		errs = append(errs, errors.New("tls_fingerprint cannot be combined with force_http_version \"2\""))
+	}
+	if (c.ClientCertFile == "") != (c.ClientKeyFile == "") {
+		errs = append(errs, errors.New("client_cert_file and client_key_file must be set together"))
+	}
+	if a := c.AdaptiveRate; a != nil {
+		if a.Decrease < 0 || a.Decrease >= 1 {
+			errs = append(errs, fmt.Errorf("adaptive_rate.decrease: must be in (0, 1), got %g", a.Decrease))
+		}
+		if a.MinRate < 0 || a.MaxRate < 0 || a.Increase < 0 {
+			errs = append(errs, errors.New("adaptive_rate: rates and increase must not be negative"))
+		}
+		if a.MaxRate > 0 && a.MaxRate < a.MinRate {
+			errs = append(errs, fmt.Errorf("adaptive_rate.max_rate (%g) must not be less than min_rate (%g)", a.MaxRate, a.MinRate))
+		}
+	}
+	for _, host := range sortedKeys(c.HostAliases) {
+		if net.ParseIP(c.HostAliases[host]) == nil {
+			errs = append(errs, fmt.Errorf("host_aliases.%s: %q is not an IP address", host, c.HostAliases[host]))
+		}
+	}
+	for _, host := range sortedKeys(c.HostProfiles) {
+		if err := c.HostProfiles[host].validate(c.MinSleep, c.MaxSleep); err != nil {
+			errs = append(errs, fmt.Errorf("host_profiles.%s.%w", host, err))
+		}
+	}
+	for _, host := range sortedKeys(c.HostOverrides) {
+		o := c.HostOverrides[host]
+		for _, name := range sortedKeys(o.Headers) {
+			if !validHeader(name, o.Headers[name]) {
+				errs = append(errs, fmt.Errorf("host_overrides.%s.headers: invalid header %q", host, name))
+			}
+		}
+		for _, name := range sortedKeys(o.Cookies) {
+			if err := (&http.Cookie{Name: name, Value: o.Cookies[name]}).Valid(); err != nil {
+				errs = append(errs, fmt.Errorf("host_overrides.%s.cookies: invalid cookie %q", host, name))
+			}
+		}
+	}
+	for _, host := range sortedKeys(c.Auth) {
+		if a := c.Auth[host]; a.Username == "" && a.Token == "" {
+			errs = append(errs, fmt.Errorf("auth.%s: needs a username or a token", host))
+		}
+	}
+	if c.ActiveHours != nil {
+		if err := c.ActiveHours.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("active_hours.%w", err))
+		}
+	}
+	switch c.VisitedBackend {
+	case "", "memory":
+	case "redis":
+		if c.Redis == nil || c.Redis.Addr == "" {
+			errs = append(errs, errors.New("visited_backend \"redis\" requires redis.addr"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("visited_backend: must be \"memory\" or \"redis\", got %q", c.VisitedBackend))
+	}
+	if f := c.VisitedFilter; f != nil {
+		if f.ExpectedItems <= 0 {
+			errs = append(errs, fmt.Errorf("visited_filter.expected_items: must be positive, got %d", f.ExpectedItems))
+		}
+		if f.FalsePositiveRate <= 0 || f.FalsePositiveRate >= 1 {
+			errs = append(errs, fmt.Errorf("visited_filter.false_positive_rate: must be in (0, 1), got %g", f.FalsePositiveRate))
+		}
+		if c.VisitedBackend == "redis" {
+			errs = append(errs, errors.New("visited_filter only applies to the memory visited_backend"))
+		}
+	}
+	switch c.QueueBackend {
+	case "", "memory":
+	case "redis":
+		if c.Redis == nil || c.Redis.Addr == "" {
+			errs = append(errs, errors.New("queue_backend \"redis\" requires redis.addr"))
+		}
+		if c.FrontierMode {
+			errs = append(errs, errors.New("frontier_mode only applies to the memory queue_backend"))
+		}
+		if c.MaxWorkers > 1 {
+			errs = append(errs, errors.New("max_workers only applies to the memory queue_backend"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("queue_backend: must be \"memory\" or \"redis\", got %q", c.QueueBackend))
+	}
+	for i, r := range c.URLRewrites {
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			errs = append(errs, fmt.Errorf("url_rewrites[%d]: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks p against the global sleeps it falls back to.
+func (p HostProfile) validate(minSleep, maxSleep int) error {
+	if p.MinSleep != nil {
+		minSleep = *p.MinSleep
+	}
+	if p.MaxSleep != nil {
+		maxSleep = *p.MaxSleep
+	}
+	switch {
+	case minSleep < 0 || maxSleep < 0:
+		return fmt.Errorf("min_sleep/max_sleep: must not be negative, got %d/%d", minSleep, maxSleep)
+	case maxSleep < minSleep:
+		return fmt.Errorf("max_sleep (%d) must not be less than min_sleep (%d)", maxSleep, minSleep)
+	case p.RequestsPerSecond < 0:
+		return fmt.Errorf("requests_per_second: must not be negative, got %g", p.RequestsPerSecond)
+	}
+	return nil
+}
+
+// validHeader reports whether name is an RFC 9110 token and value holds
+// no control characters that would split or end the header line.
+func validHeader(name, value string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return !strings.ContainsAny(value, "\r\n\x00")
+}
+
+// uaPlaceholder matches a placeholder of a randomize_ua_version template.
+var uaPlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// checkUATemplate reports the first placeholder of ua that is neither
+// "{rand}" nor an ascending "{min-max}" range.
+func checkUATemplate(ua string) error {
+	for _, m := range uaPlaceholder.FindAllStringSubmatch(ua, -1) {
+		if m[1] == "rand" {
+			continue
+		}
+		lo, hi, ok := strings.Cut(m[1], "-")
+		a, errA := strconv.Atoi(lo)
+		b, errB := strconv.Atoi(hi)
+		if !ok || errA != nil || errB != nil || a < 0 || b < a {
+			return fmt.Errorf("placeholder %s: must be {rand} or {min-max}", m[0])
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in order, so errors are reported
+// deterministically.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// renderUnsupported lists the settings in use that Chrome's own fetches
+// for render_js would bypass.
+func (c *Config) renderUnsupported() []string {
+	var names []string
+	if c.GlobalRequestsPerSecond > 0 {
+		names = append(names, "global_requests_per_second")
+	}
+	if c.AdaptiveRate != nil {
+		names = append(names, "adaptive_rate")
+	}
+	for _, host := range sortedKeys(c.HostProfiles) {
+		if c.HostProfiles[host].RequestsPerSecond > 0 {
+			names = append(names, "host_profiles."+host+".requests_per_second")
+		}
+	}
+	if len(c.HostOverrides) > 0 {
+		names = append(names, "host_overrides")
+	}
+	if len(c.Auth) > 0 {
+		names = append(names, "auth")
+	}
+	if c.ClientCertFile != "" {
+		names = append(names, "client_cert_file")
+	}
+	if c.CACertFile != "" {
+		names = append(names, "ca_cert_file")
+	}
+	if len(c.HostAliases) > 0 {
+		names = append(names, "host_aliases")
+	}
+	return names
+}
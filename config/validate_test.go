@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func validConfig() *Config {
+	return &Config{
+		MaxDepth:   5,
+		MinSleep:   1,
+		MaxSleep:   2,
+		RootURLs:   []string{"https://example.com"},
+		UserAgents: []string{"urusai"},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(c *Config)
+		wantErr string
+	}{
+		{"Valid", func(c *Config) {}, ""},
+		{"Equal sleeps", func(c *Config) { c.MaxSleep = c.MinSleep }, ""},
+		{"Inverted sleeps", func(c *Config) { c.MinSleep, c.MaxSleep = 5, 3 }, "max_sleep (3) must not be less than min_sleep (5)"},
+		{"No roots", func(c *Config) { c.RootURLs = nil }, "root_urls"},
+		{"No user agents", func(c *Config) { c.UserAgents = nil }, "user_agents"},
+		{"Negative depth", func(c *Config) { c.MaxDepth = -1 }, "max_depth"},
+		{"Host change depth", func(c *Config) { c.ResetDepthOnHostChange, c.HostChangeDepth = true, 5 }, "host_change_depth"},
+		{"Negative session", func(c *Config) { c.SessionDuration = -1 }, "session_duration"},
+		{"Descend probability", func(c *Config) { p := 1.5; c.DescendProbability = &p }, "descend_probability"},
+		{"Global rate", func(c *Config) { c.GlobalRequestsPerSecond = -1 }, "global_requests_per_second"},
+		{"Ramp up without rate", func(c *Config) { c.RampUpDuration = 1000 }, "ramp_up_duration"},
+		{"Significant params alone", func(c *Config) { c.SignificantQueryParams = []string{"lang"} }, "significant_query_params"},
+		{"Blacklist weight", func(c *Config) { c.BlacklistWeights = map[string]float64{"/logout": 2} }, "blacklist_weights./logout"},
+		{"Stop at", func(c *Config) { c.StopAt = "tomorrow" }, "stop_at"},
+		{"Schedule", func(c *Config) { c.Schedule, c.Timeout = "0 9 * * 1-5", 7200 }, ""},
+		{"Bad schedule", func(c *Config) { c.Schedule, c.Timeout = "at nine", 7200 }, "schedule"},
+		{"Schedule without timeout", func(c *Config) { c.Schedule = "0 9 * * 1-5" }, "schedule requires timeout"},
+		{"Auth without credentials", func(c *Config) { c.Auth = map[string]HostAuth{"example.com": {Password: "x"}} }, "auth.example.com"},
+		{"Roots per iteration", func(c *Config) { c.RootsPerIteration = -1 }, "roots_per_iteration"},
+		{"Max total bytes", func(c *Config) { c.MaxTotalBytes = -1 }, "max_total_bytes"},
+		{"Fault injection", func(c *Config) { c.FaultInjection = 1.5 }, "fault_injection"},
+		{"Fault delay alone", func(c *Config) { c.FaultDelay = 100 }, "fault_delay requires"},
+		{"Cache bust", func(c *Config) { c.CacheBust = -0.1 }, "cache_bust"},
+		{"HTTP version", func(c *Config) { c.ForceHTTPVersion = "3" }, "force_http_version"},
+		{"Rewrite pattern", func(c *Config) { c.URLRewrites = []URLRewrite{{Pattern: "("}} }, "url_rewrites[0]"},
+		{"TLS fingerprint", func(c *Config) { c.TLSFingerprint = "opera" }, "tls_fingerprint"},
+		{"gRPC with schedule", func(c *Config) { c.GRPCAddr, c.Schedule, c.Timeout = ":50051", "* * * * *", 60 }, "grpc_addr"},
+		{"TLS fingerprint over HTTP/2", func(c *Config) { c.TLSFingerprint, c.ForceHTTPVersion = "chrome", "2" }, "force_http_version"},
+		{"Visited backend", func(c *Config) { c.VisitedBackend = "disk" }, "visited_backend"},
+		{"Redis without addr", func(c *Config) { c.VisitedBackend = "redis" }, "redis.addr"},
+		{"Redis", func(c *Config) { c.VisitedBackend, c.Redis = "redis", &Redis{Addr: "localhost:6379"} }, ""},
+		{"Queue backend", func(c *Config) { c.QueueBackend = "nats" }, "queue_backend"},
+		{"Queue without addr", func(c *Config) { c.QueueBackend = "redis" }, "queue_backend \"redis\" requires redis.addr"},
+		{"Shared frontier", func(c *Config) {
+			c.QueueBackend, c.Redis, c.FrontierMode = "redis", &Redis{Addr: "localhost:6379"}, true
+		}, "frontier_mode"},
+		{"UA template", func(c *Config) { c.RandomizeUAVersion, c.UserAgents = true, []string{"Chrome/{major}.0"} }, "user_agents[0]: placeholder {major}"},
+		{"UA range", func(c *Config) { c.RandomizeUAVersion, c.UserAgents = true, []string{"Chrome/{126-120}.0"} }, "user_agents[0]"},
+		{"UA template off", func(c *Config) { c.UserAgents = []string{"Chrome/{major}.0"} }, ""},
+		{"Render budget", func(c *Config) { c.RenderJS, c.RenderBudget = true, -1 }, "render_budget"},
+		{"Render while streaming", func(c *Config) { c.RenderJS, c.StreamLinks = true, true }, "render_js cannot be combined with stream_links"},
+		{"Render with a rate limit", func(c *Config) { c.RenderJS, c.GlobalRequestsPerSecond = true, 2 }, "render_js cannot be combined with global_requests_per_second"},
+		{"Render with host overrides", func(c *Config) {
+			c.RenderJS = true
+			c.HostOverrides = map[string]HostOverride{"a.example": {Headers: map[string]string{"X-Api-Key": "k"}}}
+		}, "render_js cannot be combined with host_overrides"},
+		{"Render with host aliases", func(c *Config) {
+			c.RenderJS = true
+			c.HostAliases = map[string]string{"a.example": "127.0.0.1"}
+		}, "render_js cannot be combined with host_aliases"},
+		{"Negative recursion cap", func(c *Config) { c.MaxRecursion = -1 }, "max_recursion"},
+		{"Negative branch duration", func(c *Config) { c.MaxBranchDuration = -1 }, "max_branch_duration"},
+		{"Negative link age", func(c *Config) { c.MaxLinkAge = -1 }, "max_link_age"},
+		{"Negative workers", func(c *Config) { c.MaxWorkers = -1 }, "max_workers"},
+		{"Workers on shared queue", func(c *Config) { c.MaxWorkers, c.QueueBackend = 4, "redis" }, "max_workers only applies to the memory queue_backend"},
+		{"Frontier workers", func(c *Config) { c.FrontierMode, c.MaxWorkers = true, 4 }, ""},
+		{"Negative revisit interval", func(c *Config) { c.MinRevisitInterval = -1 }, "min_revisit_interval"},
+		{"Negative graph cap", func(c *Config) { c.MaxGraphNodes = -1 }, "max_graph_nodes"},
+		{"Negative path depth", func(c *Config) { c.MaxPathDepth = -1 }, "max_path_depth"},
+		{"Link sample rate", func(c *Config) { r := 1.5; c.LinkSampleRate = &r }, "link_sample_rate"},
+		{"Probe fail fast alone", func(c *Config) { c.ProbeRootsFailFast = true }, "probe_roots_fail_fast requires probe_roots_on_startup"},
+		{"Visited filter size", func(c *Config) { c.VisitedFilter = &VisitedFilter{FalsePositiveRate: 0.01} }, "visited_filter.expected_items"},
+		{"Visited filter rate", func(c *Config) { c.VisitedFilter = &VisitedFilter{ExpectedItems: 10, FalsePositiveRate: 1} }, "visited_filter.false_positive_rate"},
+		{"Negative DNS limit", func(c *Config) { c.MaxConcurrentDNS = -1 }, "max_concurrent_dns"},
+		{"Dial network", func(c *Config) { c.DialNetwork = "udp" }, "dial_network"},
+		{"Host alias", func(c *Config) { c.HostAliases = map[string]string{"staging.example": "stage.internal"} }, "host_aliases.staging.example"},
+		{"Host profile sleeps", func(c *Config) { n := 0; c.HostProfiles = map[string]HostProfile{"legacy.example": {MaxSleep: &n}} }, "host_profiles.legacy.example.max_sleep (0) must not be less than min_sleep (1)"},
+		{"Host override header", func(c *Config) {
+			c.HostOverrides = map[string]HostOverride{"api.example.com": {Headers: map[string]string{"X Key": "v"}}}
+		}, "host_overrides.api.example.com.headers"},
+		{"Host override cookie", func(c *Config) {
+			c.HostOverrides = map[string]HostOverride{"api.example.com": {Cookies: map[string]string{"a;b": "v"}}}
+		}, "host_overrides.api.example.com.cookies"},
+		{"Browser profile", func(c *Config) { c.BrowserProfile = "lynx" }, "browser_profile"},
+		{"Active hours time", func(c *Config) { c.ActiveHours = &ActiveHours{Start: "9am", End: "17:00"} }, "active_hours.start"},
+		{"Active hours day", func(c *Config) { c.ActiveHours = &ActiveHours{Start: "09:00", End: "17:00", Days: []string{"monday"}} }, "active_hours.days"},
+		{"Active hours factor", func(c *Config) { c.ActiveHours = &ActiveHours{Start: "09:00", End: "17:00", IdleFactor: 0.5} }, "active_hours.idle_factor"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.mutate(cfg)
+			err := cfg.Validate()
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("Expected no error, got %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Errorf("Expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadFromFileRejectsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"min_sleep": 6, "max_sleep": 3, "root_urls": ["http://a/"], "user_agents": ["x"]}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFromFile(path); err == nil {
+		t.Error("Expected inverted sleep range to be rejected at load")
+	}
+}
+
+func TestDefaultConfigIsValid(t *testing.T) {
+	cfg, err := LoadDefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected default config to be valid, got %v", err)
+	}
+}
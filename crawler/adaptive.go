@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/calpa/urusai/config"
+)
+
+// Defaults for unset AdaptiveRate fields.
+const (
+	defaultAdaptiveMinRate  = 0.2
+	defaultAdaptiveMaxRate  = 10
+	defaultAdaptiveIncrease = 0.5
+	defaultAdaptiveDecrease = 0.5
+	defaultAdaptiveTarget   = time.Second
+)
+
+// adaptiveRate paces requests per host with an AIMD controller: every
+// fast, successful response adds a fixed step to the host's rate, while
+// slow responses, errors, 429 and 503 multiply it down.
+type adaptiveRate struct {
+	min, max         float64 // requests per second
+	increase, factor float64
+	target           time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostPace
+}
+
+type hostPace struct {
+	rate float64   // current requests per second
+	next time.Time // earliest start of the next request
+}
+
+func newAdaptiveRate(cfg *config.AdaptiveRate) *adaptiveRate {
+	a := &adaptiveRate{
+		min:      orFloat(cfg.MinRate, defaultAdaptiveMinRate),
+		max:      orFloat(cfg.MaxRate, defaultAdaptiveMaxRate),
+		increase: orFloat(cfg.Increase, defaultAdaptiveIncrease),
+		factor:   orFloat(cfg.Decrease, defaultAdaptiveDecrease),
+		target:   millisOr(cfg.TargetLatency, defaultAdaptiveTarget),
+		hosts:    make(map[string]*hostPace),
+	}
+	if a.max < a.min {
+		a.max = a.min
+	}
+	return a
+}
+
+func orFloat(v, def float64) float64 {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+// pace returns host's state, starting new hosts at the minimum rate.
+// The caller must hold a.mu.
+func (a *adaptiveRate) pace(host string) *hostPace {
+	p, ok := a.hosts[host]
+	if !ok {
+		p = &hostPace{rate: a.min}
+		a.hosts[host] = p
+	}
+	return p
+}
+
+// rate returns the current requests-per-second allowance for host.
+func (a *adaptiveRate) rate(host string) float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.pace(host).rate
+}
+
+// wait blocks until host's next request slot, or until ctx is done.
+func (a *adaptiveRate) wait(ctx context.Context, host string) error {
+	a.mu.Lock()
+	p := a.pace(host)
+	now := time.Now()
+	start := p.next
+	if start.Before(now) {
+		start = now
+	}
+	p.next = start.Add(time.Duration(float64(time.Second) / p.rate))
+	a.mu.Unlock()
+
+	if d := time.Until(start); d > 0 && !sleepCtx(ctx, d) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// observe feeds one response into host's controller. status is 0 when
+// the request failed without a response.
+func (a *adaptiveRate) observe(host string, latency time.Duration, status int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	p := a.pace(host)
+
+	backOff := status == 0 ||
+		status == http.StatusTooManyRequests ||
+		status == http.StatusServiceUnavailable ||
+		latency > a.target
+	if backOff {
+		p.rate *= a.factor
+	} else {
+		p.rate += a.increase
+	}
+
+	if p.rate < a.min {
+		p.rate = a.min
+	}
+	if p.rate > a.max {
+		p.rate = a.max
+	}
+}
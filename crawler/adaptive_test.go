@@ -0,0 +1,86 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/calpa/urusai/config"
+)
+
+func TestAdaptiveRateAIMD(t *testing.T) {
+	a := newAdaptiveRate(&config.AdaptiveRate{
+		MinRate:       1,
+		MaxRate:       5,
+		Increase:      1,
+		Decrease:      0.5,
+		TargetLatency: 100,
+	})
+	const host = "example.com"
+	fast, slow := 10*time.Millisecond, 500*time.Millisecond
+
+	steps := []struct {
+		latency time.Duration
+		status  int
+		want    float64
+	}{
+		{fast, 200, 2},
+		{fast, 200, 3},
+		{fast, 200, 4},
+		{fast, 200, 5},
+		{fast, 200, 5}, // capped at max
+		{slow, 200, 2.5},
+		{fast, 429, 1.25},
+		{fast, 503, 1}, // floored at min
+		{fast, 0, 1},
+		{fast, 404, 2}, // a fast client error is still a fast answer
+	}
+	for i, s := range steps {
+		a.observe(host, s.latency, s.status)
+		if got := a.rate(host); got != s.want {
+			t.Fatalf("Step %d: expected rate %g, got %g", i, s.want, got)
+		}
+	}
+	if got := a.rate("other.example"); got != 1 {
+		t.Errorf("Expected new hosts to start at min rate, got %g", got)
+	}
+}
+
+func TestAdaptiveRateWaitPaces(t *testing.T) {
+	a := newAdaptiveRate(&config.AdaptiveRate{MinRate: 20, MaxRate: 20})
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := a.wait(context.Background(), "example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected 3 requests at 20/s to take at least 100ms, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := a.wait(ctx, "example.com"); err == nil {
+		t.Error("Expected wait to honour a cancelled context")
+	}
+}
+
+func TestFetchFeedsAdaptiveRate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.AdaptiveRate = &config.AdaptiveRate{MinRate: 50, MaxRate: 100, Increase: 10}
+	c := newTestCrawler(t, cfg)
+	if _, _, err := c.fetch(context.Background(), srv.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse(srv.URL)
+	if got := c.pacer.rate(u.Host); got != 60 {
+		t.Errorf("Expected a fast response to raise the rate to 60, got %g", got)
+	}
+}
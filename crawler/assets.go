@@ -0,0 +1,149 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// assetBurstConcurrency matches the per-host connection limit of
+	// common browsers.
+	assetBurstConcurrency = 6
+	// maxAssetsPerPage bounds the burst following one page.
+	maxAssetsPerPage = 20
+)
+
+// extractAssets returns the subresources a browser would load for an
+// HTML page: images, scripts, stylesheets and icons, deduplicated and in
+// document order.
+func (c *Crawler) extractAssets(body []byte, base string) []string {
+	z := html.NewTokenizer(bytes.NewReader(body))
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]struct{})
+	var out []string
+	for len(out) < maxAssetsPerPage {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		t := z.Token()
+		ref := assetRef(t)
+		if ref == "" {
+			continue
+		}
+		link := c.normalize(ref, baseURL)
+		if _, dup := seen[link]; dup || !c.assetAllowed(base, link) {
+			continue
+		}
+		seen[link] = struct{}{}
+		out = append(out, link)
+	}
+	return out
+}
+
+// assetRef returns the subresource URL referenced by t, if any.
+func assetRef(t html.Token) string {
+	attr := func(key string) string {
+		v, _ := attrVal(t, key)
+		return v
+	}
+	switch t.DataAtom {
+	case atom.Img, atom.Script:
+		return attr("src")
+	case atom.Link:
+		for _, rel := range strings.Fields(strings.ToLower(attr("rel"))) {
+			if rel == "stylesheet" || rel == "icon" {
+				return attr("href")
+			}
+		}
+	}
+	return ""
+}
+
+// assetAllowed applies the blacklist, scheme, bad-host and mixed
+// content rules to an asset referenced by page. Unlike accept it ignores
+// visited state and path prefixes, since a browser loads a page's assets
+// wherever they live.
+func (c *Crawler) assetAllowed(page, link string) bool {
+	if link == "" || c.mixedContent(page, link) {
+		return false
+	}
+	if c.blacklistedBy(link) != "" {
+		return false
+	}
+	u, err := url.ParseRequestURI(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return !c.bad.has(u.Host)
+}
+
+// fetchAssets loads the assets of a fetched HTML page in one concurrent
+// burst, as a browser does before the reader starts reading, when
+// cfg.RealisticAssetTiming is set. Stylesheets in the burst are parsed
+// for their own url() and @import references, which join it. It returns
+// once the burst is over.
+func (c *Crawler) fetchAssets(ctx context.Context, body []byte, contentType, page string) {
+	if !c.cfg.RealisticAssetTiming || isJSON(contentType) {
+		return
+	}
+	assets := c.extractAssets(body, page)
+	if len(assets) == 0 {
+		return
+	}
+
+	burst := assetBurstConcurrency
+	if c.cfg.DeterministicOrder {
+		burst = 1 // concurrent fetches would draw from c.rand in any order
+	}
+	seen := make(map[string]struct{}, len(assets))
+	var queue []string
+	// enqueue adds assets to the burst, skipping repeats and stopping at
+	// maxAssetsPerPage across the page and all its stylesheets.
+	enqueue := func(assets []string) {
+		for _, asset := range assets {
+			if _, dup := seen[asset]; dup || len(seen) >= maxAssetsPerPage {
+				continue
+			}
+			seen[asset] = struct{}{}
+			queue = append(queue, asset)
+		}
+	}
+	enqueue(assets)
+
+	// Only this loop touches queue; fetches report the references of
+	// any stylesheet they loaded back on found.
+	found := make(chan []string)
+	for inflight := 0; len(queue) > 0 || inflight > 0; {
+		if len(queue) > 0 && inflight < burst {
+			asset := queue[0]
+			queue = queue[1:]
+			inflight++
+			go func() {
+				var refs []string
+				body, contentType, err := c.fetch(ctx, asset)
+				if err != nil {
+					c.debugf("asset %s for %s: %v", asset, page, err)
+				} else if isCSS(contentType) {
+					refs = c.extractCSSRefs(body, asset)
+				}
+				found <- refs
+			}()
+			continue
+		}
+		enqueue(<-found)
+		inflight--
+	}
+}
@@ -0,0 +1,110 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExtractAssets(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.BlacklistedURLs = []string{"tracker"}
+	c := newTestCrawler(t, cfg)
+
+	page := []byte(`<html><head>
+		<link rel="stylesheet" href="/site.css">
+		<link rel="Shortcut Icon" href="/favicon.ico">
+		<link rel="canonical" href="/canonical">
+		<script src="https://cdn.example/app.js"></script>
+		<script>inline()</script>
+	</head><body>
+		<img src="logo.png"><img src="logo.png"/>
+		<img src="http://tracker.example/pixel.gif">
+		<img src="data:image/png;base64,AAAA">
+		<a href="/page">not an asset</a>
+	</body></html>`)
+
+	got := c.extractAssets(page, "http://example.com/dir/")
+	want := []string{
+		"http://example.com/site.css",
+		"http://example.com/favicon.ico",
+		"https://cdn.example/app.js",
+		"http://example.com/dir/logo.png",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestRealisticAssetTimingBurstThenPause(t *testing.T) {
+	const assetDelay = 30 * time.Millisecond
+	pages := map[string]string{
+		"/":  `<a href="/a">a</a><img src="/r1.png"><img src="/r2.png">`,
+		"/a": `<a href="/b">b</a><img src="/a1.png"><img src="/a2.png"><script src="/a3.js"></script>`,
+		"/b": `<img src="/b1.png">`,
+	}
+
+	var mu sync.Mutex
+	seen := make(map[string]time.Time)
+	done := make(map[string]time.Time)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.URL.Path] = time.Now()
+		mu.Unlock()
+		if body, ok := pages[r.URL.Path]; ok {
+			fmt.Fprint(w, body)
+		} else {
+			time.Sleep(assetDelay)
+		}
+		mu.Lock()
+		done[r.URL.Path] = time.Now()
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.MaxDepth = 2
+	cfg.MinSleep, cfg.MaxSleep = 200000, 200000 // 200ms think time
+	cfg.RealisticAssetTiming = true
+	c := newTestCrawler(t, cfg)
+	c.crawlRoot(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, p := range []string{"/", "/r1.png", "/r2.png", "/a", "/a1.png", "/a2.png", "/a3.js", "/b", "/b1.png"} {
+		if _, ok := seen[p]; !ok {
+			t.Fatalf("Expected %s to be fetched, got %v", p, seen)
+		}
+	}
+
+	// The burst: every asset of /a starts right after the page and the
+	// assets load concurrently rather than one after another.
+	var burstEnd time.Time
+	for _, asset := range []string{"/a1.png", "/a2.png", "/a3.js"} {
+		if seen[asset].Before(done["/a"]) {
+			t.Errorf("Expected %s to be requested after /a loaded", asset)
+		}
+		if gap := seen[asset].Sub(done["/a"]); gap > assetDelay {
+			t.Errorf("Expected %s within the burst, started %v after /a", asset, gap)
+		}
+		if done[asset].After(burstEnd) {
+			burstEnd = done[asset]
+		}
+	}
+	if d := burstEnd.Sub(done["/a"]); d >= 3*assetDelay {
+		t.Errorf("Expected the 3 assets to load concurrently, burst took %v", d)
+	}
+
+	// The pause: the think time follows the burst, not the page.
+	if gap := seen["/b"].Sub(burstEnd); gap < 150*time.Millisecond {
+		t.Errorf("Expected the think-time pause after the asset burst, next page came %v later", gap)
+	}
+	if seen["/a"].Before(done["/r2.png"]) || seen["/a"].Before(done["/r1.png"]) {
+		t.Error("Expected the root's assets to finish before the branch continued")
+	}
+}
@@ -0,0 +1,69 @@
+package crawler
+
+import (
+	"io"
+	"net/http"
+	"strings"
+)
+
+// do sends req and, when the host answers 401 with a challenge that
+// its cfg.Auth entry can meet, sends it once more with credentials.
+// Requests already carrying an Authorization header are not retried.
+func (c *Crawler) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || req.Header.Get("Authorization") != "" {
+		return resp, err
+	}
+	// After a redirect the challenge comes from the final host, and net/http
+	// would strip credentials on the way there, so answer it directly.
+	last := resp.Request
+	scheme, authorize := c.authFor(last.URL.Host, resp.Header.Values("WWW-Authenticate"))
+	if authorize == nil {
+		return resp, nil
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10)) // reuse the connection
+	resp.Body.Close()
+
+	retry := last.Clone(req.Context())
+	authorize(retry)
+	c.debugf("fetch %s: 401, retrying with %s credentials", shorten(retry.URL.String(), maxLoggedURL), scheme)
+	c.countRequest(retry)
+	return c.client.Do(retry)
+}
+
+// authFor picks the first scheme among the WWW-Authenticate challenges
+// that the cfg.Auth entry for host can answer, and returns it with a
+// function setting the matching Authorization header.
+func (c *Crawler) authFor(host string, challenges []string) (string, func(*http.Request)) {
+	_, a, ok := lookupHost(c.cfg.Auth, host)
+	if !ok {
+		return "", nil
+	}
+	for _, scheme := range challengeSchemes(challenges) {
+		switch {
+		case scheme == "basic" && a.Username != "":
+			return "Basic", func(r *http.Request) { r.SetBasicAuth(a.Username, a.Password) }
+		case scheme == "bearer" && a.Token != "":
+			return "Bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+a.Token) }
+		}
+	}
+	return "", nil
+}
+
+// challengeSchemes returns the lower-cased auth schemes named by
+// WWW-Authenticate values, in order. A value may hold several
+// challenges separated by commas, as may their parameters; a scheme is
+// an element whose first word is not a name=value parameter.
+func challengeSchemes(values []string) []string {
+	var schemes []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			fields := strings.Fields(part)
+			if len(fields) == 0 || strings.Contains(fields[0], "=") {
+				continue
+			}
+			schemes = append(schemes, strings.ToLower(fields[0]))
+		}
+	}
+	return schemes
+}
@@ -0,0 +1,69 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/calpa/urusai/config"
+)
+
+func TestAuthRetry(t *testing.T) {
+	testCases := []struct {
+		name      string
+		challenge string
+		auth      *config.HostAuth
+		wantAuth  string // Authorization the server accepts
+		wantErr   bool
+		wantHits  int32
+	}{
+		{"Basic", `Basic realm="staging"`, &config.HostAuth{Username: "ann", Password: "s3cret"}, "Basic YW5uOnMzY3JldA==", false, 2},
+		{"Bearer", `Bearer realm="api", error="invalid_token"`, &config.HostAuth{Token: "tok"}, "Bearer tok", false, 2},
+		{"Second challenge", `Negotiate, Basic realm="x"`, &config.HostAuth{Username: "ann"}, "Basic YW5uOg==", false, 2},
+		{"No matching scheme", `Basic realm="x"`, &config.HostAuth{Token: "tok"}, "", true, 1},
+		{"No credentials", `Basic realm="x"`, nil, "", true, 1},
+		{"Rejected credentials", `Basic realm="x"`, &config.HostAuth{Username: "ann", Password: "wrong"}, "Basic YW5uOnMzY3JldA==", true, 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var hits int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&hits, 1)
+				if tc.wantAuth == "" || r.Header.Get("Authorization") != tc.wantAuth {
+					w.Header().Set("WWW-Authenticate", tc.challenge)
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.Write([]byte("welcome"))
+			}))
+			defer srv.Close()
+
+			cfg := testConfig(srv.URL)
+			if tc.auth != nil {
+				u, _ := url.Parse(srv.URL)
+				cfg.Auth = map[string]config.HostAuth{u.Host: *tc.auth}
+			}
+			c := newTestCrawler(t, cfg)
+
+			body, _, err := c.fetch(context.Background(), srv.URL+"/")
+			var fe *FetchError
+			switch {
+			case tc.wantErr && !(errors.As(err, &fe) && fe.StatusCode == http.StatusUnauthorized):
+				t.Errorf("Expected a 401 error, got %v", err)
+			case !tc.wantErr && (err != nil || !strings.Contains(string(body), "welcome")):
+				t.Errorf("Expected the authenticated page, got %q, %v", body, err)
+			}
+			if n := atomic.LoadInt32(&hits); n != tc.wantHits {
+				t.Errorf("Expected %d requests, got %d", tc.wantHits, n)
+			}
+			if s := c.Snapshot(); s.Requests != int(tc.wantHits) {
+				t.Errorf("Expected %d counted requests, got %d", tc.wantHits, s.Requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package crawler
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// bloomSet is a VisitedSet with fixed memory. It never forgets a URL but
+// may report a URL it has not seen as visited, with a false-positive rate
+// close to the configured one until more than the expected number of
+// URLs have been added.
+type bloomSet struct {
+	mu     sync.Mutex
+	bits   []uint64
+	m      uint64 // number of bits
+	hashes uint64 // bits set per URL
+}
+
+// newBloomSet sizes a filter for n items at false-positive rate p.
+func newBloomSet(n int, p float64) *bloomSet {
+	m := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/float64(n)*math.Ln2))
+	words := (uint64(m) + 63) / 64
+	return &bloomSet{bits: make([]uint64, words), m: words * 64, hashes: uint64(k)}
+}
+
+// locations derives the URL's bit positions by double hashing two FNV
+// hashes, as described by Kirsch and Mitzenmacher.
+func (b *bloomSet) locations(url string) func(i uint64) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(url)) // hash writes never fail
+	h1 := h.Sum64()
+	_, _ = h.Write([]byte{0})
+	h2 := h.Sum64() | 1
+	return func(i uint64) uint64 { return (h1 + i*h2) % b.m }
+}
+
+func (b *bloomSet) Add(_ context.Context, url string) (bool, error) {
+	loc := b.locations(url)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	added := false
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := loc(i)
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if b.bits[word]&mask == 0 {
+			b.bits[word] |= mask
+			added = true
+		}
+	}
+	return added, nil
+}
+
+func (b *bloomSet) Has(_ context.Context, url string) (bool, error) {
+	loc := b.locations(url)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := loc(i)
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// sizeBytes reports the memory held by the bit array.
+func (b *bloomSet) sizeBytes() int {
+	return len(b.bits) * 8
+}
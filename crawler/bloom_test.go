@@ -0,0 +1,73 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/calpa/urusai/config"
+)
+
+func TestBloomSetRemembersAdded(t *testing.T) {
+	ctx := context.Background()
+	b := newBloomSet(10000, 0.01)
+
+	for i := 0; i < 10000; i++ {
+		if _, err := b.Add(ctx, fmt.Sprintf("http://example.com/page/%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 10000; i++ {
+		u := fmt.Sprintf("http://example.com/page/%d", i)
+		if seen, _ := b.Has(ctx, u); !seen {
+			t.Fatalf("Expected %s to be remembered", u)
+		}
+		if added, _ := b.Add(ctx, u); added {
+			t.Fatalf("Expected re-adding %s to report it as present", u)
+		}
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if seen, _ := b.Has(ctx, fmt.Sprintf("http://other.example/%d", i)); seen {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / 10000; rate > 0.02 {
+		t.Errorf("Expected a false-positive rate near 1%%, got %.2f%%", rate*100)
+	}
+}
+
+func TestBloomSetSizeBound(t *testing.T) {
+	// 1M URLs at 1% need about 9.6 bits each, roughly 1.2 MB.
+	b := newBloomSet(1_000_000, 0.01)
+	if size := b.sizeBytes(); size > 1_250_000 {
+		t.Errorf("Expected the filter to stay under 1.25 MB, got %d bytes", size)
+	}
+	if b.hashes != 7 {
+		t.Errorf("Expected 7 hash functions at 1%%, got %d", b.hashes)
+	}
+
+	// The filter does not grow as URLs are added.
+	before := b.sizeBytes()
+	for i := 0; i < 1000; i++ {
+		if _, err := b.Add(context.Background(), fmt.Sprintf("http://example.com/%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b.sizeBytes() != before {
+		t.Errorf("Expected a fixed size, grew from %d to %d bytes", before, b.sizeBytes())
+	}
+}
+
+func TestVisitedFilterSelectsBloom(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.VisitedFilter = &config.VisitedFilter{ExpectedItems: 1000, FalsePositiveRate: 0.001}
+	c := newTestCrawler(t, cfg)
+	if _, ok := c.visited.(*bloomSet); !ok {
+		t.Fatalf("Expected a bloom filter visited set, got %T", c.visited)
+	}
+	if !c.markVisited(context.Background(), "http://example.com/a") || c.markVisited(context.Background(), "http://example.com/a") {
+		t.Error("Expected the first claim to win and the second to be refused")
+	}
+}
@@ -0,0 +1,47 @@
+package crawler
+
+import (
+	"context"
+	"io"
+)
+
+// readBody reads at most limit bytes from body, reporting whether more
+// were left, and gives up as soon as ctx is done, returning ctx's error.
+// A read already blocked on a slow peer is interrupted by closing body,
+// so a large response trickling in cannot hold up shutdown. The limit
+// applies to what body yields, so a compressed response is capped after
+// decompression and a small gzip bomb cannot exhaust memory.
+func readBody(ctx context.Context, body io.ReadCloser, limit int64) ([]byte, bool, error) {
+	stop := context.AfterFunc(ctx, func() { _ = body.Close() })
+	defer stop()
+
+	data, err := io.ReadAll(io.LimitReader(ctxReader{ctx: ctx, r: body}, limit+1))
+	if err != nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	truncated := int64(len(data)) > limit
+	if truncated {
+		data = data[:limit]
+	}
+	return data, truncated, err
+}
+
+// ctxReader fails every Read once ctx is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// readCloser reads from one source and closes another, such as a
+// TeeReader over a response body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
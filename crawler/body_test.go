@@ -0,0 +1,144 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchAbortsSlowBodyOnCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		for {
+			if _, err := w.Write([]byte(strings.Repeat("x", 64))); err != nil {
+				return
+			}
+			flusher.Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+	}))
+	defer srv.Close()
+
+	c := newTestCrawler(t, testConfig(srv.URL))
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, _, err := c.fetch(ctx, srv.URL)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected fetch to return promptly after cancel, took %v", elapsed)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancellation error, got %v", err)
+	}
+	if n := c.errorCount(); n != 0 {
+		t.Errorf("Expected a cancelled fetch not to count as an error, got %d", n)
+	}
+}
+
+func TestHungBodyTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>"))
+		w.(http.Flusher).Flush()
+		// Never finish the body nor close the connection.
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.RequestTimeout = 200
+	c := newTestCrawler(t, cfg)
+
+	start := time.Now()
+	_, _, err := c.fetch(context.Background(), srv.URL)
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected the fetch to give up after the 200ms request timeout, took %v", elapsed)
+	}
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected ErrTimeout, got %v", err)
+	}
+	if k := c.Snapshot().ErrorKinds; k.Timeout != 1 {
+		t.Errorf("Expected the hung body to count as a timeout, got %+v", k)
+	}
+}
+
+func TestReadBodyStopsAtDoneContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := &endlessBody{}
+	if _, _, err := readBody(ctx, r, 1<<20); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if r.reads != 0 {
+		t.Errorf("Expected no reads after cancellation, got %d", r.reads)
+	}
+}
+
+func TestGzipBombIsBounded(t *testing.T) {
+	var bomb bytes.Buffer
+	zw := gzip.NewWriter(&bomb)
+	if _, err := zw.Write(make([]byte, 64*maxBodyBytes)); err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(bomb.Bytes())
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	c := newTestCrawler(t, testConfig(srv.URL))
+	body, _, err := c.fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != maxBodyBytes {
+		t.Errorf("Expected a %d-byte gzip payload to be cut at %d bytes once decompressed, got %d", bomb.Len(), maxBodyBytes, len(body))
+	}
+	if !strings.Contains(buf.String(), "decompressed body truncated") {
+		t.Errorf("Expected a truncation warning, got %q", buf.String())
+	}
+}
+
+func TestReadBodyReportsTruncation(t *testing.T) {
+	for _, tc := range []struct {
+		body      string
+		truncated bool
+	}{{"abc", false}, {"abcd", false}, {"abcde", true}} {
+		got, truncated, err := readBody(context.Background(), io.NopCloser(strings.NewReader(tc.body)), 4)
+		if err != nil || truncated != tc.truncated || len(got) > 4 {
+			t.Errorf("readBody(%q, 4) = %q, %v, %v; expected truncated %v", tc.body, got, truncated, err, tc.truncated)
+		}
+	}
+}
+
+// endlessBody is a body without end that counts its reads.
+type endlessBody struct{ reads int }
+
+func (b *endlessBody) Read(p []byte) (int, error) {
+	b.reads++
+	return len(p), nil
+}
+
+func (b *endlessBody) Close() error { return nil }
@@ -0,0 +1,93 @@
+package crawler
+
+import (
+	"net/http"
+	"strings"
+)
+
+// browserProfile is the bundle of headers a browser sends with a
+// top-level navigation, together with a test for user agents it is
+// consistent with.
+type browserProfile struct {
+	matches func(ua string) bool
+	headers [][2]string // in the order the browser sends them
+}
+
+// browserProfiles are selected by cfg.BrowserProfile. Accept-Encoding is
+// deliberately absent: net/http only decompresses responses when it sets
+// that header itself.
+var browserProfiles = map[string]browserProfile{
+	"chrome": {
+		matches: func(ua string) bool { return strings.Contains(ua, "Chrome/") },
+		headers: [][2]string{
+			{"Upgrade-Insecure-Requests", "1"},
+			{"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
+			{"Sec-Fetch-Site", "none"},
+			{"Sec-Fetch-Mode", "navigate"},
+			{"Sec-Fetch-User", "?1"},
+			{"Sec-Fetch-Dest", "document"},
+			{"Accept-Language", "en-US,en;q=0.9"},
+		},
+	},
+	"firefox": {
+		matches: func(ua string) bool { return strings.Contains(ua, "Firefox/") },
+		headers: [][2]string{
+			{"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+			{"Accept-Language", "en-US,en;q=0.5"},
+			{"Upgrade-Insecure-Requests", "1"},
+			{"Sec-Fetch-Dest", "document"},
+			{"Sec-Fetch-Mode", "navigate"},
+			{"Sec-Fetch-Site", "none"},
+			{"Sec-Fetch-User", "?1"},
+		},
+	},
+	"safari": {
+		matches: func(ua string) bool {
+			return strings.Contains(ua, "Safari/") && !strings.Contains(ua, "Chrome/")
+		},
+		headers: [][2]string{
+			{"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+			{"Sec-Fetch-Site", "none"},
+			{"Sec-Fetch-Dest", "document"},
+			{"Accept-Language", "en-US,en;q=0.9"},
+			{"Sec-Fetch-Mode", "navigate"},
+		},
+	},
+}
+
+// userAgent picks a random configured user agent, preferring ones that
+// match the active browser profile so headers and UA stay consistent,
+// and fills in its version placeholders with cfg.RandomizeUAVersion.
+func (c *Crawler) userAgent() string {
+	agents := c.cfg.UserAgents
+	if p, ok := browserProfiles[c.cfg.BrowserProfile]; ok {
+		var matching []string
+		for _, ua := range agents {
+			if p.matches(ua) {
+				matching = append(matching, ua)
+			}
+		}
+		if len(matching) > 0 {
+			agents = matching
+		}
+	}
+	ua := agents[c.rand.Intn(len(agents))]
+	if c.cfg.RandomizeUAVersion {
+		ua = c.expandUA(ua)
+	}
+	return ua
+}
+
+// applyBrowserHeaders sets the active profile's header bundle on req.
+// HTTP/1.1 requests are written by net/http in sorted key order and
+// HTTP/2 uses HPACK, so the browser's ordering is not reproduced on the
+// wire; the set of headers and their values are.
+func (c *Crawler) applyBrowserHeaders(req *http.Request) {
+	p, ok := browserProfiles[c.cfg.BrowserProfile]
+	if !ok {
+		return
+	}
+	for _, h := range p.headers {
+		req.Header.Set(h[0], h[1])
+	}
+}
@@ -0,0 +1,89 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const (
+	chromeUA  = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	firefoxUA = "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0"
+	safariUA  = "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_2) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15"
+)
+
+func TestBrowserProfileHeaders(t *testing.T) {
+	for name, profile := range browserProfiles {
+		t.Run(name, func(t *testing.T) {
+			var got http.Header
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Clone()
+			}))
+			defer srv.Close()
+
+			cfg := testConfig(srv.URL)
+			cfg.UserAgents = []string{chromeUA, firefoxUA, safariUA}
+			cfg.BrowserProfile = name
+			c := newTestCrawler(t, cfg)
+			if _, _, err := c.fetch(context.Background(), srv.URL); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, h := range profile.headers {
+				if v := got.Get(h[0]); v != h[1] {
+					t.Errorf("Expected %s: %q, got %q", h[0], h[1], v)
+				}
+			}
+			if ua := got.Get("User-Agent"); !profile.matches(ua) {
+				t.Errorf("Expected a %s user agent, got %q", name, ua)
+			}
+			if !strings.Contains(got.Get("Accept-Encoding"), "gzip") {
+				t.Error("Expected transparent gzip negotiation to remain enabled")
+			}
+		})
+	}
+}
+
+func TestUserAgentFallsBackWithoutMatch(t *testing.T) {
+	cfg := testConfig()
+	cfg.UserAgents = []string{firefoxUA}
+	cfg.BrowserProfile = "safari"
+	c := newTestCrawler(t, cfg)
+
+	if ua := c.userAgent(); ua != firefoxUA {
+		t.Errorf("Expected fallback to configured agents, got %q", ua)
+	}
+}
+
+func TestRandomizeUAVersion(t *testing.T) {
+	cfg := testConfig()
+	cfg.UserAgents = []string{"Mozilla/5.0 (X11; Linux x86_64) Chrome/{120-126}.0.{rand}.{rand} Safari/537.36"}
+	cfg.RandomizeUAVersion = true
+	c := newTestCrawler(t, cfg)
+
+	pattern := regexp.MustCompile(`^Mozilla/5\.0 \(X11; Linux x86_64\) Chrome/(\d+)\.0\.\d{1,4}\.\d{1,4} Safari/537\.36$`)
+	seen := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		ua := c.userAgent()
+		m := pattern.FindStringSubmatch(ua)
+		if m == nil {
+			t.Fatalf("Expected %q to match the template", ua)
+		}
+		if major, _ := strconv.Atoi(m[1]); major < 120 || major > 126 {
+			t.Errorf("Expected a major version in [120, 126], got %d", major)
+		}
+		seen[ua] = true
+	}
+	if len(seen) < 100 {
+		t.Errorf("Expected varied user agents, got %d distinct in 200", len(seen))
+	}
+
+	cfg.RandomizeUAVersion = false
+	if ua := newTestCrawler(t, cfg).userAgent(); ua != cfg.UserAgents[0] {
+		t.Errorf("Expected the template verbatim when disabled, got %q", ua)
+	}
+}
@@ -0,0 +1,54 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+)
+
+// byteBudget is a counting semaphore over bytes. Fetches reserve the
+// size of the body they are about to read and block, honouring their
+// context, while the budget is exhausted.
+type byteBudget struct {
+	mu    sync.Mutex
+	limit int64
+	used  int64
+	wake  chan struct{} // closed and replaced on every release
+}
+
+func newByteBudget(limit int64) *byteBudget {
+	return &byteBudget{limit: limit, wake: make(chan struct{})}
+}
+
+// acquire reserves n bytes, clamped to the budget's limit so a single
+// oversized body can still proceed on its own. It returns the number of
+// bytes actually reserved, which must be passed back to release.
+func (b *byteBudget) acquire(ctx context.Context, n int64) (int64, error) {
+	if n > b.limit {
+		n = b.limit
+	}
+	for {
+		b.mu.Lock()
+		if b.used+n <= b.limit {
+			b.used += n
+			b.mu.Unlock()
+			return n, nil
+		}
+		wake := b.wake
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// release returns n previously reserved bytes and wakes any waiters.
+func (b *byteBudget) release(n int64) {
+	b.mu.Lock()
+	b.used -= n
+	close(b.wake)
+	b.wake = make(chan struct{})
+	b.mu.Unlock()
+}
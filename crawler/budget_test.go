@@ -0,0 +1,114 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowBody streams a fixed payload slowly and tracks how many bodies are
+// being read at the same time.
+type slowBody struct {
+	r       io.Reader
+	active  *int32
+	peak    *int32
+	started bool
+}
+
+func (b *slowBody) Read(p []byte) (int, error) {
+	if !b.started {
+		b.started = true
+		n := atomic.AddInt32(b.active, 1)
+		for {
+			old := atomic.LoadInt32(b.peak)
+			if n <= old || atomic.CompareAndSwapInt32(b.peak, old, n) {
+				break
+			}
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	if len(p) > 256 {
+		p = p[:256]
+	}
+	return b.r.Read(p)
+}
+
+func (b *slowBody) Close() error {
+	if b.started {
+		atomic.AddInt32(b.active, -1)
+	}
+	return nil
+}
+
+type slowTransport struct {
+	size         int
+	active, peak int32
+}
+
+func (t *slowTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Status:        "200 OK",
+		ContentLength: int64(t.size),
+		Body:          &slowBody{r: bytes.NewReader(make([]byte, t.size)), active: &t.active, peak: &t.peak},
+		Request:       req,
+	}, nil
+}
+
+func TestInFlightBytesSerializesFetches(t *testing.T) {
+	const size = 2048
+	cfg := testConfig("http://example.com")
+	cfg.MaxInFlightBytes = size
+	c := newTestCrawler(t, cfg)
+	tr := &slowTransport{size: size}
+	c.client.Transport = tr
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body, _, err := c.fetch(context.Background(), "http://example.com/big")
+			if err != nil {
+				t.Errorf("fetch: %v", err)
+			}
+			if len(body) != size {
+				t.Errorf("Expected %d bytes, got %d", size, len(body))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if tr.peak != 1 {
+		t.Errorf("Expected bodies to be read one at a time, peak concurrency was %d", tr.peak)
+	}
+}
+
+func TestInFlightBytesRespectsContext(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.MaxInFlightBytes = 100
+	c := newTestCrawler(t, cfg)
+	c.client.Transport = &slowTransport{size: 100}
+
+	held, err := c.budget.acquire(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := c.fetch(ctx, "http://example.com/"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected fetch to block until deadline, got %v", err)
+	}
+
+	c.budget.release(held)
+	if _, _, err := c.fetch(context.Background(), "http://example.com/"); err != nil {
+		t.Errorf("Expected fetch to succeed once budget is free, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package crawler
+
+import (
+	"bytes"
+	"mime"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// charsetSniffLen is how much of a body is scanned for a <meta> charset
+// declaration, matching the HTML spec's prescan limit.
+const charsetSniffLen = 1024
+
+// detectCharset returns the lower-cased charset declared by the
+// Content-Type header or, failing that, a <meta> tag near the start of
+// body. It returns "" when nothing is declared.
+func detectCharset(contentType string, body []byte) string {
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if cs := params["charset"]; cs != "" {
+			return strings.ToLower(cs)
+		}
+	}
+
+	if len(body) > charsetSniffLen {
+		body = body[:charsetSniffLen]
+	}
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			if t.DataAtom != atom.Meta {
+				continue
+			}
+			var httpEquiv, content string
+			for _, a := range t.Attr {
+				switch strings.ToLower(a.Key) {
+				case "charset":
+					return strings.ToLower(strings.TrimSpace(a.Val))
+				case "http-equiv":
+					httpEquiv = strings.ToLower(a.Val)
+				case "content":
+					content = a.Val
+				}
+			}
+			if httpEquiv == "content-type" {
+				if _, params, err := mime.ParseMediaType(content); err == nil && params["charset"] != "" {
+					return strings.ToLower(params["charset"])
+				}
+			}
+		}
+	}
+}
+
+// toUTF8 transcodes body from charset, any label the WHATWG Encoding
+// Standard knows such as Shift_JIS, GBK or windows-1252, to UTF-8. It
+// reports false when the charset is unknown and body was left as is.
+func toUTF8(body []byte, charset string) ([]byte, bool) {
+	switch charset {
+	case "", "utf-8", "utf8":
+		return body, true
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return body, false
+	}
+	utf, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return body, false
+	}
+	return bytes.TrimPrefix(utf, []byte("\uFEFF")), true
+}
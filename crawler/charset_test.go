@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetectCharset(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"Header", "text/html; charset=Shift_JIS", "", "shift_jis"},
+		{"Meta charset", "text/html", `<html><head><meta charset="GBK"></head>`, "gbk"},
+		{"Meta http-equiv", "", `<meta http-equiv="Content-Type" content="text/html; charset=EUC-JP">`, "euc-jp"},
+		{"Header wins", "text/html; charset=utf-8", `<meta charset="iso-8859-1">`, "utf-8"},
+		{"Undeclared", "text/html", `<a href="/">x</a>`, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := detectCharset(tc.contentType, []byte(tc.body)); got != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFetchTranscodesLatin1(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+		w.Write([]byte("<a href=\"/caf\xe9\">caf\xe9</a>"))
+	}))
+	defer srv.Close()
+
+	c := newTestCrawler(t, testConfig(srv.URL))
+	body, _, err := c.fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links := c.extractLinks(body, srv.URL)
+	want := srv.URL + "/caf%C3%A9"
+	if len(links) != 1 || links[0] != want {
+		t.Errorf("Expected [%s], got %v", want, links)
+	}
+}
+
+func TestFetchTranscodesShiftJIS(t *testing.T) {
+	// <a href="/ニュース">ニュース</a>, path and text in Shift_JIS.
+	news := "\x83\x6a\x83\x85\x81\x5b\x83\x58"
+	page := []byte(`<meta charset="Shift_JIS"><a href="/` + news + `">` + news + `</a>`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(page)
+	}))
+	defer srv.Close()
+
+	c := newTestCrawler(t, testConfig(srv.URL))
+	body, _, err := c.fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links := c.extractLinks(body, srv.URL)
+	want := srv.URL + "/%E3%83%8B%E3%83%A5%E3%83%BC%E3%82%B9" // /ニュース in UTF-8
+	if len(links) != 1 || links[0] != want {
+		t.Errorf("Expected [%s], got %v", want, links)
+	}
+}
+
+func TestToUTF8(t *testing.T) {
+	testCases := []struct {
+		charset string
+		in      []byte
+		want    string
+	}{
+		{"windows-1252", []byte("\x93quoted\x94 \x80"), "“quoted” €"},
+		{"utf-16le", []byte{0xFF, 0xFE, 'h', 0, 'i', 0}, "hi"},
+		{"utf-16be", []byte{0, 'h', 0, 'i'}, "hi"},
+		{"utf-8", []byte("日本"), "日本"},
+		{"shift_jis", []byte("\x93\xfa\x96\x7b"), "日本"},
+		{"gbk", []byte("\xd6\xd0\xce\xc4"), "中文"},
+		{"euc-jp", []byte("\xc6\xfc\xcb\xdc"), "日本"},
+	}
+	for _, tc := range testCases {
+		got, ok := toUTF8(tc.in, tc.charset)
+		if !ok || string(got) != tc.want {
+			t.Errorf("toUTF8(%q) = %q, %v; expected %q", tc.charset, got, ok, tc.want)
+		}
+	}
+	if _, ok := toUTF8([]byte("x"), "klingon"); ok {
+		t.Error("Expected an unknown charset to be reported as unsupported")
+	}
+}
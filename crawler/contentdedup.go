@@ -0,0 +1,28 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"sync"
+)
+
+// contentSet remembers the SHA-256 of every body parsed for links, for
+// cfg.DedupByContent.
+type contentSet struct {
+	mu   sync.Mutex
+	seen map[[sha256.Size]byte]struct{}
+}
+
+// add records body, reporting whether an identical body was seen before.
+func (s *contentSet) add(body []byte) bool {
+	sum := sha256.Sum256(body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen == nil {
+		s.seen = make(map[[sha256.Size]byte]struct{})
+	}
+	if _, dup := s.seen[sum]; dup {
+		return true
+	}
+	s.seen[sum] = struct{}{}
+	return false
+}
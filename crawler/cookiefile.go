@@ -0,0 +1,105 @@
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// fileCookie is one cookie of a cookies.txt file, with the URL it is
+// stored for.
+type fileCookie struct {
+	url    *url.URL
+	cookie *http.Cookie
+}
+
+// httpOnlyPrefix marks HttpOnly cookies in files written by curl and
+// browser exporters; other lines starting with # are comments.
+const httpOnlyPrefix = "#HttpOnly_"
+
+// parseCookieFile reads a Netscape cookies.txt: one cookie per line as
+// domain, include-subdomains flag, path, secure flag, expiry in Unix
+// seconds (0 for a session cookie), name and value, separated by tabs.
+// Cookies expired by now are skipped.
+func parseCookieFile(r io.Reader, now time.Time) ([]fileCookie, error) {
+	var cookies []fileCookie
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimRight(sc.Text(), "\r")
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		line = strings.TrimPrefix(line, httpOnlyPrefix)
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Split(line, "\t")
+		if len(f) != 7 {
+			return nil, fmt.Errorf("line %d: want 7 tab-separated fields, got %d", n, len(f))
+		}
+		domain, subdomains, path, secure, expiry, name, value := f[0], f[1], f[2], f[3], f[4], f[5], f[6]
+		exp, err := strconv.ParseInt(expiry, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: expiry: %w", n, err)
+		}
+		if exp != 0 && !time.Unix(exp, 0).After(now) {
+			continue
+		}
+
+		host := strings.TrimPrefix(domain, ".")
+		if path == "" {
+			path = "/"
+		}
+		c := &http.Cookie{
+			Name:     name,
+			Value:    value,
+			Path:     path,
+			Secure:   strings.EqualFold(secure, "TRUE"),
+			HttpOnly: httpOnly,
+		}
+		if strings.EqualFold(subdomains, "TRUE") {
+			c.Domain = host
+		}
+		if exp != 0 {
+			c.Expires = time.Unix(exp, 0)
+		}
+		scheme := "http"
+		if c.Secure {
+			scheme = "https"
+		}
+		cookies = append(cookies, fileCookie{url: &url.URL{Scheme: scheme, Host: host, Path: path}, cookie: c})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return cookies, nil
+}
+
+// loadCookieJar returns a cookie jar holding the cookies of the
+// cookies.txt at path, for cfg.CookieFile.
+func loadCookieJar(path string) (*cookiejar.Jar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cookies, err := parseCookieFile(f, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, err
+	}
+	for _, fc := range cookies {
+		jar.SetCookies(fc.url, []*http.Cookie{fc.cookie})
+	}
+	return jar, nil
+}
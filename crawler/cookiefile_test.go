@@ -0,0 +1,85 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCookieFile(t *testing.T) {
+	seen := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var names []string
+		for _, c := range r.Cookies() {
+			names = append(names, c.Name+"="+c.Value)
+		}
+		sort.Strings(names)
+		seen[r.URL.Path] = strings.Join(names, " ")
+	}))
+	defer srv.Close()
+
+	future := time.Now().Add(time.Hour).Unix()
+	file := fmt.Sprintf(`# Netscape HTTP Cookie File
+# This is a generated file! Do not edit.
+
+127.0.0.1	FALSE	/	FALSE	0	session	abc
+#HttpOnly_127.0.0.1	FALSE	/private	FALSE	%d	token	xyz
+127.0.0.1	FALSE	/	FALSE	1	expired	old
+.example.com	TRUE	/	FALSE	%d	other	elsewhere
+`, future, future)
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig(srv.URL)
+	cfg.CookieFile = path
+	c := newTestCrawler(t, cfg)
+	for _, p := range []string{"/", "/private/page"} {
+		if _, _, err := c.fetch(context.Background(), srv.URL+p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := seen["/"]; got != "session=abc" {
+		t.Errorf("Expected only the session cookie on /, got %q", got)
+	}
+	if got := seen["/private/page"]; got != "session=abc token=xyz" {
+		t.Errorf("Expected the path-scoped cookie under /private, got %q", got)
+	}
+}
+
+func TestParseCookieFile(t *testing.T) {
+	cookies, err := parseCookieFile(strings.NewReader("#HttpOnly_.example.com\tTRUE\t/app\tTRUE\t2000000000\tid\t42\n"), time.Unix(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 1 {
+		t.Fatalf("Expected 1 cookie, got %d", len(cookies))
+	}
+	fc := cookies[0]
+	c := fc.cookie
+	if fc.url.String() != "https://example.com/app" || c.Domain != "example.com" || c.Path != "/app" ||
+		!c.Secure || !c.HttpOnly || c.Expires.Unix() != 2000000000 || c.Name != "id" || c.Value != "42" {
+		t.Errorf("Expected every field to be parsed, got %s %+v", fc.url, c)
+	}
+
+	if _, err := parseCookieFile(strings.NewReader("example.com\tFALSE\t/\n"), time.Now()); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected a malformed line to be reported, got %v", err)
+	}
+}
+
+func TestCookieFileMissing(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.CookieFile = filepath.Join(t.TempDir(), "missing.txt")
+	if _, err := NewCrawler(cfg); err == nil || !strings.Contains(err.Error(), "cookie_file") {
+		t.Errorf("Expected a missing cookie file to fail NewCrawler, got %v", err)
+	}
+}
@@ -1,30 +1,59 @@
 package crawler
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"math/rand"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
+	"os"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
+	"golang.org/x/net/idna"
 
 	"github.com/calpa/urusai/config"
+	"github.com/calpa/urusai/statsd"
 )
 
+// maxBodyBytes caps how much of a single response body is read.
+const maxBodyBytes = 1 << 20 // 1 MiB
+
+// defaultRequestTimeout bounds a request, body read included, when
+// cfg.RequestTimeout is unset.
+const defaultRequestTimeout = 5 * time.Second
+
+// defaultMaxURLLength bounds queued and redirected URLs when
+// cfg.MaxURLLength is unset.
+const defaultMaxURLLength = 2048
+
+// maxLoggedURL is how much of a rejected link is logged.
+const maxLoggedURL = 200
+
+// defaultStatsDPrefix is prepended to metric names when
+// cfg.StatsDPrefix is unset.
+const defaultStatsDPrefix = "urusai."
+
 // Crawler generates random HTTP traffic starting from a set of roots.
 // It respects depth and timeout limits, avoids already‑visited URLs and
 // extracts links with the standard library HTML tokenizer for robustness.
 // All network calls honour the supplied context so callers can cancel
 // the crawl at any time (e.g. when a global deadline or signal fires).
 //
-// Public API is intentionally small — call New() then Crawl(ctx).
+// Public API is intentionally small — call NewCrawler() then Crawl(ctx).
 // The crawler retains no global state and can be created many times in
 // one process or test.
 
@@ -32,156 +61,1204 @@ type Crawler struct {
 	cfg       *config.Config
 	client    *http.Client
 	rand      *rand.Rand
+	clock     Clock
 	startTime time.Time
+	stopAt    time.Time // cfg.StopAt, zero when unset
+	rampStart time.Time // by clock; zero outside Crawl
+
+	sessionStart time.Time // by clock; zero before the first session
+
+	roots   []string       // cfg.RootURLs, less those dropped by probeRoots
+	links   []string       // queue of links to visit next
+	queue   LinkQueue      // c.links by default, or a queue shared with other crawlers
+	visited VisitedSet     // URLs claimed, possibly shared with other crawlers
+	claimed atomic.Int64   // URLs this crawler claimed in visited
+	fetched atomic.Int64   // body bytes downloaded, for cfg.MaxTotalBytes
+	flights flightGroup    // coalesces concurrent fetches of one URL
+	bodies  contentSet     // bodies already parsed, by hash
+	latency *hostLatency   // rolling per-host fetch latency
+	budget  *byteBudget    // in-flight body bytes; nil when unlimited
+	bad     *hostSet       // hosts excluded after redirect loops
+	hosts   *hostSet       // every host that answered a request
+	graph   *linkGraph     // links followed, for cfg.GraphFile; nil when unset
+	secure  *hostSet       // hosts that answered an https upgrade
+	plain   *hostSet       // hosts whose https upgrade failed
+	pacer   *adaptiveRate  // per-host AIMD pacing; nil when disabled
+	global  *leakyBucket   // global request cadence; nil when unlimited
+	metrics *statsd.Client // StatsD sink; nil when disabled
+
+	rewrites  []rewriteRule      // outgoing URL rewrites
+	delays    []time.Duration    // cfg.DelaySamplesFile think times; nil draws from the sleep range
+	avoid     []string           // sorted cfg.BlacklistWeights patterns
+	fetchers  map[string]Fetcher // by URL scheme
+	chrome    string             // browser binary for cfg.RenderJS
+	transform BodyTransform      // applied to fetched bodies; nil is identity
+	signer    RequestSigner      // run on each request before it is sent; nil skips
+	events    EventHandler       // told of every page visit; nil skips
+
+	statsMu     sync.Mutex
+	stats       Stats
+	lastSuccess time.Time // end of the last successful fetch
+
+	branchHost  string    // host of the last page fetched on the branch
+	branchStart time.Time // by clock; when the current root was picked
+	frames      int       // nested depthFirst calls on the current branch
+
+	hostRates map[string]*leakyBucket // cfg.HostProfiles rates, by profile key
+
+	rootDepths map[string]int // shallowest root path depth, by host
+
+	linksMu  sync.Mutex           // guards links, queuedAt and hops for cfg.MaxWorkers
+	queuedAt map[string]time.Time // by clock; when each queued link was last queued, for cfg.MaxLinkAge
+	hops     map[string]int       // depth each queued link is visited at, for parallelBranch
+	revisits *revisitLog          // last fetch of each URL, for cfg.MinRevisitInterval; nil when unset
+
+	casingMu sync.Mutex
+	casing   map[string]string // cfg.RandomizeHeaderCase spellings, by canonical name
+
+	gate pauseGate // holds new fetches between Pause and Resume
+
+	debug bool // log debug-only diagnostics such as rejected links
+}
+
+// NewCrawler returns a ready‑to‑use Crawler. Its PRNG is seeded from
+// cfg.Seed, or from the clock when that is 0, so that runs can be
+// reproduced. It fails when resources named by cfg, such as TLS
+// certificates, cannot be loaded. The keys of cfg's per-host maps are
+// replaced by their lowercase forms.
+func NewCrawler(cfg *config.Config) (*Crawler, error) {
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	rewrites, err := compileRewrites(cfg.URLRewrites)
+	if err != nil {
+		return nil, err
+	}
+
+	// Per-host maps are matched case-insensitively, like host_aliases.
+	cfg.HostProfiles = lowerHostKeys(cfg.HostProfiles)
+	cfg.HostOverrides = lowerHostKeys(cfg.HostOverrides)
+	cfg.HostPathPrefixes = lowerHostKeys(cfg.HostPathPrefixes)
+	cfg.Auth = lowerHostKeys(cfg.Auth)
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	c := &Crawler{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout:   millisOr(cfg.RequestTimeout, defaultRequestTimeout),
+			Transport: transport,
+		},
+		rand:    rand.New(newLockedSource(seed)),
+		clock:   systemClock{},
+		latency: newHostLatency(cfg.HostLatencyWindow),
+		bad:     newHostSet(),
+		hosts:   newHostSet(),
+		secure:  newHostSet(),
+		plain:   newHostSet(),
+
+		rewrites: rewrites,
+	}
+	c.client.CheckRedirect = c.checkRedirect
+	if cfg.CookieFile != "" {
+		if c.client.Jar, err = loadCookieJar(cfg.CookieFile); err != nil {
+			return nil, fmt.Errorf("cookie_file: %w", err)
+		}
+	}
+	if cfg.DelaySamplesFile != "" {
+		if c.delays, err = loadDelaySamples(cfg.DelaySamplesFile); err != nil {
+			return nil, fmt.Errorf("delay_samples_file: %w", err)
+		}
+	}
+	if cfg.StopAt != "" {
+		if c.stopAt, err = time.Parse(time.RFC3339, cfg.StopAt); err != nil {
+			return nil, fmt.Errorf("stop_at: %w", err)
+		}
+	}
+	for blk := range cfg.BlacklistWeights {
+		c.avoid = append(c.avoid, blk)
+	}
+	sort.Strings(c.avoid)
+	c.roots = cfg.RootURLs
+	if cfg.MaxLinkAge > 0 {
+		c.queuedAt = make(map[string]time.Time)
+	}
+	if cfg.MaxWorkers > 1 && !cfg.FrontierMode {
+		c.hops = make(map[string]int)
+	}
+	if cfg.MinRevisitInterval > 0 {
+		c.revisits = newRevisitLog(time.Duration(cfg.MinRevisitInterval) * time.Millisecond)
+	}
+	c.rootDepths = rootPathDepths(cfg.RootURLs)
+	c.fetchers = c.defaultFetchers()
+	if c.visited, err = newVisitedSet(cfg); err != nil {
+		return nil, err
+	}
+	if c.queue, err = newLinkQueue(c, cfg); err != nil {
+		return nil, err
+	}
+	if cfg.RenderJS {
+		if c.chrome, err = findChrome(cfg.ChromePath); err != nil {
+			return nil, fmt.Errorf("render_js: %w", err)
+		}
+	}
+	if cfg.GraphFile != "" {
+		c.graph = newLinkGraph(cfg.MaxGraphNodes)
+	}
+	if cfg.MaxInFlightBytes > 0 {
+		c.budget = newByteBudget(cfg.MaxInFlightBytes)
+	}
+	if cfg.AdaptiveRate != nil {
+		c.pacer = newAdaptiveRate(cfg.AdaptiveRate)
+	}
+	c.hostRates = newHostRates(cfg.HostProfiles)
+	if cfg.GlobalRequestsPerSecond > 0 {
+		c.global = newLeakyBucket(cfg.GlobalRequestsPerSecond, millisOr(cfg.RampUpDuration, 0))
+	}
+	if cfg.FaultInjection > 0 {
+		log.Printf("fault injection enabled: %.0f%% of fetches will be failed or delayed on purpose", cfg.FaultInjection*100)
+	}
+	if cfg.StatsDAddr != "" {
+		prefix := cfg.StatsDPrefix
+		if prefix == "" {
+			prefix = defaultStatsDPrefix
+		}
+		if c.metrics, err = statsd.New(cfg.StatsDAddr, prefix, cfg.StatsDTags...); err != nil {
+			return nil, fmt.Errorf("statsd: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// Hosts returns every distinct host that has answered a request so far,
+// sorted.
+func (c *Crawler) Hosts() []string {
+	return c.hosts.sorted()
+}
 
-	links   []string            // queue of links to visit next
-	visited map[string]struct{} // fast membership test to avoid repeats
+// SetDebug enables debug-only logging, such as the reason every
+// candidate link was rejected.
+func (c *Crawler) SetDebug(on bool) {
+	c.debug = on
 }
 
-// New returns a ready‑to‑use Crawler. A fresh PRNG is seeded so that
-// tests can supply their own *rand.Source when determinism is required.
-func NewCrawler(cfg *config.Config) *Crawler {
-	return &Crawler{
-		cfg:     cfg,
-		client:  &http.Client{Timeout: 5 * time.Second},
-		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
-		visited: make(map[string]struct{}),
+// debugf logs only when debug logging is enabled.
+func (c *Crawler) debugf(format string, args ...any) {
+	if c.debug {
+		log.Printf(format, args...)
 	}
 }
 
+// ErrTooManyErrors is returned by Crawl when the number of failed
+// requests exceeds cfg.MaxErrors.
+var ErrTooManyErrors = errors.New("too many failed requests")
+
+// ErrByteLimit is returned by Crawl when the bodies fetched reach
+// cfg.MaxTotalBytes.
+var ErrByteLimit = errors.New("total byte limit reached")
+
+// ErrIdleTimeout is returned by Crawl when no fetch succeeded within
+// cfg.IdleTimeout.
+var ErrIdleTimeout = errors.New("no successful fetch within idle timeout")
+
+// ErrTimeLimit is returned by Crawl when it ends at cfg.Timeout or
+// cfg.StopAt. Unlike the other errors it does not mean anything went
+// wrong, only that the run was cut short by its time limit.
+var ErrTimeLimit = errors.New("crawl time limit reached")
+
 // Crawl walks the Web until one of the following happens:
 //   - The supplied context is cancelled
-//   - Global timeout (cfg.Timeout) elapses
+//   - Global timeout (cfg.Timeout) elapses or cfg.StopAt passes
 //   - Maximum link depth (cfg.MaxDepth) is reached
-func (c *Crawler) Crawl(ctx context.Context) {
+//   - More than cfg.MaxErrors requests have failed
+//   - No fetch has succeeded for cfg.IdleTimeout
+//   - cfg.MaxTotalBytes of bodies have been fetched
+//   - cfg.ProbeRootsFailFast finds no root reachable at startup
+//
+// All but the first and third conditions are reported as errors; a time
+// limit as ErrTimeLimit.
+func (c *Crawler) Crawl(ctx context.Context) error {
 	c.startTime = time.Now()
+	defer c.writeDiscoveredHosts()
+	defer c.writeGraph()
+
+	if splay := c.splayDelay(); splay > 0 {
+		log.Printf("startup splay: waiting %v before first fetch", splay)
+		if !sleepCtx(ctx, splay) {
+			return nil
+		}
+	}
+	if c.cfg.ProbeRootsOnStartup {
+		if err := c.probeRoots(ctx); err != nil {
+			return err
+		}
+	}
+	if c.cfg.WarmupConnections {
+		c.warmup(ctx)
+		c.startTime = time.Now() // cfg.Timeout measures from warm connections
+	}
+	c.rampStart = c.clock.Now()
+	c.markSuccess()
 
+	idle := 0 // consecutive iterations that visited nothing new
 	for {
-		if ctx.Err() != nil || c.isTimeoutReached() {
-			return
+		if c.cfg.ExitOnDrain > 0 && idle >= c.cfg.ExitOnDrain {
+			log.Printf("no new links for %d iterations, crawl drained", idle)
+			return nil
+		}
+		if c.tooManyErrors() {
+			return fmt.Errorf("%w: %d failed, max_errors is %d", ErrTooManyErrors, c.errorCount(), c.cfg.MaxErrors)
+		}
+		if c.isIdle() {
+			return fmt.Errorf("%w: idle_timeout is %dms", ErrIdleTimeout, c.cfg.IdleTimeout)
+		}
+		if c.byteLimitReached() {
+			return c.byteLimitError()
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if c.isTimeoutReached() {
+			return c.timeLimitError()
 		}
 
-		root := c.cfg.RootURLs[c.rand.Intn(len(c.cfg.RootURLs))]
-		body, err := c.fetch(ctx, root)
-		if err != nil {
+		if pause := c.sessionBreak(); pause > 0 {
+			log.Printf("session over, pausing for %v", pause)
+			if !sleepCtx(ctx, c.untilTimeout(pause)) {
+				return nil
+			}
+			c.markSuccess() // a planned pause is not idleness
+		}
+
+		seen := c.visitedCount()
+		c.crawlRoot(ctx)
+		if c.visitedCount() > seen {
+			idle = 0
+		} else {
+			idle++
+		}
+	}
+}
+
+// crawlRoot fetches cfg.RootsPerIteration random roots, one by default,
+// and walks a branch from their merged links, or crawls them level by
+// level with cfg.FrontierMode.
+func (c *Crawler) crawlRoot(ctx context.Context) {
+	roots := c.pickRoots()
+	c.branchStart = c.clock.Now()
+	c.links = c.links[:0]
+	clear(c.queuedAt)
+	clear(c.hops)
+	c.branchHost = hostOf(roots[0])
+	for _, root := range roots {
+		if !c.waitRevisit(ctx, root) {
+			return
+		}
+		if _, err := c.visit(ctx, root, 0); err != nil {
 			log.Printf("root fetch %s: %v", root, err)
-			continue
 		}
+	}
+	if len(c.links) == 0 && !c.sharedQueue() {
+		return
+	}
+
+	switch {
+	case c.cfg.FrontierMode:
+		c.breadthFirst(ctx)
+	case c.cfg.MaxWorkers > 1:
+		c.parallelBranch(ctx)
+	default:
+		c.depthFirst(ctx, 0)
+	}
+}
+
+// pickRoots draws cfg.RootsPerIteration distinct roots at random, or
+// all of them when there are fewer.
+func (c *Crawler) pickRoots() []string {
+	roots := c.roots
+	n := min(max(c.cfg.RootsPerIteration, 1), len(roots))
+	if n == 1 {
+		return []string{roots[c.rand.Intn(len(roots))]}
+	}
+	picked := make([]string, n)
+	for i, idx := range c.rand.Perm(len(roots))[:n] {
+		picked[i] = roots[idx]
+	}
+	return picked
+}
+
+// visit fetches a page, loads its assets and queues its links, which
+// it also returns. With cfg.StreamLinks the links are queued while the
+// page downloads, so those parsed before a failed read are kept.
+// With cfg.RenderJS they are read from the page as Chrome renders it.
+// depth counts the link hops from the root, for the Event reported to
+// the handler set with SetEventHandler.
+func (c *Crawler) visit(ctx context.Context, target string, depth int) ([]string, error) {
+	var (
+		body        []byte
+		contentType string
+		found       []string
+		streamed    bool
+		status      int
+		err         error
+	)
+	fetchCtx := ctx
+	if c.events != nil {
+		fetchCtx = withStatus(ctx, &status)
+	}
+	c.recordFetch(target)
+	start := time.Now()
+	if c.cfg.StreamLinks {
+		body, contentType, found, streamed, err = c.fetchStreaming(fetchCtx, target)
+	} else {
+		body, contentType, err = c.fetch(fetchCtx, target)
+	}
+	if c.events != nil {
+		c.events(Event{URL: target, Status: status, Latency: time.Since(start), Depth: depth, Err: err})
+	}
+	if err != nil && !isStatusError(err) {
+		return nil, err
+	}
+	// An error page has already been counted as a failure by fetch; its
+	// links are followed like any other page's.
+	c.fetchAssets(ctx, body, contentType, target)
+	if !streamed {
+		found = c.pageLinks(c.renderedBody(ctx, target, body, contentType), contentType, target)
+		c.push(ctx, found...)
+	}
+	if c.graph != nil {
+		c.graph.add(target, found)
+	}
+	return found, nil
+}
 
-		c.links = c.extractLinks(body, root)
-		if len(c.links) == 0 {
+// fetch performs a single HTTP GET, returns the page body (max 1 MiB)
+// and its Content-Type.
+// Failures are returned as *FetchError; for an error status the body of
+// the error page is returned alongside it.
+// Transport failures and error statuses (4xx/5xx) are counted toward
+// cfg.MaxErrors unless they are caused by ctx ending.
+// It is safe for concurrent use; concurrent fetches of the same URL share
+// one request, which a caller stops waiting for when its own ctx ends and
+// makes afresh when the caller that sent it gave up.
+func (c *Crawler) fetch(ctx context.Context, raw string) ([]byte, string, error) {
+	for {
+		f, shared := c.flights.do(ctx, raw, func() ([]byte, string, error) {
+			return c.fetchOnce(ctx, raw)
+		})
+		if shared && f.abandoned && ctx.Err() == nil {
+			// The caller that made the request gave up on it; the server
+			// did not fail, so make one of our own.
+			c.debugf("fetch %s: in-flight request abandoned, retrying", raw)
 			continue
 		}
+		if shared {
+			c.debugf("fetch %s: shared an in-flight request", raw)
+		}
+		return f.body, f.contentType, f.err
+	}
+}
 
-		c.depthFirst(ctx, 0)
+// fetchOnce performs the request behind fetch and records its outcome.
+func (c *Crawler) fetchOnce(ctx context.Context, raw string) ([]byte, string, error) {
+	if err := c.waitResumed(ctx); err != nil {
+		return nil, "", err
+	}
+	if c.byteLimitReached() {
+		return nil, "", &FetchError{URL: raw, Err: c.byteLimitError()}
 	}
+	if c.global != nil {
+		if err := c.global.wait(ctx, c.rampElapsed()); err != nil {
+			return nil, "", err
+		}
+	}
+	start := time.Now()
+	var (
+		body        []byte
+		contentType string
+		err         = c.injectFault(ctx, raw)
+	)
+	if err == nil {
+		body, contentType, err = c.dispatch(ctx, raw)
+		c.fetched.Add(int64(len(body)))
+	}
+	c.metrics.Count("requests", 1)
+	if c.transform != nil && (err == nil || isStatusError(err)) {
+		body = c.transform(body)
+	}
+	switch {
+	case err == nil:
+		c.markSuccess()
+		c.metrics.Timing("latency", time.Since(start))
+	case ctx.Err() == nil:
+		kind := classifyError(err)
+		c.count(func(s *Stats) {
+			s.Errors++
+			s.ErrorKinds.add(kind)
+		})
+		c.metrics.Count("errors", 1)
+		c.metrics.Count("errors."+string(kind), 1)
+	}
+	return body, contentType, err
 }
 
-// fetch performs a single HTTP GET, returns the page body (max 1 MiB).
-func (c *Crawler) fetch(ctx context.Context, raw string) ([]byte, error) {
+// request issues the GET for fetch.
+func (c *Crawler) request(ctx context.Context, raw string) ([]byte, string, error) {
+	page := raw
+	if target := c.rewrite(raw); target != raw {
+		c.debugf("rewrite %s -> %s", raw, target)
+		raw = target
+	}
+	raw = c.cacheBust(raw)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, raw, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", &FetchError{URL: raw, Err: err}
+	}
+	req.Header.Set("User-Agent", c.userAgent())
+	c.applyBrowserHeaders(req)
+	c.applyHostOverrides(req)
+
+	c.countRequest(req)
+
+	var newConn bool
+	if c.debug {
+		req = req.WithContext(httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) { newConn = !info.Reused },
+		}))
+	}
+
+	if c.pacer != nil {
+		if err := c.pacer.wait(ctx, req.URL.Host); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := c.waitHostRate(ctx, req.URL.Host); err != nil {
+		return nil, "", err
 	}
-	req.Header.Set("User-Agent", c.cfg.UserAgents[c.rand.Intn(len(c.cfg.UserAgents))])
 
-	resp, err := c.client.Do(req)
+	if c.cfg.RandomizeHeaderCase {
+		c.recaseHeaders(req.Header)
+	}
+	if c.signer != nil {
+		if err := c.signer(req); err != nil {
+			return nil, "", &FetchError{URL: raw, Err: fmt.Errorf("sign request: %w", err)}
+		}
+	}
+	c.logHeaders("request headers", raw, req.Header)
+	start := time.Now()
+	resp, err := c.do(req)
+	if c.pacer != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		c.pacer.observe(req.URL.Host, time.Since(start), status)
+	}
 	if err != nil {
-		return nil, err
+		if errors.Is(err, ErrRedirectLoop) {
+			c.count(func(s *Stats) { s.RedirectLoops++ })
+			if c.bad.add(req.URL.Host) {
+				log.Printf("fetch %s: %v; skipping host %s from now on", raw, err, req.URL.Host)
+			}
+		}
+		return nil, "", transportError(raw, err)
 	}
 	log.Printf("fetch %s: %s, Gorutine: %d", raw, resp.Status, runtime.NumGoroutine())
+	recordStatus(ctx, resp.StatusCode)
+	c.logHeaders("response headers", raw, resp.Header)
 	defer resp.Body.Close()
+	c.hosts.add(req.URL.Host)
+	if newConn {
+		c.debugf("new connection to %s negotiated %s", req.URL.Host, resp.Proto)
+	}
+	// An error status fails the fetch, but its body is still read: error
+	// pages such as a custom 404 often link back into the site.
+	var statusErr error
+	if resp.StatusCode >= http.StatusBadRequest {
+		statusErr = statusError(raw, resp.StatusCode)
+	}
+
+	if c.budget != nil {
+		want := int64(maxBodyBytes)
+		if resp.ContentLength >= 0 && resp.ContentLength < want {
+			want = resp.ContentLength
+		}
+		reserved, err := c.budget.acquire(ctx, want)
+		if err != nil {
+			return nil, "", transportError(raw, err)
+		}
+		defer c.budget.release(reserved)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	rc := resp.Body
+	if sink := linkSinkFrom(ctx); sink != nil {
+		br := bufio.NewReaderSize(resp.Body, charsetSniffLen)
+		rc = readCloser{br, resp.Body}
+		if c.canStream(contentType, br) {
+			w, wait := c.streamLinks(sink, page)
+			defer wait()
+			rc = readCloser{io.TeeReader(br, w), resp.Body}
+		}
+	}
+	body, truncated, err := readBody(ctx, rc, maxBodyBytes)
+	c.latency.observe(req.URL.Host, time.Since(start))
+	if truncated {
+		what := "body"
+		if resp.Uncompressed {
+			what = "decompressed body"
+		}
+		log.Printf("warning: fetch %s: %s truncated at %d bytes", raw, what, maxBodyBytes)
+	}
+	if err != nil {
+		return body, contentType, transportError(raw, err)
+	}
 
-	return io.ReadAll(io.LimitReader(resp.Body, 1<<20)) // 1 MiB safety cap
+	if cs := detectCharset(contentType, body); cs != "" {
+		utf, ok := toUTF8(body, cs)
+		if !ok {
+			c.debugf("fetch %s: no decoder for charset %q, parsing as-is", raw, cs)
+		}
+		body = utf
+	}
+	return body, contentType, statusErr
 }
 
-// extractLinks returns all acceptable links found in the supplied HTML.
+// pageLinks extracts links from a fetched body, reading JSON documents
+// when cfg.FollowJSONLinks is set and HTML otherwise. With
+// cfg.DedupByContent a body identical to one already parsed yields no
+// links, since its links were queued the first time.
+func (c *Crawler) pageLinks(body []byte, contentType, base string) []string {
+	if c.cfg.DedupByContent && c.bodies.add(body) {
+		c.count(func(s *Stats) { s.DuplicateBodies++ })
+		c.debugf("skip links of %s: body already seen", shorten(base, maxLoggedURL))
+		return nil
+	}
+	if c.cfg.FollowJSONLinks && isJSON(contentType) {
+		return c.extractJSONLinks(body, base)
+	}
+	return c.extractLinks(body, base)
+}
+
+// extractLinks returns all acceptable links found in the supplied HTML:
+// <a href> targets, the variants named by <link rel="alternate"> when
+// cfg.FollowAlternates is set and, with cfg.ScanDataAttributes, the
+// targets of data-href, data-url and onclick navigation on any element.
+// A <link rel="canonical"> pointing elsewhere marks its target visited
+// first, since base has just served that content.
 // It uses the html tokenizer instead of brittle regexes.
 func (c *Crawler) extractLinks(body []byte, base string) []string {
-	z := html.NewTokenizer(bytes.NewReader(body))
-	baseURL, _ := url.Parse(base)
+	var candidates []string
+	if c.scanLinks(bytes.NewReader(body), base, func(link string) { candidates = append(candidates, link) }) {
+		c.debugf("skip %d links: %s declares robots nofollow", len(candidates), base)
+		return nil
+	}
 
 	var out []string
-	for {
-		switch z.Next() {
-		case html.ErrorToken:
-			return out
-		case html.StartTagToken:
-			t := z.Token()
-			if t.DataAtom != atom.A {
+	for _, href := range candidates {
+		if c.keepLink(base, href) {
+			out = append(out, href)
+		}
+	}
+	return out
+}
+
+// scanLinks tokenizes the HTML read from r and passes each link
+// candidate for extractLinks to emit as soon as its tag is complete,
+// marking a canonical URL visited when it is seen. Under
+// cfg.ObeyNofollow it stops emitting at a robots nofollow <meta> and
+// reports that it found one. r is read to the end either way.
+func (c *Crawler) scanLinks(r io.Reader, base string, emit func(link string)) (nofollow bool) {
+	z := html.NewTokenizer(r)
+	baseURL, _ := url.Parse(base)
+
+	var canonical bool
+	for tt := z.Next(); tt != html.ErrorToken; tt = z.Next() {
+		if nofollow || (tt != html.StartTagToken && tt != html.SelfClosingTagToken) {
+			continue
+		}
+		t := z.Token()
+		if t.DataAtom == atom.Meta && c.cfg.ObeyNofollow && robotsNofollow(t) {
+			nofollow = true
+			continue
+		}
+		if rel, _ := attrVal(t, "rel"); c.cfg.ScanDataAttributes && !(c.cfg.ObeyNofollow && hasToken(rel, "nofollow")) {
+			for _, href := range scriptedHrefs(t) {
+				if link := c.normalize(href, baseURL); !selfLink(link, baseURL) {
+					emit(link)
+				}
+			}
+		}
+		href, ok := attrVal(t, "href")
+		if !ok {
+			continue
+		}
+		switch t.DataAtom {
+		case atom.A:
+			if rel, _ := attrVal(t, "rel"); c.cfg.ObeyNofollow && hasToken(rel, "nofollow") {
+				c.debugf("skip %s: rel=nofollow on %s", shorten(href, maxLoggedURL), base)
 				continue
 			}
-			for _, a := range t.Attr {
-				if a.Key != "href" {
-					continue
-				}
-				href := c.normalize(a.Val, baseURL)
-				if c.accept(href) {
-					out = append(out, href)
+			link := c.normalize(href, baseURL)
+			if selfLink(link, baseURL) {
+				c.debugf("skip %q: links back to %s", href, base)
+				continue
+			}
+			emit(link)
+		case atom.Link:
+			rel, _ := attrVal(t, "rel")
+			for _, r := range strings.Fields(strings.ToLower(rel)) {
+				switch {
+				case r == "canonical" && !canonical:
+					canonical = true
+					c.markCanonical(c.normalize(href, baseURL), base)
+				case r == "alternate" && c.cfg.FollowAlternates:
+					emit(c.normalize(href, baseURL))
 				}
 			}
 		}
 	}
+	return nofollow
 }
 
-// normalize resolves relative links against base and tidies schemeless // URLs.
+// markCanonical marks the canonical URL declared by base visited, unless
+// it is base itself.
+func (c *Crawler) markCanonical(canonical, base string) {
+	if canonical == "" || canonical == base {
+		return
+	}
+	c.debugf("%s declares canonical %s", base, canonical)
+	if _, err := c.visited.Add(context.Background(), c.dedupKey(canonical)); err != nil {
+		c.debugf("visited set: %v", err)
+	}
+}
+
+// robotsNofollow reports whether t is a robots <meta> tag forbidding
+// link following, through "nofollow" or "none".
+func robotsNofollow(t html.Token) bool {
+	if name, _ := attrVal(t, "name"); !strings.EqualFold(strings.TrimSpace(name), "robots") {
+		return false
+	}
+	content, _ := attrVal(t, "content")
+	for _, d := range strings.Split(content, ",") {
+		if d = strings.TrimSpace(d); strings.EqualFold(d, "nofollow") || strings.EqualFold(d, "none") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasToken reports whether the space-separated list attr contains tok,
+// ignoring case, as in rel attributes.
+func hasToken(attr, tok string) bool {
+	for _, f := range strings.Fields(attr) {
+		if strings.EqualFold(f, tok) {
+			return true
+		}
+	}
+	return false
+}
+
+// attrVal returns the value of t's attribute key.
+func attrVal(t html.Token, key string) (string, bool) {
+	for _, a := range t.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// normalize resolves relative links against base and tidies schemeless
+// URLs. Fragments and a bare trailing "?" are dropped, since they name
+// the same resource, and internationalized hosts are converted to
+// punycode so that both spellings dedup as one.
 func (c *Crawler) normalize(href string, base *url.URL) string {
 	if strings.HasPrefix(href, "//") {
-		return base.Scheme + ":" + href
+		href = c.relativeScheme(base) + ":" + href
 	}
 	ref, err := url.Parse(href)
 	if err != nil {
 		return ""
 	}
-	return base.ResolveReference(ref).String()
+	u := base.ResolveReference(ref)
+	u.Fragment, u.RawFragment, u.ForceQuery = "", "", false
+	if u.Host, err = asciiHost(u.Host); err != nil {
+		return ""
+	}
+	return u.String()
+}
+
+// asciiHost converts the name in host, which may carry a port, to its
+// punycode form. ASCII hosts are returned as they are.
+func asciiHost(host string) (string, error) {
+	if isASCII(host) {
+		return host, nil
+	}
+	name, port := host, ""
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.Contains(host[i:], "]") {
+		name, port = host[:i], host[i:]
+	}
+	name, err := idna.Lookup.ToASCII(name)
+	if err != nil {
+		return "", err
+	}
+	return name + port, nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// selfLink reports whether link, as returned by normalize, leads back to
+// base itself, as href="", "#top" and "?" do.
+func selfLink(link string, base *url.URL) bool {
+	page := *base
+	page.Fragment, page.RawFragment, page.ForceQuery = "", "", false
+	return link == page.String()
+}
+
+// relativeScheme picks the scheme for a protocol-relative link found on
+// base, honouring cfg.HTTPSRatio when set.
+func (c *Crawler) relativeScheme(base *url.URL) string {
+	if c.cfg.HTTPSRatio == nil {
+		return base.Scheme
+	}
+	if c.rand.Float64() < *c.cfg.HTTPSRatio {
+		return "https"
+	}
+	return "http"
+}
+
+// keepLink reports whether link, found on page, is queued: it must not
+// be mixed content blocked by cfg.BlockMixedContent, must pass accept
+// and must survive cfg.LinkSampleRate.
+func (c *Crawler) keepLink(page, link string) bool {
+	if c.mixedContent(page, link) {
+		c.debugf("skip %s: http link on https page %s", shorten(link, maxLoggedURL), shorten(page, maxLoggedURL))
+		return false
+	}
+	return c.accept(link) && c.sampled()
+}
+
+// mixedContent reports whether cfg.BlockMixedContent forbids following
+// link from page, as browsers refuse http content on https pages.
+func (c *Crawler) mixedContent(page, link string) bool {
+	return c.cfg.BlockMixedContent && hasScheme(page, "https") && hasScheme(link, "http")
+}
+
+// hasScheme reports whether raw starts with scheme followed by a colon,
+// in any case.
+func hasScheme(raw, scheme string) bool {
+	return len(raw) > len(scheme) && raw[len(scheme)] == ':' && strings.EqualFold(raw[:len(scheme)], scheme)
+}
+
+// sampled reports whether an accepted link is kept under
+// cfg.LinkSampleRate.
+func (c *Crawler) sampled() bool {
+	p := c.cfg.LinkSampleRate
+	return p == nil || c.rand.Float64() < *p
 }
 
 // accept applies validation, blacklist and dedup rules.
 func (c *Crawler) accept(link string) bool {
-	if link == "" {
+	if reason := c.rejectReason(link); reason != "" {
+		c.debugf("skip %s: %s", shorten(link, maxLoggedURL), reason)
 		return false
 	}
-	if _, seen := c.visited[link]; seen {
-		return false
+	return true
+}
+
+// rejectReason explains why link must not be queued, or returns "" when
+// it is acceptable.
+func (c *Crawler) rejectReason(link string) string {
+	if link == "" {
+		return "unparseable link"
+	}
+	if limit := c.maxURLLength(); len(link) > limit {
+		return fmt.Sprintf("URL is %d bytes, limit %d", len(link), limit)
+	}
+	if scheme, _, ok := strings.Cut(link, ":"); ok && (strings.EqualFold(scheme, "data") || strings.EqualFold(scheme, "blob")) {
+		return strings.ToLower(scheme) + ": URI"
+	}
+	// Link extraction has no context; the backend bounds its own calls.
+	if c.isVisited(context.Background(), link) {
+		return "already visited"
+	}
+	if wait := c.revisitWait(link); wait > 0 {
+		return fmt.Sprintf("fetched %v ago, min_revisit_interval %dms", time.Duration(c.cfg.MinRevisitInterval)*time.Millisecond-wait, c.cfg.MinRevisitInterval)
+	}
+	if blk := c.blacklistedBy(link); blk != "" {
+		return fmt.Sprintf("blacklisted by %q", blk)
 	}
+	u, err := url.ParseRequestURI(link)
+	if err != nil {
+		return "parse failure: " + err.Error()
+	}
+	if _, ok := c.fetchers[u.Scheme]; !ok {
+		return fmt.Sprintf("unsupported scheme %q", u.Scheme)
+	}
+	if !c.allowedPath(u) {
+		return "path outside allowed prefixes"
+	}
+	if below, deep := c.pathTooDeep(u); deep {
+		return fmt.Sprintf("path %d segments below its root, max_path_depth %d", below, c.cfg.MaxPathDepth)
+	}
+	if c.cfg.MaxHosts > 0 && !c.hosts.has(u.Host) && c.hosts.len() >= c.cfg.MaxHosts {
+		return fmt.Sprintf("max_hosts %d reached", c.cfg.MaxHosts)
+	}
+	if c.bad.has(u.Host) {
+		return "host has a redirect loop"
+	}
+	if c.isSlowHost(u.Host) {
+		return "host exceeds latency budget"
+	}
+	if blk := c.softAvoidedBy(link); blk != "" {
+		return fmt.Sprintf("avoided by %q (weight %g)", blk, c.cfg.BlacklistWeights[blk])
+	}
+	return ""
+}
+
+// blacklistedBy returns the cfg.BlacklistedURLs entry link contains, or
+// "" when none does. Entries given a weight below 1 in
+// cfg.BlacklistWeights are soft and left to softAvoidedBy.
+func (c *Crawler) blacklistedBy(link string) string {
 	for _, blk := range c.cfg.BlacklistedURLs {
+		if w, soft := c.cfg.BlacklistWeights[blk]; soft && w < 1 {
+			continue
+		}
 		if strings.Contains(link, blk) {
-			return false
+			return blk
+		}
+	}
+	return ""
+}
+
+// softAvoidedBy draws, for each cfg.BlacklistWeights pattern link
+// contains, whether to drop it with the pattern's weight as probability,
+// and returns the pattern that dropped it, or "". Patterns are tried in
+// sorted order so that a fixed seed gives the same draws.
+func (c *Crawler) softAvoidedBy(link string) string {
+	for _, blk := range c.avoid {
+		if strings.Contains(link, blk) && c.rand.Float64() < c.cfg.BlacklistWeights[blk] {
+			return blk
+		}
+	}
+	return ""
+}
+
+// maxURLLength returns cfg.MaxURLLength or its default.
+func (c *Crawler) maxURLLength() int {
+	if c.cfg.MaxURLLength > 0 {
+		return c.cfg.MaxURLLength
+	}
+	return defaultMaxURLLength
+}
+
+// shorten truncates s to n bytes for logging.
+func shorten(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
+
+// allowedPath reports whether u's path starts with one of the prefixes
+// configured for its host, falling back to cfg.PathPrefixes.
+func (c *Crawler) allowedPath(u *url.URL) bool {
+	_, prefixes, ok := lookupHost(c.cfg.HostPathPrefixes, u.Host)
+	if !ok {
+		prefixes = c.cfg.PathPrefixes
+	}
+	if len(prefixes) == 0 {
+		return true
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(path, p) {
+			return true
 		}
 	}
-	_, err := url.ParseRequestURI(link)
-	return err == nil
+	return false
+}
+
+// isSlowHost reports whether host's rolling average latency exceeds
+// cfg.MaxHostLatency.
+func (c *Crawler) isSlowHost(host string) bool {
+	if c.cfg.MaxHostLatency <= 0 {
+		return false
+	}
+	return c.latency.average(host) > time.Duration(c.cfg.MaxHostLatency)*time.Millisecond
 }
 
 // depthFirst walks one branch until MaxDepth or stop conditions fire.
+// With cfg.ResetDepthOnHostChange, crossing to another host restarts
+// the depth count at cfg.HostChangeDepth. Each page nests one call, so
+// cfg.MaxRecursion bounds the stack whatever the depth, and
+// cfg.MaxBranchDuration bounds the time spent since the root was picked.
 func (c *Crawler) depthFirst(ctx context.Context, depth int) {
-	if depth >= c.cfg.MaxDepth || ctx.Err() != nil || c.isTimeoutReached() {
+	if depth >= c.cfg.MaxDepth || c.stopped(ctx) {
 		return
 	}
-	if len(c.links) == 0 {
+	if c.branchExpired() {
+		c.debugf("stop branch at depth %d: max_branch_duration %dms spent", depth, c.cfg.MaxBranchDuration)
 		return
 	}
+	if limit := c.maxRecursion(); c.frames >= limit {
+		log.Printf("warning: branch stopped after %d pages by max_recursion", limit)
+		return
+	}
+	c.frames++
+	defer func() { c.frames-- }()
+	target, ok := c.nextTarget(ctx)
+	if !ok {
+		return
+	}
+	if host := hostOf(target); host != c.branchHost {
+		if c.cfg.ResetDepthOnHostChange && c.branchHost != "" && depth != c.cfg.HostChangeDepth {
+			c.debugf("branch moves from %s to %s: depth %d reset to %d", c.branchHost, host, depth, c.cfg.HostChangeDepth)
+			depth = c.cfg.HostChangeDepth
+		}
+		c.branchHost = host
+	}
 
-	idx := c.rand.Intn(len(c.links))
-	target := c.links[idx]
-	c.links = append(c.links[:idx], c.links[idx+1:]...)
-	c.visited[target] = struct{}{}
-
-	body, err := c.fetch(ctx, target)
+	found, err := c.visit(ctx, target, depth+1)
 	if err != nil {
 		log.Printf("visit %s: %v", target, err)
 		return
 	}
+	if len(found) < c.cfg.MinLinksToDescend {
+		c.debugf("stop branch at %s: %d links < min_links_to_descend %d", target, len(found), c.cfg.MinLinksToDescend)
+		return
+	}
+	if p := c.cfg.DescendProbability; p != nil && c.rand.Float64() >= *p {
+		c.debugf("stop branch at %s: depth %d by descend_probability", target, depth+1)
+		return
+	}
 
-	c.links = append(c.links, c.extractLinks(body, target)...)
-
-	sleep := time.Duration(c.rand.Intn(c.cfg.MaxSleep-c.cfg.MinSleep+1)+c.cfg.MinSleep) * time.Microsecond
-	time.Sleep(sleep)
+	time.Sleep(c.thinkTime(hostOf(target)))
 
 	c.depthFirst(ctx, depth+1)
 }
 
+// defaultMaxRecursion is the cfg.MaxRecursion used when it is unset.
+const defaultMaxRecursion = 10000
+
+func (c *Crawler) maxRecursion() int {
+	if c.cfg.MaxRecursion > 0 {
+		return c.cfg.MaxRecursion
+	}
+	return defaultMaxRecursion
+}
+
+// nextTarget pops random links from the queue until it finds one not
+// yet visited, and marks it visited before it is fetched.
+func (c *Crawler) nextTarget(ctx context.Context) (string, bool) {
+	for {
+		target, ok, err := c.queue.Pop(ctx)
+		if err != nil {
+			log.Printf("link queue: %v", err)
+			return "", false
+		}
+		if !ok {
+			return "", false
+		}
+		if c.markVisited(ctx, target) {
+			return target, true
+		}
+	}
+}
+
+// hostOf returns the host of link, or "" when it does not parse.
+func hostOf(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// pickLink returns the queue index of a random link. With
+// cfg.PreferNewHosts it picks among links to hosts that have not
+// answered yet, when there are any.
+func (c *Crawler) pickLink() int {
+	if c.cfg.PreferNewHosts {
+		var fresh []int
+		for i, link := range c.links {
+			if host := hostOf(link); host != "" && !c.hosts.has(host) {
+				fresh = append(fresh, i)
+			}
+		}
+		if len(fresh) > 0 {
+			return fresh[c.rand.Intn(len(fresh))]
+		}
+	}
+	return c.rand.Intn(len(c.links))
+}
+
+// enqueue appends links to the queue, discarding the oldest entries when
+// that would exceed cfg.MaxQueueSize. With cfg.DeterministicOrder the
+// batch is sorted first.
+func (c *Crawler) enqueue(links []string) {
+	if c.cfg.DeterministicOrder {
+		links = append([]string(nil), links...)
+		sort.Strings(links)
+	}
+	c.links = append(c.links, links...)
+	if c.cfg.MaxLinkAge > 0 {
+		now := c.clock.Now()
+		for _, link := range links {
+			c.queuedAt[link] = now
+		}
+	}
+	if c.cfg.MaxQueueSize <= 0 || len(c.links) <= c.cfg.MaxQueueSize {
+		return
+	}
+	drop := len(c.links) - c.cfg.MaxQueueSize
+	for _, link := range c.links[:drop] {
+		delete(c.queuedAt, link)
+	}
+	c.links = append(c.links[:0], c.links[drop:]...)
+	c.count(func(s *Stats) { s.QueueDrops += drop })
+}
+
+// thinkTime returns the pause taken after reading a page on host,
+// drawn uniformly from [MinSleep, MaxSleep], or the host's range in
+// cfg.HostProfiles, and stretched outside cfg.ActiveHours. With
+// cfg.DelaySamplesFile it is drawn from the file's delays instead,
+// except for hosts whose profile sets a range. A range that is empty or
+// inverted falls back to its minimum rather than panicking in Intn.
+func (c *Crawler) thinkTime(host string) time.Duration {
+	if d, ok := c.sampledDelay(host); ok {
+		return time.Duration(float64(d) * c.scheduleFactor())
+	}
+	minSleep, maxSleep := c.sleepRange(host)
+	sleep := minSleep
+	if maxSleep > minSleep {
+		sleep += c.rand.Intn(maxSleep - minSleep + 1)
+	}
+	return time.Duration(float64(sleep) * c.scheduleFactor() * float64(time.Microsecond))
+}
+
+// writeDiscoveredHosts saves Hosts to cfg.DiscoveredHostsFile, one per
+// line, when a file is configured.
+func (c *Crawler) writeDiscoveredHosts() {
+	if c.cfg.DiscoveredHostsFile == "" {
+		return
+	}
+	hosts := c.Hosts()
+	data := strings.Join(hosts, "\n")
+	if len(hosts) > 0 {
+		data += "\n"
+	}
+	if err := os.WriteFile(c.cfg.DiscoveredHostsFile, []byte(data), 0o600); err != nil {
+		log.Printf("write discovered hosts: %v", err)
+		return
+	}
+	log.Printf("wrote %d discovered hosts to %s", len(hosts), c.cfg.DiscoveredHostsFile)
+}
+
+// splayDelay returns a random startup delay in [0, cfg.StartupSplay].
+func (c *Crawler) splayDelay() time.Duration {
+	if c.cfg.StartupSplay <= 0 {
+		return 0
+	}
+	limit := time.Duration(c.cfg.StartupSplay) * time.Millisecond
+	return time.Duration(c.rand.Int63n(int64(limit) + 1))
+}
+
+// sleepCtx pauses for d or until ctx is done, reporting whether the full
+// duration elapsed.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// stopped reports whether the current branch must end early: ctx is
+// done or the timeout, error or idle limit has been reached.
+func (c *Crawler) stopped(ctx context.Context) bool {
+	return ctx.Err() != nil || c.isTimeoutReached() || c.tooManyErrors() || c.isIdle() || c.byteLimitReached()
+}
+
+// byteLimitReached reports whether cfg.MaxTotalBytes of bodies have been
+// fetched.
+func (c *Crawler) byteLimitReached() bool {
+	return c.cfg.MaxTotalBytes > 0 && c.fetched.Load() >= c.cfg.MaxTotalBytes
+}
+
+func (c *Crawler) byteLimitError() error {
+	return fmt.Errorf("%w: %d bytes fetched, max_total_bytes is %d", ErrByteLimit, c.fetched.Load(), c.cfg.MaxTotalBytes)
+}
+
+// tooManyErrors reports whether more than cfg.MaxErrors requests failed.
+func (c *Crawler) tooManyErrors() bool {
+	return c.cfg.MaxErrors > 0 && c.errorCount() > c.cfg.MaxErrors
+}
+
+func (c *Crawler) errorCount() int {
+	return c.Snapshot().Errors
+}
+
+func (c *Crawler) markSuccess() {
+	c.statsMu.Lock()
+	c.lastSuccess = time.Now()
+	c.statsMu.Unlock()
+}
+
+// isIdle reports whether cfg.IdleTimeout has passed since the last
+// successful fetch.
+func (c *Crawler) isIdle() bool {
+	if c.cfg.IdleTimeout <= 0 {
+		return false
+	}
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	return time.Since(c.lastSuccess) > time.Duration(c.cfg.IdleTimeout)*time.Millisecond
+}
+
+// untilTimeout shortens d so that a wait of d ends no later than
+// cfg.Timeout or cfg.StopAt.
+func (c *Crawler) untilTimeout(d time.Duration) time.Duration {
+	if c.cfg.Timeout != 0 {
+		d = min(d, time.Duration(c.cfg.Timeout)*time.Second-time.Since(c.startTime))
+	}
+	if !c.stopAt.IsZero() {
+		d = min(d, c.stopAt.Sub(c.clock.Now()))
+	}
+	return max(d, 0)
+}
+
+// timeLimitError reports which time limit ended the crawl.
+func (c *Crawler) timeLimitError() error {
+	if !c.stopAt.IsZero() && !c.clock.Now().Before(c.stopAt) {
+		return fmt.Errorf("%w: stop_at %s passed", ErrTimeLimit, c.cfg.StopAt)
+	}
+	return fmt.Errorf("%w: timeout is %ds", ErrTimeLimit, c.cfg.Timeout)
+}
+
+// isTimeoutReached reports whether cfg.Timeout has elapsed since the
+// start or the clock has passed cfg.StopAt.
 func (c *Crawler) isTimeoutReached() bool {
+	if !c.stopAt.IsZero() && !c.clock.Now().Before(c.stopAt) {
+		return true
+	}
 	if c.cfg.Timeout == 0 {
 		return false
 	}
@@ -0,0 +1,1010 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/calpa/urusai/config"
+)
+
+// testConfig returns a minimal configuration suitable for unit tests.
+func testConfig(roots ...string) *config.Config {
+	return &config.Config{
+		MaxDepth:   3,
+		MinSleep:   0,
+		MaxSleep:   0,
+		RootURLs:   roots,
+		UserAgents: []string{"urusai-test"},
+	}
+}
+
+// newTestCrawler builds a crawler for cfg, failing the test on error.
+func newTestCrawler(t *testing.T, cfg *config.Config) *Crawler {
+	t.Helper()
+	c, err := NewCrawler(cfg)
+	if err != nil {
+		t.Fatalf("NewCrawler: %v", err)
+	}
+	return c
+}
+
+func TestAccept(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.BlacklistedURLs = []string{".png"}
+	c := newTestCrawler(t, cfg)
+	c.markVisited(context.Background(), "http://example.com/seen")
+
+	testCases := []struct {
+		link string
+		want bool
+	}{
+		{"http://example.com/page", true},
+		{"http://example.com/seen", false},
+		{"http://example.com/logo.png", false},
+		{"", false},
+		{"not a url", false},
+		{"mailto:someone@example.com", false},
+	}
+	for _, tc := range testCases {
+		if got := c.accept(tc.link); got != tc.want {
+			t.Errorf("accept(%q) = %v, expected %v", tc.link, got, tc.want)
+		}
+	}
+}
+
+func TestRejectReason(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.BlacklistedURLs = []string{"logout"}
+	c := newTestCrawler(t, cfg)
+	c.markVisited(context.Background(), "http://example.com/seen")
+
+	testCases := []struct {
+		link string
+		want string
+	}{
+		{"http://example.com/ok", ""},
+		{"http://example.com/seen", "already visited"},
+		{"http://example.com/logout", `blacklisted by "logout"`},
+		{"javascript:void(0)", `unsupported scheme "javascript"`},
+		{"data:text/html;base64,PGgxPg==", "data: URI"},
+		{"BLOB:https://example.com/1234", "blob: URI"},
+		{"http://example.com/?q=" + strings.Repeat("x", 2048), "URL is 2070 bytes, limit 2048"},
+		{"", "unparseable link"},
+	}
+	for _, tc := range testCases {
+		if got := c.rejectReason(tc.link); got != tc.want {
+			t.Errorf("rejectReason(%q) = %q, expected %q", tc.link, got, tc.want)
+		}
+	}
+}
+
+func TestMaxURLLength(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.MaxURLLength = 40
+	c := newTestCrawler(t, cfg)
+
+	if !c.accept("http://example.com/short") {
+		t.Error("Expected a short URL to be accepted")
+	}
+	long := "http://example.com/" + strings.Repeat("a", 30)
+	if c.accept(long) {
+		t.Errorf("Expected a %d-byte URL to be rejected with max_url_length 40", len(long))
+	}
+
+	// Over-long links found on a page never reach the queue.
+	page := []byte(`<a href="/ok">ok</a><a href="/` + strings.Repeat("b", 100) + `">long</a>`)
+	if links := c.extractLinks(page, "http://example.com/"); len(links) != 1 {
+		t.Errorf("Expected only the short link to be extracted, got %v", links)
+	}
+}
+
+func TestProtocolRelativeScheme(t *testing.T) {
+	ratio := func(f float64) *float64 { return &f }
+	page := []byte(`<a href="//other.example/a">a</a>`)
+
+	testCases := []struct {
+		name  string
+		ratio *float64
+		base  string
+		want  string
+	}{
+		{"Inherit http", nil, "http://example.com/", "http://other.example/a"},
+		{"Inherit https", nil, "https://example.com/", "https://other.example/a"},
+		{"Always https", ratio(1), "http://example.com/", "https://other.example/a"},
+		{"Always http", ratio(0), "https://example.com/", "http://other.example/a"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig(tc.base)
+			cfg.HTTPSRatio = tc.ratio
+			c := newTestCrawler(t, cfg)
+
+			links := c.extractLinks(page, tc.base)
+			if len(links) != 1 || links[0] != tc.want {
+				t.Errorf("Expected [%s], got %v", tc.want, links)
+			}
+		})
+	}
+}
+
+func TestProtocolRelativeSchemeMix(t *testing.T) {
+	cfg := testConfig("http://example.com/")
+	cfg.HTTPSRatio = new(float64)
+	*cfg.HTTPSRatio = 0.5
+	c := newTestCrawler(t, cfg)
+	c.rand.Seed(1)
+
+	base, _ := url.Parse("http://example.com/")
+	https := 0
+	for i := 0; i < 1000; i++ {
+		if strings.HasPrefix(c.normalize("//other.example/", base), "https:") {
+			https++
+		}
+	}
+	if https < 400 || https > 600 {
+		t.Errorf("Expected roughly half https links, got %d/1000", https)
+	}
+}
+
+func TestFetchCountsScheme(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	tls := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tls.Close()
+
+	c := newTestCrawler(t, testConfig(srv.URL))
+	c.client = tls.Client()
+	for _, u := range []string{srv.URL, srv.URL, tls.URL} {
+		if _, _, err := c.fetch(context.Background(), u); err != nil {
+			t.Fatalf("fetch %s: %v", u, err)
+		}
+	}
+
+	if c.stats.Requests != 3 || c.stats.HTTPRequests != 2 || c.stats.HTTPSRequests != 1 {
+		t.Errorf("Expected 3 requests split 2 http / 1 https, got %+v", c.stats)
+	}
+}
+
+func TestThinkTimeInvertedRange(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.MinSleep, cfg.MaxSleep = 7, 3
+	c := newTestCrawler(t, cfg)
+
+	for i := 0; i < 10; i++ {
+		if got := c.thinkTime("example.com"); got != 7*time.Microsecond {
+			t.Fatalf("Expected fixed MinSleep pause of 7µs, got %v", got)
+		}
+	}
+}
+
+func TestThinkTimeRange(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.MinSleep, cfg.MaxSleep = 2, 4
+	c := newTestCrawler(t, cfg)
+
+	for i := 0; i < 100; i++ {
+		got := c.thinkTime("example.com")
+		if got < 2*time.Microsecond || got > 4*time.Microsecond {
+			t.Fatalf("Expected pause within [2µs, 4µs], got %v", got)
+		}
+	}
+}
+
+// linkSite serves pages whose bodies link to the paths listed in pages,
+// counting every request it receives.
+func linkSite(t *testing.T, pages map[string][]string, hits *int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		for _, link := range pages[r.URL.Path] {
+			fmt.Fprintf(w, `<a href="%s">link</a>`, link)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestMinLinksToDescend(t *testing.T) {
+	pages := map[string][]string{
+		"/poor": {"/p1"},
+		"/rich": {"/r1", "/r2", "/r3"},
+		"/r1":   {"/r1a", "/r1b", "/r1c"},
+		"/r2":   {"/r2a", "/r2b", "/r2c"},
+		"/r3":   {"/r3a", "/r3b", "/r3c"},
+	}
+
+	testCases := []struct {
+		start string
+		want  int32
+	}{
+		{"/poor", 1},
+		{"/rich", 3},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.start, func(t *testing.T) {
+			var hits int32
+			srv := linkSite(t, pages, &hits)
+
+			cfg := testConfig(srv.URL)
+			cfg.MaxDepth = 3
+			cfg.MinLinksToDescend = 2
+			c := newTestCrawler(t, cfg)
+			c.links = []string{srv.URL + tc.start}
+			c.depthFirst(context.Background(), 0)
+
+			if hits != tc.want {
+				t.Errorf("Expected %d fetches starting from %s, got %d", tc.want, tc.start, hits)
+			}
+		})
+	}
+}
+
+func TestSplayDelay(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	c := newTestCrawler(t, cfg)
+	if d := c.splayDelay(); d != 0 {
+		t.Errorf("Expected no splay by default, got %v", d)
+	}
+
+	cfg.StartupSplay = 100
+	for i := 0; i < 100; i++ {
+		if d := c.splayDelay(); d < 0 || d > 100*time.Millisecond {
+			t.Fatalf("Expected splay within [0, 100ms], got %v", d)
+		}
+	}
+}
+
+func TestCrawlSplayRespectsContext(t *testing.T) {
+	var hits int32
+	srv := linkSite(t, nil, &hits)
+
+	cfg := testConfig(srv.URL)
+	cfg.StartupSplay = int(time.Hour / time.Millisecond)
+	c := newTestCrawler(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := c.Crawl(ctx); err != nil {
+		t.Errorf("Expected clean exit on cancel, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Crawl to abandon the splay on cancel, took %v", elapsed)
+	}
+	if hits != 0 {
+		t.Errorf("Expected no fetches during splay, got %d", hits)
+	}
+}
+
+func TestEnqueueDropsOldest(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.MaxQueueSize = 3
+	c := newTestCrawler(t, cfg)
+
+	c.enqueue([]string{"a", "b"})
+	c.enqueue([]string{"c", "d", "e"})
+
+	want := []string{"c", "d", "e"}
+	if strings.Join(c.links, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected queue %v, got %v", want, c.links)
+	}
+	if c.stats.QueueDrops != 2 {
+		t.Errorf("Expected 2 drops, got %d", c.stats.QueueDrops)
+	}
+}
+
+func TestMaxLinkAge(t *testing.T) {
+	cfg := testConfig("http://example.com/")
+	cfg.MaxLinkAge = 1000
+	c := newTestCrawler(t, cfg)
+	clk := &fakeClock{now: time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)}
+	c.SetClock(clk)
+	ctx := context.Background()
+
+	c.enqueue([]string{"http://example.com/old1", "http://example.com/old2"})
+	clk.advance(600 * time.Millisecond)
+	c.enqueue([]string{"http://example.com/new"})
+	clk.advance(600 * time.Millisecond)
+
+	if got, ok := c.nextTarget(ctx); !ok || got != "http://example.com/new" {
+		t.Errorf("Expected only the recent link to be visited, got %q, %v", got, ok)
+	}
+	if got, ok := c.nextTarget(ctx); ok {
+		t.Errorf("Expected stale links to be dropped, got %q", got)
+	}
+	if n := c.Snapshot().StaleLinks; n != 2 {
+		t.Errorf("Expected 2 stale links, got %d", n)
+	}
+	if len(c.queuedAt) != 0 {
+		t.Errorf("Expected no timestamps left for an empty queue, got %v", c.queuedAt)
+	}
+}
+
+func TestQueueNeverExceedsCap(t *testing.T) {
+	pages := map[string][]string{}
+	for i := 0; i < 20; i++ {
+		var links []string
+		for j := 0; j < 20; j++ {
+			links = append(links, fmt.Sprintf("/p%d", i*20+j))
+		}
+		pages[fmt.Sprintf("/p%d", i)] = links
+	}
+	pages["/"] = pages["/p0"]
+
+	var hits int32
+	srv := linkSite(t, pages, &hits)
+	cfg := testConfig(srv.URL)
+	cfg.MaxDepth = 10
+	cfg.MaxQueueSize = 7
+	c := newTestCrawler(t, cfg)
+
+	c.links = nil
+	c.enqueue(c.extractLinks([]byte(`<a href="/p1">x</a><a href="/p2">y</a>`), srv.URL))
+	for depth := 0; depth < cfg.MaxDepth; depth++ {
+		c.depthFirst(context.Background(), cfg.MaxDepth-1)
+		if len(c.links) > cfg.MaxQueueSize {
+			t.Fatalf("Queue grew to %d, cap is %d", len(c.links), cfg.MaxQueueSize)
+		}
+	}
+	if c.stats.QueueDrops == 0 {
+		t.Error("Expected link-rich pages to cause drops")
+	}
+}
+
+func TestCrawlAbortsAfterMaxErrors(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		http.Error(w, "down", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.MaxErrors = 3
+	c := newTestCrawler(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := c.Crawl(ctx)
+	if !errors.Is(err, ErrTooManyErrors) {
+		t.Fatalf("Expected ErrTooManyErrors, got %v", err)
+	}
+	if hits != 4 {
+		t.Errorf("Expected the crawl to stop after the 4th failure, server saw %d requests", hits)
+	}
+}
+
+func TestErrorPageLinksAreFollowed(t *testing.T) {
+	var found int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/gone">gone</a>`)
+		case "/gone":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `Not here. <a href="/home">Back home</a>`)
+		case "/home":
+			atomic.AddInt32(&found, 1)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL + "/")
+	c := newTestCrawler(t, cfg)
+	c.crawlRoot(context.Background())
+
+	if found != 1 {
+		t.Errorf("Expected the link on the 404 page to be followed once, got %d", found)
+	}
+	if got := c.Snapshot().Errors; got != 1 {
+		t.Errorf("Expected the 404 to count as 1 error, got %d", got)
+	}
+}
+
+func TestPathPrefixes(t *testing.T) {
+	page := []byte(`
+		<a href="/docs/intro">in</a>
+		<a href="/docs/api/v1">in</a>
+		<a href="/blog/post">out</a>
+		<a href="/">out</a>
+		<a href="http://other.example/anything">other host</a>
+		<a href="http://api.example/v2/users">api in</a>
+		<a href="http://api.example/docs/">api out</a>`)
+
+	cfg := testConfig("http://example.com/")
+	cfg.PathPrefixes = []string{"/docs/"}
+	cfg.HostPathPrefixes = map[string][]string{
+		"other.example": nil,
+		"api.example":   {"/v2/"},
+	}
+	c := newTestCrawler(t, cfg)
+
+	got := c.extractLinks(page, "http://example.com/")
+	want := []string{
+		"http://example.com/docs/intro",
+		"http://example.com/docs/api/v1",
+		"http://other.example/anything",
+		"http://api.example/v2/users",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestExitOnDrain(t *testing.T) {
+	var hits int32
+	srv := linkSite(t, map[string][]string{
+		"/":  {"/a", "/b"},
+		"/a": {"/b"},
+	}, &hits)
+
+	cfg := testConfig(srv.URL + "/")
+	cfg.ExitOnDrain = 3
+	c := newTestCrawler(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Crawl(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Expected Crawl to exit on drain before the deadline")
+	}
+	if got := c.visitedCount(); got != 2 {
+		t.Errorf("Expected every page to be visited, got %d", got)
+	}
+}
+
+func TestCrawlIdleTimeout(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) > 2 {
+			<-release // the target goes dark
+			return
+		}
+		fmt.Fprintf(w, `<a href="/p%d">next</a>`, hits)
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	cfg := testConfig(srv.URL)
+	cfg.ResponseHeaderTimeout = 20
+	cfg.IdleTimeout = 100
+	c := newTestCrawler(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := c.Crawl(ctx)
+	if !errors.Is(err, ErrIdleTimeout) {
+		t.Fatalf("Expected ErrIdleTimeout, got %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Error("Expected the idle timeout to fire before the overall deadline")
+	}
+}
+
+func TestDescendProbabilityDepths(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		fmt.Fprintf(w, `<a href="/p%da">a</a><a href="/p%db">b</a>`, n, n)
+	}))
+	defer srv.Close()
+
+	p := 0.5
+	cfg := testConfig(srv.URL)
+	cfg.MaxDepth = 50
+	cfg.DescendProbability = &p
+	c := newTestCrawler(t, cfg)
+	c.rand = rand.New(newLockedSource(1))
+
+	const branches = 400
+	depths := make(map[int]int)
+	total := 0
+	for i := 0; i < branches; i++ {
+		before := atomic.LoadInt32(&hits)
+		c.crawlRoot(context.Background())
+		depth := int(atomic.LoadInt32(&hits)-before) - 1 // minus the root fetch
+		depths[depth]++
+		total += depth
+	}
+
+	// Depths follow a geometric distribution with mean 1/(1-p) = 2 and
+	// P(1) = 1-p = 0.5, far from the MaxDepth every branch reaches
+	// without the setting.
+	if mean := float64(total) / branches; mean < 1.7 || mean > 2.3 {
+		t.Errorf("Expected mean depth near 2, got %.2f (%v)", mean, depths)
+	}
+	if share := float64(depths[1]) / branches; share < 0.42 || share > 0.58 {
+		t.Errorf("Expected about half of the branches to stop after one page, got %.2f (%v)", share, depths)
+	}
+	if depths[0] != 0 || depths[cfg.MaxDepth] != 0 {
+		t.Errorf("Expected every branch to end between 1 and MaxDepth pages, got %v", depths)
+	}
+}
+
+func TestCanonicalAndAlternateLinks(t *testing.T) {
+	page := []byte(`<html><head>
+		<link rel="canonical" href="/article">
+		<link rel="alternate" hreflang="ja" href="/ja/article" />
+		<link rel="Alternate" hreflang="de" href="https://de.example.com/article">
+		<link rel="stylesheet" href="/site.css">
+	</head><body>
+		<a href="/article">permalink</a>
+		<a href="/next">next</a>
+	</body></html>`)
+	base := "http://example.com/article?utm_source=feed"
+
+	testCases := []struct {
+		name   string
+		follow bool
+		want   []string
+	}{
+		{"Without alternates", false, []string{"http://example.com/next"}},
+		{"With alternates", true, []string{
+			"http://example.com/ja/article",
+			"https://de.example.com/article",
+			"http://example.com/next",
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig("http://example.com")
+			cfg.FollowAlternates = tc.follow
+			c := newTestCrawler(t, cfg)
+
+			got := c.extractLinks(page, base)
+			if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+			if !c.isVisited(context.Background(), "http://example.com/article") {
+				t.Error("Expected the canonical URL to be marked visited")
+			}
+			if c.visitedCount() != 0 {
+				t.Errorf("Expected the canonical mark not to count as a fetch, got %d", c.visitedCount())
+			}
+		})
+	}
+}
+
+func TestSelfCanonicalIsNotMarked(t *testing.T) {
+	c := newTestCrawler(t, testConfig("http://example.com"))
+	page := []byte(`<link rel="canonical" href="http://example.com/a"><a href="/b">b</a>`)
+	c.extractLinks(page, "http://example.com/a")
+	if c.isVisited(context.Background(), "http://example.com/b") {
+		t.Error("Expected ordinary links to stay unvisited")
+	}
+	if links := c.extractLinks([]byte(`<a href="/a">a</a>`), "http://example.com/"); len(links) != 1 {
+		t.Errorf("Expected a self-canonical page to remain linkable, got %v", links)
+	}
+}
+
+func TestObeyNofollow(t *testing.T) {
+	testCases := []struct {
+		name string
+		obey bool
+		page string
+		want []string
+	}{
+		{"Link nofollow", true, `<a href="/a">a</a><a rel="noopener NoFollow" href="/ad">ad</a><a href="/b">b</a>`,
+			[]string{"http://example.com/a", "http://example.com/b"}},
+		{"Meta nofollow", true, `<head><meta name="Robots" content="noindex, nofollow"></head><body><a href="/a">a</a></body>`, nil},
+		{"Meta none", true, `<meta name="robots" content="none"><a href="/a">a</a>`, nil},
+		{"Meta index", true, `<meta name="robots" content="index,follow"><meta name="description" content="nofollow"><a href="/a">a</a>`,
+			[]string{"http://example.com/a"}},
+		{"Disabled", false, `<meta name="robots" content="nofollow"><a href="/a">a</a><a rel="nofollow" href="/ad">ad</a>`,
+			[]string{"http://example.com/a", "http://example.com/ad"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig("http://example.com")
+			cfg.ObeyNofollow = tc.obey
+			c := newTestCrawler(t, cfg)
+
+			got := c.extractLinks([]byte(tc.page), "http://example.com/")
+			if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestResetDepthOnHostChange(t *testing.T) {
+	var hitsB int32
+	srvB := linkSite(t, map[string][]string{"/": {"/1"}, "/1": {"/2"}, "/2": {"/3"}, "/3": {"/4"}}, &hitsB)
+
+	testCases := []struct {
+		reset     bool
+		resetTo   int
+		wantHitsB int32
+	}{
+		{false, 0, 1}, // srvB/ is reached at depth 2, the last allowed
+		{true, 0, 3},  // srvB/, /1 and /2 at depths 0-2
+		{true, 1, 2},  // srvB/ and /1 at depths 1-2
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("reset=%v/%d", tc.reset, tc.resetTo), func(t *testing.T) {
+			var hitsA int32
+			srvA := linkSite(t, map[string][]string{"/": {"/1"}, "/1": {"/2"}, "/2": {srvB.URL + "/"}}, &hitsA)
+			atomic.StoreInt32(&hitsB, 0)
+
+			cfg := testConfig(srvA.URL + "/")
+			cfg.MaxDepth = 3
+			cfg.ResetDepthOnHostChange = tc.reset
+			cfg.HostChangeDepth = tc.resetTo
+			c := newTestCrawler(t, cfg)
+			c.crawlRoot(context.Background())
+
+			if got := atomic.LoadInt32(&hitsB); got != tc.wantHitsB {
+				t.Errorf("Expected %d fetches on the second host, got %d", tc.wantHitsB, got)
+			}
+		})
+	}
+}
+
+func TestDedupByContent(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		fmt.Fprint(w, `<a href="/list?page=2">next</a><a href="/about">about</a>`)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.DedupByContent = true
+	c := newTestCrawler(t, cfg)
+
+	for i, u := range []string{srv.URL + "/list?sort=asc", srv.URL + "/list?sort=desc"} {
+		body, contentType, err := c.fetch(context.Background(), u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		links := c.pageLinks(body, contentType, u)
+		if i == 0 && len(links) != 2 {
+			t.Errorf("Expected the first copy to yield 2 links, got %v", links)
+		}
+		if i == 1 && len(links) != 0 {
+			t.Errorf("Expected the identical second body to skip extraction, got %v", links)
+		}
+	}
+	if got := c.Snapshot().DuplicateBodies; got != 1 {
+		t.Errorf("Expected 1 duplicate body recorded, got %d", got)
+	}
+	if hits != 2 {
+		t.Errorf("Expected both URLs to be fetched, server saw %d requests", hits)
+	}
+}
+
+func TestBlacklistWeights(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.Seed = 1
+	cfg.BlacklistedURLs = []string{"/admin", "/logout"}
+	cfg.BlacklistWeights = map[string]float64{"/logout": 0.8, "/print": 0}
+	c := newTestCrawler(t, cfg)
+
+	const n = 2000
+	dropped := 0
+	for i := 0; i < n; i++ {
+		if !c.accept(fmt.Sprintf("http://example.com/logout?n=%d", i)) {
+			dropped++
+		}
+		if c.accept(fmt.Sprintf("http://example.com/admin/%d", i)) {
+			t.Fatal("Expected unweighted blacklist entries to block outright")
+		}
+		if !c.accept(fmt.Sprintf("http://example.com/print/%d", i)) {
+			t.Fatal("Expected a zero weight never to drop a link")
+		}
+	}
+	if rate := float64(dropped) / n; rate < 0.77 || rate > 0.83 {
+		t.Errorf("Expected about 80%% of /logout links dropped, got %.1f%%", 100*rate)
+	}
+	if blk := c.blacklistedBy("http://example.com/logout"); blk != "" {
+		t.Errorf("Expected soft entries not to block redirects and assets, got %q", blk)
+	}
+}
+
+func TestRootsPerIteration(t *testing.T) {
+	var mu sync.Mutex
+	rootHits := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/child") {
+			return
+		}
+		mu.Lock()
+		rootHits[r.URL.Path]++
+		mu.Unlock()
+		fmt.Fprintf(w, `<a href="%s/child">child</a>`, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	for _, n := range []int{0, 1, 3, 9} {
+		mu.Lock()
+		clear(rootHits)
+		mu.Unlock()
+
+		cfg := testConfig(srv.URL)
+		cfg.RootURLs = []string{srv.URL + "/r0", srv.URL + "/r1", srv.URL + "/r2", srv.URL + "/r3"}
+		cfg.RootsPerIteration = n
+		cfg.MaxDepth = 0 // stop after the roots
+		c := newTestCrawler(t, cfg)
+		c.crawlRoot(context.Background())
+
+		want := min(max(n, 1), len(cfg.RootURLs))
+		mu.Lock()
+		if len(rootHits) != want {
+			t.Errorf("Expected %d distinct roots fetched for roots_per_iteration %d, got %v", want, n, rootHits)
+		}
+		for root, hits := range rootHits {
+			if hits != 1 {
+				t.Errorf("Expected %s to be fetched once, got %d", root, hits)
+			}
+		}
+		mu.Unlock()
+		if len(c.links) != want {
+			t.Errorf("Expected the links of %d roots to be merged, got %v", want, c.links)
+		}
+	}
+}
+
+func TestMaxTotalBytes(t *testing.T) {
+	var hits int32
+	// Every page is one 21-byte link: <a href="/N">link</a>
+	srv := linkSite(t, map[string][]string{"/": {"/1"}, "/1": {"/2"}, "/2": {"/3"}, "/3": {"/4"}, "/4": {"/5"}}, &hits)
+
+	cfg := testConfig(srv.URL)
+	cfg.MaxDepth = 10
+	cfg.MaxTotalBytes = 50
+	c := newTestCrawler(t, cfg)
+
+	err := c.Crawl(context.Background())
+	if !errors.Is(err, ErrByteLimit) {
+		t.Fatalf("Expected ErrByteLimit, got %v", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 3 {
+		t.Errorf("Expected the crawl to stop after the 3rd page crossed 50 bytes, got %d requests", n)
+	}
+	if b := c.Snapshot().Bytes; b != 63 {
+		t.Errorf("Expected 63 bytes fetched, got %d", b)
+	}
+	if _, _, err := c.fetch(context.Background(), srv.URL+"/4"); !errors.Is(err, ErrByteLimit) {
+		t.Errorf("Expected fetches past the limit to fail with ErrByteLimit, got %v", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 3 {
+		t.Errorf("Expected no request past the limit, got %d requests", n)
+	}
+}
+
+func TestMaxRecursion(t *testing.T) {
+	var hits int32
+	// Every page links to the next one, so the branch never runs dry.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		fmt.Fprintf(w, `<a href="/page/%d">next</a>`, n)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL + "/")
+	cfg.MaxDepth = 1 << 30
+	cfg.MaxRecursion = 200
+	c := newTestCrawler(t, cfg)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	c.crawlRoot(context.Background())
+
+	if n := atomic.LoadInt32(&hits); n != 201 {
+		t.Errorf("Expected the root and 200 branch pages, got %d requests", n)
+	}
+	if c.frames != 0 {
+		t.Errorf("Expected every call to unwind, got %d frames left", c.frames)
+	}
+	if !strings.Contains(buf.String(), "warning: branch stopped after 200 pages by max_recursion") {
+		t.Errorf("Expected a max_recursion warning, got %q", buf.String())
+	}
+}
+
+func TestMaxBranchDuration(t *testing.T) {
+	var hits int32
+	// An endless chain of pages that each take 50ms.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprintf(w, `<a href="/page/%d">next</a>`, n)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL + "/")
+	cfg.MaxDepth = 50
+	cfg.MaxBranchDuration = 120
+	c := newTestCrawler(t, cfg)
+
+	start := time.Now()
+	c.crawlRoot(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the branch to end near its 120ms budget, took %v", elapsed)
+	}
+	// The root and two pages fit in the budget; scheduling may shift one.
+	if n := atomic.LoadInt32(&hits); n < 2 || n > 4 {
+		t.Errorf("Expected about 3 requests within the budget, got %d", n)
+	}
+}
+
+func TestSelfLinksSkipped(t *testing.T) {
+	c := newTestCrawler(t, testConfig("http://example.com"))
+	body := []byte(`<a href="#">top</a><a href="">reload</a><a href="?">bare</a>` +
+		`<a href="#section">jump</a><a href="/page#section">jump</a>` +
+		`<a href="?x=1">filter</a><a href="/other#part">other</a>`)
+
+	got := c.extractLinks(body, "http://example.com/page")
+	want := []string{"http://example.com/page?x=1", "http://example.com/other"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestMaxPathDepth(t *testing.T) {
+	page := []byte(`
+		<a href="/a">1</a>
+		<a href="/a/b/">2</a>
+		<a href="/a/b/c">3</a>
+		<a href="http://docs.example/guide/a/b">2 below its root</a>
+		<a href="http://docs.example/guide/a/b/c">3 below its root</a>
+		<a href="http://other.example/x/y/z">3 from /</a>`)
+
+	cfg := testConfig("http://example.com/", "http://docs.example/guide/", "http://docs.example/guide/intro/")
+	cfg.MaxPathDepth = 2
+	c := newTestCrawler(t, cfg)
+
+	got := c.extractLinks(page, "http://example.com/")
+	want := []string{
+		"http://example.com/a",
+		"http://example.com/a/b/",
+		"http://docs.example/guide/a/b",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := c.rejectReason("http://example.com/a/b/c"); got != "path 3 segments below its root, max_path_depth 2" {
+		t.Errorf("Expected a max_path_depth rejection, got %q", got)
+	}
+}
+
+func TestLinkSampleRate(t *testing.T) {
+	rate := func(f float64) *float64 { return &f }
+	var page strings.Builder
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&page, `<a href="/p/%d">%d</a>`, i, i)
+	}
+	body := []byte(page.String())
+
+	for _, tc := range []struct {
+		rate     *float64
+		min, max int
+	}{
+		{nil, 2000, 2000},
+		{rate(0.25), 400, 600},
+		{rate(0), 0, 0},
+	} {
+		cfg := testConfig("http://example.com/")
+		cfg.Seed = 7
+		cfg.LinkSampleRate = tc.rate
+		got := len(newTestCrawler(t, cfg).extractLinks(body, "http://example.com/"))
+		if got < tc.min || got > tc.max {
+			t.Errorf("Expected between %d and %d of 2000 links, got %d", tc.min, tc.max, got)
+		}
+	}
+}
+
+func TestBlockMixedContent(t *testing.T) {
+	page := []byte(`<a href="http://example.com/plain">plain</a><a href="https://example.com/secure">secure</a>` +
+		`<a href="/relative">relative</a><img src="http://cdn.example/i.png"><img src="https://cdn.example/j.png">`)
+	cfg := testConfig("https://example.com/")
+	cfg.BlockMixedContent = true
+	c := newTestCrawler(t, cfg)
+
+	links := c.extractLinks(page, "https://example.com/")
+	if want := "https://example.com/secure https://example.com/relative"; strings.Join(links, " ") != want {
+		t.Errorf("Expected http links dropped from an https page, got %v", links)
+	}
+	assets := c.extractAssets(page, "https://example.com/")
+	if len(assets) != 1 || assets[0] != "https://cdn.example/j.png" {
+		t.Errorf("Expected http assets dropped from an https page, got %v", assets)
+	}
+	if links := c.extractLinks(page, "http://example.com/"); len(links) != 3 {
+		t.Errorf("Expected an http page to keep every link, got %v", links)
+	}
+}
+
+func TestIDNLinksUsePunycode(t *testing.T) {
+	c := newTestCrawler(t, testConfig("http://example.com/"))
+	page := []byte(`<a href="http://例え.jp/page">idn</a><a href="http://xn--r8jz45g.jp/page">punycode</a>` +
+		`<a href="http://%E4%BE%8B%E3%81%88.jp:8080/">escaped</a>`)
+
+	links := c.extractLinks(page, "http://example.com/")
+	want := []string{"http://xn--r8jz45g.jp/page", "http://xn--r8jz45g.jp/page", "http://xn--r8jz45g.jp:8080/"}
+	if strings.Join(links, " ") != strings.Join(want, " ") {
+		t.Fatalf("Expected IDN hosts in punycode %v, got %v", want, links)
+	}
+	if !c.accept(links[0]) {
+		t.Errorf("Expected %s to be accepted", links[0])
+	}
+	c.markVisited(context.Background(), links[1])
+	if c.accept(links[0]) {
+		t.Error("Expected the Unicode spelling to dedup against the punycode one")
+	}
+}
+
+func TestScanDataAttributes(t *testing.T) {
+	page := []byte(`<a href="/plain">plain</a>
+<div data-href="/card">card</div>
+<tr data-url="https://example.com/row"></tr>
+<button onclick="window.location.href='/clicked'">go</button>
+<span onclick="location.assign(&quot;/assigned&quot;)">go</span>
+<div data-href="not a link" data-url="{&quot;id&quot;: 3}"></div>
+<button onclick="track('/analytics')">no navigation</button>`)
+	cfg := testConfig("http://example.com/")
+	c := newTestCrawler(t, cfg)
+	if links := c.extractLinks(page, "http://example.com/"); len(links) != 1 {
+		t.Errorf("Expected only href links without scan_data_attributes, got %v", links)
+	}
+
+	cfg.ScanDataAttributes = true
+	links := c.extractLinks(page, "http://example.com/")
+	want := "http://example.com/plain http://example.com/card https://example.com/row http://example.com/clicked http://example.com/assigned"
+	if got := strings.Join(links, " "); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestMinRevisitInterval(t *testing.T) {
+	var hits int32
+	srv := linkSite(t, map[string][]string{"/": {"/", "/a"}}, &hits)
+	root := srv.URL + "/"
+	cfg := testConfig(root)
+	cfg.MinRevisitInterval = 1000
+	c := newTestCrawler(t, cfg)
+	clk := &fakeClock{now: time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)}
+	c.SetClock(clk)
+
+	// The root is not marked visited, so without the floor its link back
+	// to itself would be fetched again straight away.
+	links, err := c.visit(context.Background(), root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(links, " ") != srv.URL+"/a" {
+		t.Errorf("Expected the just-fetched root to be held back, got %v", links)
+	}
+
+	clk.advance(999 * time.Millisecond)
+	if c.accept(root) {
+		t.Error("Expected the root to be rejected inside min_revisit_interval")
+	}
+	clk.advance(time.Millisecond)
+	if !c.accept(root) {
+		t.Error("Expected the root to be accepted once min_revisit_interval passed")
+	}
+
+	clk.advance(-500 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if c.waitRevisit(ctx, root) {
+		t.Error("Expected a root inside min_revisit_interval to wait past ctx")
+	}
+}
@@ -0,0 +1,50 @@
+package crawler
+
+import (
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	// cssRef matches url(...) with any quoting and the string form of
+	// @import, whose url(...) form is already covered by the first.
+	cssRef = regexp.MustCompile(`(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^)"'\s]*))\s*\)|@import\s+(?:"([^"]*)"|'([^']*)')`)
+)
+
+// isCSS reports whether contentType names a stylesheet.
+func isCSS(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mt == "text/css"
+}
+
+// extractCSSRefs returns the url(...) and @import targets of a
+// stylesheet, deduplicated and in order. They are resolved against the
+// stylesheet's own URL, as browsers do, not the page that linked it.
+func (c *Crawler) extractCSSRefs(body []byte, base string) []string {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil
+	}
+	css := cssComment.ReplaceAllString(string(body), "")
+
+	seen := make(map[string]struct{})
+	var out []string
+	for _, m := range cssRef.FindAllStringSubmatch(css, -1) {
+		ref := strings.TrimSpace(strings.Join(m[1:], ""))
+		// Fragment-only references point into the same document (SVG
+		// filters) and data: URIs carry their payload inline.
+		if ref == "" || strings.HasPrefix(ref, "#") || strings.HasPrefix(strings.ToLower(ref), "data:") {
+			continue
+		}
+		link := c.normalize(ref, baseURL)
+		if _, dup := seen[link]; dup || !c.assetAllowed(base, link) {
+			continue
+		}
+		seen[link] = struct{}{}
+		out = append(out, link)
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestExtractCSSRefs(t *testing.T) {
+	c := newTestCrawler(t, testConfig("http://example.com"))
+
+	css := []byte(`@import "theme.css";
+		@import url('print.css') print;
+		/* body { background: url(commented.png) } */
+		.a { background: url( "img/bg.png" ) }
+		.b { background: URL(/abs.png), url(img/bg.png) }
+		.c { filter: url(#blur); mask: url(data:image/svg+xml;base64,AAAA) }
+		@font-face { src: url(../fonts/f.woff2) format("woff2") }`)
+
+	got := c.extractCSSRefs(css, "http://example.com/static/css/main.css")
+	want := []string{
+		"http://example.com/static/css/theme.css",
+		"http://example.com/static/css/print.css",
+		"http://example.com/static/css/img/bg.png",
+		"http://example.com/abs.png",
+		"http://example.com/static/fonts/f.woff2",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestFetchAssetsFollowsStylesheets(t *testing.T) {
+	files := map[string]string{
+		"/static/main.css": `@import "theme.css";
+			/* .x { background: url(commented.png) } */
+			body { background: url('img/bg.png') }
+			.logo { background: url(/abs.png) }
+			.dot { background: url(data:image/gif;base64,R0lG) }`,
+		"/static/theme.css": `@font-face { src: url(../fonts/f.woff2) }`,
+	}
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.URL.Path] = true
+		mu.Unlock()
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<link rel="stylesheet" href="/static/main.css">`)
+			return
+		}
+		if body, ok := files[r.URL.Path]; ok {
+			w.Header().Set("Content-Type", "text/css; charset=utf-8")
+			fmt.Fprint(w, body)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.RealisticAssetTiming = true
+	c := newTestCrawler(t, cfg)
+	body, contentType, err := c.fetch(context.Background(), srv.URL+"/")
+	if err != nil {
+		t.Fatalf("Expected the page to load, got %v", err)
+	}
+	c.fetchAssets(context.Background(), body, contentType, srv.URL+"/")
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, p := range []string{"/static/main.css", "/static/theme.css", "/static/img/bg.png", "/abs.png", "/fonts/f.woff2"} {
+		if !seen[p] {
+			t.Errorf("Expected %s to be fetched, got %v", p, seen)
+		}
+	}
+	if seen["/static/commented.png"] {
+		t.Error("Expected references inside CSS comments to be ignored")
+	}
+}
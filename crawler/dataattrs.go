@@ -0,0 +1,52 @@
+package crawler
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// dataURLAttrs are the attributes read as link targets under
+// cfg.ScanDataAttributes.
+var dataURLAttrs = []string{"data-href", "data-url"}
+
+// onclickNav matches the quoted target of a script navigation such as
+// location.href='/next' or window.location.assign("/next").
+var onclickNav = regexp.MustCompile(`\blocation(?:\.href)?\s*=\s*['"]([^'"\s]+)['"]|\blocation\.(?:assign|replace)\(\s*['"]([^'"\s]+)['"]\s*\)`)
+
+// scriptedHrefs returns the navigation targets t carries outside href,
+// in data-href and data-url attributes or in an onclick handler. Only
+// values shaped like a URL or a path are kept, so data attributes used
+// for anything else are ignored.
+func scriptedHrefs(t html.Token) []string {
+	var hrefs []string
+	for _, key := range dataURLAttrs {
+		if v, ok := attrVal(t, key); ok && looksLikeURL(v) {
+			hrefs = append(hrefs, strings.TrimSpace(v))
+		}
+	}
+	if js, ok := attrVal(t, "onclick"); ok {
+		for _, m := range onclickNav.FindAllStringSubmatch(js, -1) {
+			if v := m[1] + m[2]; looksLikeURL(v) {
+				hrefs = append(hrefs, v)
+			}
+		}
+	}
+	return hrefs
+}
+
+// looksLikeURL reports whether v is an absolute http(s) URL or a
+// relative path, rather than some other value a script stored.
+func looksLikeURL(v string) bool {
+	v = strings.TrimSpace(v)
+	if strings.ContainsAny(v, " \t\n<>") {
+		return false
+	}
+	for _, prefix := range []string{"http://", "https://", "/", "./", "../"} {
+		if strings.HasPrefix(strings.ToLower(v), prefix) {
+			return true
+		}
+	}
+	return false
+}
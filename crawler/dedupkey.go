@@ -0,0 +1,26 @@
+package crawler
+
+import "net/url"
+
+// dedupKey returns the form of link recorded in the visited set. With
+// cfg.IgnoreQueryParams every query parameter is dropped except those in
+// cfg.SignificantQueryParams, which are kept in sorted order so that
+// their order in the link does not matter either.
+func (c *Crawler) dedupKey(link string) string {
+	if !c.cfg.IgnoreQueryParams {
+		return link
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.RawQuery == "" {
+		return link
+	}
+	q := u.Query()
+	kept := url.Values{}
+	for _, name := range c.cfg.SignificantQueryParams {
+		if vs, ok := q[name]; ok {
+			kept[name] = vs
+		}
+	}
+	u.RawQuery = kept.Encode()
+	return u.String()
+}
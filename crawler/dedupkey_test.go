@@ -0,0 +1,41 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSignificantQueryParams(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.IgnoreQueryParams = true
+	cfg.SignificantQueryParams = []string{"lang", "id"}
+	c := newTestCrawler(t, cfg)
+	ctx := context.Background()
+
+	if !c.markVisited(ctx, "http://example.com/page?lang=en&utm_source=mail&id=7") {
+		t.Fatal("Expected the first visit to be claimed")
+	}
+
+	testCases := []struct {
+		link string
+		want bool
+	}{
+		{"http://example.com/page?id=7&lang=en", true},                 // order ignored
+		{"http://example.com/page?lang=en&id=7&utm_source=feed", true}, // insignificant param differs
+		{"http://example.com/page?lang=de&id=7", false},                // significant param differs
+		{"http://example.com/page?lang=en", false},                     // significant param missing
+		{"http://example.com/page", false},
+	}
+	for _, tc := range testCases {
+		if got := c.isVisited(ctx, tc.link); got != tc.want {
+			t.Errorf("isVisited(%q) = %v, expected %v", tc.link, got, tc.want)
+		}
+	}
+}
+
+func TestQueryParamsKeptByDefault(t *testing.T) {
+	c := newTestCrawler(t, testConfig("http://example.com"))
+	if got := c.dedupKey("http://example.com/?b=2&a=1"); got != "http://example.com/?b=2&a=1" {
+		t.Errorf("Expected the URL unchanged, got %q", got)
+	}
+}
@@ -0,0 +1,61 @@
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// parseDelaySamples reads one think time per line in time.Duration
+// syntax, such as "850ms" or "2.5s". Blank lines and lines starting
+// with # are skipped.
+func parseDelaySamples(r io.Reader) ([]time.Duration, error) {
+	var samples []time.Duration
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		d, err := time.ParseDuration(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("line %d: negative delay %v", n, d)
+		}
+		samples = append(samples, d)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no delays found")
+	}
+	return samples, nil
+}
+
+func loadDelaySamples(path string) ([]time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseDelaySamples(f)
+}
+
+// sampledDelay draws a think time from cfg.DelaySamplesFile for pages
+// on host, reporting false when no file is loaded or host's
+// cfg.HostProfiles entry sets its own sleep range.
+func (c *Crawler) sampledDelay(host string) (time.Duration, bool) {
+	if len(c.delays) == 0 {
+		return 0, false
+	}
+	if _, p, ok := lookupHost(c.cfg.HostProfiles, host); ok && (p.MinSleep != nil || p.MaxSleep != nil) {
+		return 0, false
+	}
+	return c.delays[c.rand.Intn(len(c.delays))], true
+}
@@ -0,0 +1,59 @@
+package crawler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calpa/urusai/config"
+)
+
+func TestParseDelaySamples(t *testing.T) {
+	got, err := parseDelaySamples(strings.NewReader("# gaps\n850ms\n\n2.5s\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != 850*time.Millisecond || got[1] != 2500*time.Millisecond {
+		t.Errorf("Expected [850ms 2.5s], got %v", got)
+	}
+	for _, bad := range []string{"", "# only comments\n", "soon\n", "-1s\n"} {
+		if _, err := parseDelaySamples(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestDelaySamplesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "delays.txt")
+	if err := os.WriteFile(path, []byte("10ms\n20ms\n30ms\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig("http://example.com/")
+	cfg.MinSleep, cfg.MaxSleep = 1, 2
+	cfg.DelaySamplesFile = path
+	pinned := 5
+	cfg.HostProfiles = map[string]config.HostProfile{"pinned.example": {MinSleep: &pinned, MaxSleep: &pinned}}
+	c := newTestCrawler(t, cfg)
+
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 200; i++ {
+		d := c.thinkTime("example.com")
+		if d != 10*time.Millisecond && d != 20*time.Millisecond && d != 30*time.Millisecond {
+			t.Fatalf("Expected a delay from the samples, got %v", d)
+		}
+		seen[d] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("Expected every sample to be drawn, got %v", seen)
+	}
+	if d := c.thinkTime("pinned.example"); d != 5*time.Microsecond {
+		t.Errorf("Expected the host profile's sleep to win, got %v", d)
+	}
+
+	cfg.DelaySamplesFile = filepath.Join(t.TempDir(), "missing.txt")
+	if _, err := NewCrawler(cfg); err == nil {
+		t.Error("Expected a missing delay_samples_file to fail")
+	}
+}
@@ -0,0 +1,58 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDeterministicOrderRepeatsTraversal(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.RequestURI())
+		mu.Unlock()
+		// Every page links to eight children and four images, so the walk
+		// and the asset bursts have plenty of random choices to make.
+		for i := 0; i < 8; i++ {
+			fmt.Fprintf(w, `<a href="%s/%d">link</a>`, r.URL.Path, i)
+			if i < 4 {
+				fmt.Fprintf(w, `<img src="/img%s-%d.png">`, strings.ReplaceAll(r.URL.Path, "/", "-"), i)
+			}
+		}
+	}))
+	defer srv.Close()
+
+	run := func() []string {
+		mu.Lock()
+		paths = nil
+		mu.Unlock()
+		cfg := testConfig(srv.URL+"/a", srv.URL+"/b", srv.URL+"/c")
+		cfg.MaxDepth = 4
+		cfg.MinSleep, cfg.MaxSleep = 0, 3
+		cfg.Seed = 42
+		cfg.CacheBust = 0.5
+		cfg.DeterministicOrder = true
+		cfg.RealisticAssetTiming = true
+		c := newTestCrawler(t, cfg)
+		for i := 0; i < 5; i++ {
+			c.crawlRoot(context.Background())
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), paths...)
+	}
+
+	first, second := run(), run()
+	if len(first) < 10 {
+		t.Fatalf("Expected a substantial crawl, got %v", first)
+	}
+	if strings.Join(first, " ") != strings.Join(second, " ") {
+		t.Errorf("Expected identical fetch sequences, got\n%v\n%v", first, second)
+	}
+}
@@ -0,0 +1,73 @@
+package crawler
+
+import (
+	"context"
+	"net"
+	"net/netip"
+)
+
+// dnsLimiter bounds the number of DNS lookups in flight at once, for
+// cfg.MaxConcurrentDNS, so that a page linking to many hosts does not
+// flood the resolver. Only the lookup holds a slot, not the connection.
+type dnsLimiter struct {
+	sem    chan struct{}
+	lookup func(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+func newDNSLimiter(n int) *dnsLimiter {
+	return &dnsLimiter{
+		sem:    make(chan struct{}, n),
+		lookup: net.DefaultResolver.LookupNetIP,
+	}
+}
+
+// dialer wraps dial to resolve host names through the limiter and then
+// connect to each address in turn until one answers. IP literals are
+// dialled directly.
+func (l *dnsLimiter) dialer(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+		ips, err := l.resolve(ctx, network, host)
+		if err != nil {
+			return nil, err
+		}
+		var firstErr error
+		for _, ip := range ips {
+			conn, err := dial(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		return nil, firstErr
+	}
+}
+
+// resolve looks host up once a slot is free, or fails when ctx ends
+// first. The address family follows the dial network.
+func (l *dnsLimiter) resolve(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-l.sem }()
+
+	family := "ip"
+	switch network {
+	case "tcp4":
+		family = "ip4"
+	case "tcp6":
+		family = "ip6"
+	}
+	ips, err := l.lookup(ctx, family, host)
+	if err == nil && len(ips) == 0 {
+		err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return ips, err
+}
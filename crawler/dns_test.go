@@ -0,0 +1,93 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxConcurrentDNS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	const limit = 3
+	var active, peak, lookups int32
+	l := newDNSLimiter(limit)
+	l.lookup = func(ctx context.Context, network, host string) ([]netip.Addr, error) {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+				break
+			}
+		}
+		atomic.AddInt32(&lookups, 1)
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+		return []netip.Addr{netip.MustParseAddr("127.0.0.1")}, nil
+	}
+	dial := l.dialer((&net.Dialer{}).DialContext)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conn, err := dial(context.Background(), "tcp", fmt.Sprintf("host%d.test:%s", i, port))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			conn.Close()
+		}(i)
+	}
+	wg.Wait()
+
+	if lookups != 20 {
+		t.Errorf("Expected 20 lookups, got %d", lookups)
+	}
+	if peak > limit {
+		t.Errorf("Expected at most %d concurrent lookups, got %d", limit, peak)
+	}
+}
+
+func TestDNSLimiterHonoursContext(t *testing.T) {
+	l := newDNSLimiter(1)
+	l.sem <- struct{}{} // the only slot is taken
+	l.lookup = func(context.Context, string, string) ([]netip.Addr, error) {
+		t.Error("Expected no lookup without a free slot")
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.dialer((&net.Dialer{}).DialContext)(ctx, "tcp", "example.test:80"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait for a slot to end with the context, got %v", err)
+	}
+}
+
+func TestDNSLimiterSkipsIPLiterals(t *testing.T) {
+	l := newDNSLimiter(1)
+	l.lookup = func(context.Context, string, string) ([]netip.Addr, error) {
+		t.Error("Expected IP literals not to be looked up")
+		return nil, nil
+	}
+	var dialled string
+	dial := l.dialer(func(_ context.Context, _, addr string) (net.Conn, error) {
+		dialled = addr
+		return nil, errors.New("not connecting")
+	})
+	_, _ = dial(context.Background(), "tcp", "127.0.0.1:80")
+	if dialled != "127.0.0.1:80" {
+		t.Errorf("Expected a direct dial, got %q", dialled)
+	}
+}
@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+)
+
+// ErrorKind is the broad cause of a failed fetch, as counted in
+// Stats.ErrorKinds and reported to StatsD as "errors.<kind>".
+type ErrorKind string
+
+const (
+	KindDNS      ErrorKind = "dns"      // the host name did not resolve
+	KindTLS      ErrorKind = "tls"      // handshake or certificate failure
+	KindRefused  ErrorKind = "refused"  // nothing listening on the port
+	KindTimeout  ErrorKind = "timeout"  // a connect, header or body deadline expired
+	KindReset    ErrorKind = "reset"    // the peer dropped the connection
+	KindProtocol ErrorKind = "protocol" // the peer did not speak valid HTTP
+	KindStatus   ErrorKind = "status"   // a 4xx or 5xx response
+	KindOther    ErrorKind = "other"
+)
+
+// ErrorKinds counts failed fetches by ErrorKind.
+type ErrorKinds struct {
+	DNS, TLS, Refused, Timeout, Reset, Protocol, Status, Other int
+}
+
+func (k *ErrorKinds) add(kind ErrorKind) {
+	switch kind {
+	case KindDNS:
+		k.DNS++
+	case KindTLS:
+		k.TLS++
+	case KindRefused:
+		k.Refused++
+	case KindTimeout:
+		k.Timeout++
+	case KindReset:
+		k.Reset++
+	case KindProtocol:
+		k.Protocol++
+	case KindStatus:
+		k.Status++
+	default:
+		k.Other++
+	}
+}
+
+// classifyError returns the ErrorKind of a fetch error. DNS and TLS
+// failures win over the timeouts they may also report, so that a slow
+// resolver shows up as DNS trouble.
+func classifyError(err error) ErrorKind {
+	var (
+		dnsErr     *net.DNSError
+		recordErr  tls.RecordHeaderError
+		alertErr   tls.AlertError
+		verifyErr  *tls.CertificateVerificationError
+		authErr    x509.UnknownAuthorityError
+		hostErr    x509.HostnameError
+		invalidErr x509.CertificateInvalidError
+		protoErr   *http.ProtocolError
+		netErr     net.Error
+	)
+	switch {
+	case errors.Is(err, ErrHTTPStatus):
+		return KindStatus
+	case errors.As(err, &dnsErr):
+		return KindDNS
+	case errors.As(err, &recordErr), errors.As(err, &alertErr), errors.As(err, &verifyErr),
+		errors.As(err, &authErr), errors.As(err, &hostErr), errors.As(err, &invalidErr):
+		return KindTLS
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return KindTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return KindRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return KindReset
+	case errors.As(err, &protoErr), strings.Contains(err.Error(), "malformed HTTP"):
+		// net/http reports malformed responses with unexported types.
+		return KindProtocol
+	}
+	return KindOther
+}
@@ -0,0 +1,82 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	// wrap nests err the way net/http and request report it.
+	wrap := func(err error) error {
+		return transportError("http://example.com/", &url.Error{Op: "Get", URL: "http://example.com/", Err: err})
+	}
+	opErr := func(op string, errno syscall.Errno) error {
+		return &net.OpError{Op: op, Net: "tcp", Err: os.NewSyscallError(op, errno)}
+	}
+
+	testCases := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"DNS", wrap(&net.DNSError{Err: "no such host", Name: "nope.invalid", IsNotFound: true}), KindDNS},
+		{"DNS timeout", wrap(&net.DNSError{Err: "i/o timeout", Name: "slow.example", IsTimeout: true}), KindDNS},
+		{"Unknown authority", wrap(&tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}), KindTLS},
+		{"Hostname mismatch", wrap(x509.HostnameError{Host: "example.com", Certificate: &x509.Certificate{}}), KindTLS},
+		{"Plain HTTP on TLS port", wrap(tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}), KindTLS},
+		{"TLS alert", wrap(&net.OpError{Op: "remote error", Err: tls.AlertError(40)}), KindTLS},
+		{"Refused", wrap(opErr("connect", syscall.ECONNREFUSED)), KindRefused},
+		{"Reset", wrap(opErr("read", syscall.ECONNRESET)), KindReset},
+		{"Closed early", wrap(io.EOF), KindReset},
+		{"Deadline", wrap(context.DeadlineExceeded), KindTimeout},
+		{"Dial timeout", wrap(&net.OpError{Op: "dial", Err: timeoutErr{}}), KindTimeout},
+		{"Malformed response", wrap(fmt.Errorf("net/http: HTTP/1.x transport connection broken: %w", errors.New(`malformed HTTP response "SSH-2.0"`))), KindProtocol},
+		{"Status", statusError("http://example.com/", http.StatusBadGateway), KindStatus},
+		{"Other", errors.New("something else"), KindOther},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := classifyError(tc.err); got != tc.want {
+				t.Errorf("Expected %q for %v, got %q", tc.want, tc.err, got)
+			}
+		})
+	}
+}
+
+// timeoutErr is a net.Error that timed out.
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+func TestErrorKindsCounted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	}))
+	defer srv.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close() // nothing listens on its port any more
+
+	c := newTestCrawler(t, testConfig(srv.URL))
+	c.fetch(context.Background(), srv.URL+"/")
+	c.fetch(context.Background(), closed.URL+"/")
+	c.fetch(context.Background(), "http://nope.invalid/")
+
+	s := c.Snapshot()
+	want := ErrorKinds{Status: 1, Refused: 1, DNS: 1}
+	if s.ErrorKinds != want || s.Errors != 3 {
+		t.Errorf("Expected %+v across 3 errors, got %+v across %d", want, s.ErrorKinds, s.Errors)
+	}
+}
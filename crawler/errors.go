@@ -0,0 +1,69 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Sentinel causes carried by *FetchError, for use with errors.Is.
+var (
+	// ErrBlacklisted means a redirect led to a blacklisted URL.
+	ErrBlacklisted = errors.New("blacklisted URL")
+	// ErrTimeout means a connection, header or body deadline expired.
+	ErrTimeout = errors.New("request timed out")
+	// ErrHTTPStatus means the server answered with a 4xx or 5xx status.
+	ErrHTTPStatus = errors.New("unexpected HTTP status")
+	// ErrRedirectLoop means a redirect chain revisited a URL or never
+	// settled. Hosts producing it are dropped from the rest of the crawl.
+	ErrRedirectLoop = errors.New("redirect loop")
+	// ErrURLTooLong means a redirect pointed at a URL longer than
+	// cfg.MaxURLLength.
+	ErrURLTooLong = errors.New("URL too long")
+	// ErrInjectedFault means the fetch was failed on purpose by
+	// cfg.FaultInjection; no request was sent.
+	ErrInjectedFault = errors.New("injected fault")
+)
+
+// FetchError describes a failed fetch. StatusCode is set only when the
+// server responded; Err holds the cause and matches the sentinels above
+// as well as the underlying network error.
+type FetchError struct {
+	URL        string
+	StatusCode int
+	Err        error
+}
+
+func (e *FetchError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%v %d %s", e.Err, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return e.Err.Error()
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// statusError reports an error status returned for raw.
+func statusError(raw string, code int) error {
+	return &FetchError{URL: raw, StatusCode: code, Err: ErrHTTPStatus}
+}
+
+// isStatusError reports whether err is only an error status, so that
+// the response body is still there.
+func isStatusError(err error) bool {
+	return errors.Is(err, ErrHTTPStatus)
+}
+
+// transportError wraps a failure that produced no usable response,
+// tagging deadline expiries with ErrTimeout.
+func transportError(raw string, err error) error {
+	var ne net.Error
+	if (errors.As(err, &ne) && ne.Timeout()) || errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return &FetchError{URL: raw, Err: err}
+}
@@ -0,0 +1,76 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFetchErrorTypes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/to-blacklist":
+			http.Redirect(w, r, "/logout", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedURL := closed.URL
+	closed.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.BlacklistedURLs = []string{"logout"}
+	cfg.ResponseHeaderTimeout = 20
+	c := newTestCrawler(t, cfg)
+
+	testCases := []struct {
+		name       string
+		url        string
+		sentinel   error
+		wantStatus int
+	}{
+		{"HTTP status", srv.URL + "/missing", ErrHTTPStatus, http.StatusNotFound},
+		{"Timeout", srv.URL + "/slow", ErrTimeout, 0},
+		{"Blacklisted redirect", srv.URL + "/to-blacklist", ErrBlacklisted, 0},
+		{"Connection refused", closedURL, nil, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := c.fetch(context.Background(), tc.url)
+
+			var fe *FetchError
+			if !errors.As(err, &fe) {
+				t.Fatalf("Expected *FetchError, got %T: %v", err, err)
+			}
+			if fe.URL != tc.url {
+				t.Errorf("Expected URL %s, got %s", tc.url, fe.URL)
+			}
+			if fe.StatusCode != tc.wantStatus {
+				t.Errorf("Expected status %d, got %d", tc.wantStatus, fe.StatusCode)
+			}
+			if tc.sentinel != nil && !errors.Is(err, tc.sentinel) {
+				t.Errorf("Expected errors.Is(err, %v), got %v", tc.sentinel, err)
+			}
+			for _, other := range []error{ErrHTTPStatus, ErrTimeout, ErrBlacklisted, ErrRedirectLoop} {
+				if other != tc.sentinel && errors.Is(err, other) {
+					t.Errorf("Did not expect errors.Is(err, %v) for %v", other, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchErrorMessage(t *testing.T) {
+	err := statusError("http://example.com/", http.StatusServiceUnavailable)
+	if got, want := err.Error(), "unexpected HTTP status 503 Service Unavailable"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
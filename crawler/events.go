@@ -0,0 +1,42 @@
+package crawler
+
+import (
+	"context"
+	"time"
+)
+
+// Event describes one page visit, for programs that embed the crawler
+// and want to follow it as it runs.
+type Event struct {
+	URL     string
+	Status  int           // HTTP status; 0 when no response arrived
+	Latency time.Duration // time to fetch the page, without its assets
+	Depth   int           // link hops from the root, which is 0
+	Err     error         // why the fetch failed; nil on success
+}
+
+// EventHandler receives an Event for every page the crawler visits. It
+// is called on the crawl goroutine, or on several workers at once with
+// cfg.MaxWorkers, so it should hand events off rather than block.
+type EventHandler func(Event)
+
+// SetEventHandler installs fn to receive page events. A nil fn turns
+// them off.
+func (c *Crawler) SetEventHandler(fn EventHandler) {
+	c.events = fn
+}
+
+type statusKey struct{}
+
+// withStatus returns a context under which request stores the status
+// of the response it receives in *status.
+func withStatus(ctx context.Context, status *int) context.Context {
+	return context.WithValue(ctx, statusKey{}, status)
+}
+
+// recordStatus stores code for withStatus, if ctx asks for it.
+func recordStatus(ctx context.Context, code int) {
+	if status, ok := ctx.Value(statusKey{}).(*int); ok {
+		*status = code
+	}
+}
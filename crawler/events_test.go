@@ -0,0 +1,50 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEventHandler(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/a">a</a>`)
+		case "/a":
+			fmt.Fprint(w, `<a href="/missing">missing</a>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL + "/")
+	c := newTestCrawler(t, cfg)
+	var events []Event
+	c.SetEventHandler(func(e Event) { events = append(events, e) })
+	c.crawlRoot(context.Background())
+
+	want := []struct {
+		path   string
+		status int
+		depth  int
+	}{{"/", 200, 0}, {"/a", 200, 1}, {"/missing", 404, 2}}
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d events, got %+v", len(want), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.URL != srv.URL+w.path || e.Status != w.status || e.Depth != w.depth {
+			t.Errorf("Expected event %d for %s (%d, depth %d), got %+v", i, w.path, w.status, w.depth, e)
+		}
+		if e.Latency <= 0 {
+			t.Errorf("Expected a latency for %s, got %v", w.path, e.Latency)
+		}
+		if (e.Err != nil) != (w.status >= 400) {
+			t.Errorf("Expected an error only for the failed fetch of %s, got %v", w.path, e.Err)
+		}
+	}
+}
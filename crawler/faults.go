@@ -0,0 +1,28 @@
+package crawler
+
+import (
+	"context"
+	"time"
+)
+
+// injectFault sabotages a cfg.FaultInjection share of fetches before
+// they reach the network. It returns an error wrapping ErrInjectedFault
+// for a fetch that must fail, after holding a delayed one for up to
+// cfg.FaultDelay, and nil for fetches that should go ahead.
+func (c *Crawler) injectFault(ctx context.Context, raw string) error {
+	p := c.cfg.FaultInjection
+	if p <= 0 || c.rand.Float64() >= p {
+		return nil
+	}
+	c.count(func(s *Stats) { s.InjectedFaults++ })
+	if c.cfg.FaultDelay <= 0 || c.rand.Intn(2) == 0 {
+		c.debugf("fault injection: failing %s", raw)
+		return &FetchError{URL: raw, Err: ErrInjectedFault}
+	}
+	d := time.Duration(c.rand.Int63n(int64(c.cfg.FaultDelay)+1)) * time.Millisecond
+	c.debugf("fault injection: delaying %s by %v", raw, d)
+	if !sleepCtx(ctx, d) {
+		return ctx.Err()
+	}
+	return nil
+}
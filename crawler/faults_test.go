@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFaultInjectionRate(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer srv.Close()
+
+	const n = 1000
+	cfg := testConfig(srv.URL)
+	cfg.FaultInjection = 0.3
+	cfg.Seed = 1
+	c := newTestCrawler(t, cfg)
+
+	failed := 0
+	for i := 0; i < n; i++ {
+		_, _, err := c.fetchOnce(context.Background(), srv.URL+"/")
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, ErrInjectedFault) {
+			t.Fatalf("Expected only injected faults, got %v", err)
+		}
+		failed++
+	}
+
+	if rate := float64(failed) / n; rate < 0.25 || rate > 0.35 {
+		t.Errorf("Expected about 30%% injected failures, got %.1f%%", rate*100)
+	}
+	if got := int(atomic.LoadInt32(&hits)); got != n-failed {
+		t.Errorf("Expected injected failures not to reach the server: %d hits for %d successes", got, n-failed)
+	}
+	s := c.Snapshot()
+	if s.InjectedFaults != failed || s.Errors != failed {
+		t.Errorf("Expected %d injected faults counted as errors, got %d faults and %d errors", failed, s.InjectedFaults, s.Errors)
+	}
+}
+
+func TestFaultInjectionDelay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.FaultInjection = 1
+	cfg.FaultDelay = 20
+	cfg.Seed = 1
+	c := newTestCrawler(t, cfg)
+
+	var failed, delayed int
+	for i := 0; i < 40; i++ {
+		_, _, err := c.fetchOnce(context.Background(), srv.URL+"/")
+		switch {
+		case errors.Is(err, ErrInjectedFault):
+			failed++
+		case err != nil:
+			t.Fatalf("Expected delayed fetches to succeed, got %v", err)
+		default:
+			delayed++
+		}
+	}
+	if failed == 0 || delayed == 0 {
+		t.Errorf("Expected both failed and delayed fetches, got %d failed and %d delayed", failed, delayed)
+	}
+}
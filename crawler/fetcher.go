@@ -0,0 +1,88 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Fetcher retrieves URLs of the schemes it is registered for with
+// RegisterFetcher. It returns the body, at most maxBodyBytes of it, and
+// its media type. Fetchers must be safe for concurrent use.
+type Fetcher interface {
+	Fetch(ctx context.Context, u *url.URL) (body []byte, contentType string, err error)
+}
+
+// FetcherFunc adapts a function to Fetcher.
+type FetcherFunc func(ctx context.Context, u *url.URL) ([]byte, string, error)
+
+// Fetch implements Fetcher.
+func (f FetcherFunc) Fetch(ctx context.Context, u *url.URL) ([]byte, string, error) {
+	return f(ctx, u)
+}
+
+// RegisterFetcher routes URLs with the given scheme to f and lets links
+// with that scheme into the queue. It replaces any fetcher already
+// registered for the scheme, including the built-in http and https one,
+// and must be called before crawling starts.
+func (c *Crawler) RegisterFetcher(scheme string, f Fetcher) {
+	c.fetchers[strings.ToLower(scheme)] = f
+}
+
+// defaultFetchers returns the fetchers every crawler starts with.
+func (c *Crawler) defaultFetchers() map[string]Fetcher {
+	web := FetcherFunc(func(ctx context.Context, u *url.URL) ([]byte, string, error) {
+		if u.Scheme == "http" && c.cfg.UpgradeToHTTPS {
+			return c.fetchUpgraded(ctx, u)
+		}
+		return c.request(ctx, u.String())
+	})
+	m := map[string]Fetcher{"http": web, "https": web}
+	if c.cfg.AllowFileURLs {
+		m["file"] = FetcherFunc(fetchFile)
+	}
+	return m
+}
+
+// dispatch hands raw to the fetcher registered for its scheme.
+func (c *Crawler) dispatch(ctx context.Context, raw string) ([]byte, string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, "", &FetchError{URL: raw, Err: err}
+	}
+	f, ok := c.fetchers[u.Scheme]
+	if !ok {
+		return nil, "", &FetchError{URL: raw, Err: fmt.Errorf("no fetcher for scheme %q", u.Scheme)}
+	}
+	return f.Fetch(ctx, u)
+}
+
+// fetchFile reads a local file:// URL. The media type is guessed from the
+// file extension, defaulting to HTML so that extensionless pages are
+// parsed for links.
+func fetchFile(ctx context.Context, u *url.URL) ([]byte, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", &FetchError{URL: u.String(), Err: err}
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return nil, "", &FetchError{URL: u.String(), Err: fmt.Errorf("remote file host %q", u.Host)}
+	}
+	f, err := os.Open(filepath.FromSlash(u.Path))
+	if err != nil {
+		return nil, "", &FetchError{URL: u.String(), Err: err}
+	}
+	defer f.Close()
+	body, _, err := readBody(ctx, f, maxBodyBytes)
+	if err != nil {
+		return nil, "", &FetchError{URL: u.String(), Err: err}
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(u.Path))
+	if contentType == "" {
+		contentType = "text/html"
+	}
+	return body, contentType, nil
+}
@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCrawlLocalFiles(t *testing.T) {
+	dir := t.TempDir()
+	pages := map[string]string{
+		"index.html":     `<a href="a.html">a</a><a href="sub/b.html">b</a>`,
+		"a.html":         `<a href="index.html">home</a>`,
+		"sub/b.html":     `<a href="../a.html">a</a>`,
+		"notes.txt":      `not linked`,
+		"sub/.gitignore": ``,
+	}
+	for name, body := range pages {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dir, "index.html"))}).String()
+
+	cfg := testConfig(root)
+	cfg.MaxDepth = 5
+	cfg.AllowFileURLs = true
+	c := newTestCrawler(t, cfg)
+	c.crawlRoot(context.Background())
+
+	for _, name := range []string{"a.html", "sub/b.html"} {
+		u := (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dir, name))}).String()
+		if !c.isVisited(context.Background(), u) {
+			t.Errorf("Expected %s to be crawled", u)
+		}
+	}
+}
+
+func TestFileURLsNeedOptIn(t *testing.T) {
+	c := newTestCrawler(t, testConfig("http://example.com"))
+	if got := c.rejectReason("file:///etc/passwd"); got != `unsupported scheme "file"` {
+		t.Errorf("Expected file URLs to be rejected by default, got %q", got)
+	}
+	if _, _, err := c.fetch(context.Background(), "file:///etc/passwd"); err == nil {
+		t.Error("Expected fetching a file URL to fail by default")
+	}
+}
+
+func TestRegisterFetcher(t *testing.T) {
+	c := newTestCrawler(t, testConfig("gemini://capsule.example/"))
+	var fetched []string
+	c.RegisterFetcher("gemini", FetcherFunc(func(_ context.Context, u *url.URL) ([]byte, string, error) {
+		fetched = append(fetched, u.String())
+		return []byte(`<a href="/log">log</a>`), "text/html", nil
+	}))
+
+	body, contentType, err := c.fetch(context.Background(), "gemini://capsule.example/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := c.pageLinks(body, contentType, "gemini://capsule.example/")
+	if len(links) != 1 || links[0] != "gemini://capsule.example/log" {
+		t.Errorf("Expected the gemini link to be accepted, got %v", links)
+	}
+	if len(fetched) != 1 {
+		t.Errorf("Expected the registered fetcher to be used once, got %v", fetched)
+	}
+}
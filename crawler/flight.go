@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+)
+
+// flightGroup coalesces concurrent fetches of one URL so that a single
+// request is made and every caller shares its result, in the manner of
+// golang.org/x/sync/singleflight.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+// flight is a fetch in progress or just completed.
+type flight struct {
+	done        chan struct{}
+	body        []byte
+	contentType string
+	err         error
+	abandoned   bool // the leader's ctx ended, so err is not the server's doing
+}
+
+// do runs fn for key unless a call for key is already in flight, in which
+// case it waits for that call and returns its result. shared reports
+// whether the result was produced by another caller's request. A waiter
+// stops waiting when its own ctx ends, getting ctx's error; ctx is also
+// the one fn runs under when this caller makes the request.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() ([]byte, string, error)) (f *flight, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flight)
+	}
+	if f, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-f.done:
+			return f, true
+		case <-ctx.Done():
+			return &flight{err: ctx.Err(), abandoned: true}, true
+		}
+	}
+	f = &flight{done: make(chan struct{})}
+	g.calls[key] = f
+	g.mu.Unlock()
+
+	f.body, f.contentType, f.err = fn()
+	f.abandoned = f.err != nil && ctx.Err() != nil
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(f.done)
+	return f, false
+}
@@ -0,0 +1,149 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrentFetchesCoalesce(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		w.Write([]byte(`<a href="/next">next</a>`))
+	}))
+	defer srv.Close()
+
+	c := newTestCrawler(t, testConfig(srv.URL))
+
+	const callers = 8
+	var wg sync.WaitGroup
+	bodies := make([][]byte, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bodies[i], _, errs[i] = c.fetch(context.Background(), srv.URL)
+		}(i)
+	}
+	// Let every caller join the in-flight request before it completes.
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("Expected 1 request for %d concurrent fetches, got %d", callers, got)
+	}
+	for i := range bodies {
+		if errs[i] != nil || string(bodies[i]) != `<a href="/next">next</a>` {
+			t.Errorf("caller %d: expected the shared body, got %q, %v", i, bodies[i], errs[i])
+		}
+	}
+	if s := c.stats; s.Requests != 1 {
+		t.Errorf("Expected 1 counted request, got %d", s.Requests)
+	}
+
+	// A later fetch is not coalesced with the finished one.
+	if _, _, err := c.fetch(context.Background(), srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("Expected a fresh request after the first completed, got %d hits", got)
+	}
+}
+
+func TestFetchWaiterHonoursOwnContext(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c := newTestCrawler(t, testConfig(srv.URL))
+	go c.fetch(context.Background(), srv.URL)
+	time.Sleep(50 * time.Millisecond) // let the first fetch take the flight
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, _, err := c.fetch(ctx, srv.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the waiter's own deadline, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the waiter to give up with its context, waited %v", elapsed)
+	}
+}
+
+func TestFetchRetriesAbandonedFlight(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			<-r.Context().Done() // hold the first request until its caller gives up
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	c := newTestCrawler(t, testConfig(srv.URL))
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		c.fetch(leaderCtx, srv.URL)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	type result struct {
+		body []byte
+		err  error
+	}
+	waiter := make(chan result, 1)
+	go func() {
+		body, _, err := c.fetch(context.Background(), srv.URL)
+		waiter <- result{body, err}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancelLeader()
+	<-leaderDone
+
+	r := <-waiter
+	if r.err != nil || string(r.body) != "ok" {
+		t.Errorf("Expected the waiter to make its own request, got %q, %v", r.body, r.err)
+	}
+	if n := c.Snapshot().Errors; n != 0 {
+		t.Errorf("Expected the abandoned request not to count as an error, got %d", n)
+	}
+}
+
+func TestMarkVisitedOnce(t *testing.T) {
+	c := newTestCrawler(t, testConfig("http://example.com"))
+
+	var wins int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c.markVisited(context.Background(), "http://example.com/page") {
+				atomic.AddInt32(&wins, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if wins != 1 {
+		t.Errorf("Expected exactly one caller to claim the URL, got %d", wins)
+	}
+	if !c.isVisited(context.Background(), "http://example.com/page") {
+		t.Error("Expected the URL to be visited")
+	}
+}
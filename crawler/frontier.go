@@ -0,0 +1,52 @@
+package crawler
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// breadthFirst crawls the queued root links level by level for
+// cfg.FrontierMode. Each level visits every link of a frozen frontier,
+// in random order, while the links those pages yield are collected in
+// c.links as the next level's frontier, so pages found at depth N are
+// only visited at depth N+1. cfg.MaxQueueSize bounds each frontier.
+// With cfg.MaxWorkers above 1 a level's links are visited by a
+// workerPool that grows with the links left to visit.
+func (c *Crawler) breadthFirst(ctx context.Context) {
+	frontier := c.links
+	for depth := 0; depth < c.cfg.MaxDepth && len(frontier) > 0; depth++ {
+		c.links = nil
+		visit := func(target string) {
+			if c.stopped(ctx) || c.isStale(target) || !c.markVisited(ctx, target) {
+				return
+			}
+			if _, err := c.visit(ctx, target, depth+1); err != nil {
+				log.Printf("visit %s: %v", target, err)
+				return
+			}
+
+			time.Sleep(c.thinkTime(hostOf(target)))
+		}
+		order := c.rand.Perm(len(frontier))
+		if c.cfg.MaxWorkers > 1 {
+			links := make([]string, len(order))
+			for i, idx := range order {
+				links[i] = frontier[idx]
+			}
+			pool := newWorkerPool(c.cfg.MaxWorkers, &linkList{links: links}, visit)
+			pool.grow()
+			pool.wait()
+			c.debugf("frontier depth %d: up to %d workers for %d links", depth, pool.peak, len(links))
+		} else {
+			for _, i := range order {
+				if c.stopped(ctx) {
+					return
+				}
+				visit(frontier[i])
+			}
+		}
+		c.debugf("frontier depth %d done: %d links for depth %d", depth, len(c.links), depth+1)
+		frontier = c.links
+	}
+}
@@ -0,0 +1,111 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFrontierModeVisitsLevelByLevel(t *testing.T) {
+	pages := map[string][]string{
+		"/":   {"/a", "/b", "/c"},
+		"/a":  {"/a1", "/a2"},
+		"/b":  {"/b1"},
+		"/c":  {"/c1", "/a"},
+		"/a1": {"/a1x"},
+	}
+	level := map[string]int{
+		"/a": 0, "/b": 0, "/c": 0,
+		"/a1": 1, "/a2": 1, "/b1": 1, "/c1": 1,
+		"/a1x": 2,
+	}
+
+	var mu sync.Mutex
+	var order []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		order = append(order, r.URL.Path)
+		mu.Unlock()
+		for _, link := range pages[r.URL.Path] {
+			fmt.Fprintf(w, `<a href="%s">link</a>`, link)
+		}
+	}))
+	defer srv.Close()
+
+	for run := 0; run < 10; run++ {
+		mu.Lock()
+		order = nil
+		mu.Unlock()
+		cfg := testConfig(srv.URL + "/")
+		cfg.MaxDepth = 3
+		cfg.FrontierMode = true
+		c := newTestCrawler(t, cfg)
+		c.crawlRoot(context.Background())
+
+		mu.Lock()
+		visits := append([]string(nil), order[1:]...) // skip the root itself
+		mu.Unlock()
+		if len(visits) != len(level) {
+			t.Fatalf("Expected every page once, got %v", visits)
+		}
+		for i := 1; i < len(visits); i++ {
+			if level[visits[i]] < level[visits[i-1]] {
+				t.Fatalf("Expected links found at depth N to be visited at depth N+1, got %v", visits)
+			}
+		}
+	}
+}
+
+func TestFrontierWorkersVisitInParallel(t *testing.T) {
+	pages := map[string][]string{"/": nil}
+	for i := 0; i < 12; i++ {
+		page := fmt.Sprintf("/p%d", i)
+		pages["/"] = append(pages["/"], page)
+		pages[page] = []string{page + "/child"}
+	}
+	var (
+		mu                sync.Mutex
+		inFlight, busiest int
+		hits              = make(map[string]int)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		inFlight++
+		busiest = max(busiest, inFlight)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		for _, link := range pages[r.URL.Path] {
+			fmt.Fprintf(w, `<a href="%s">link</a>`, link)
+		}
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL + "/")
+	cfg.MaxDepth = 3
+	cfg.FrontierMode = true
+	cfg.MaxWorkers = 4
+	c := newTestCrawler(t, cfg)
+	c.crawlRoot(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(hits) != 25 {
+		t.Errorf("Expected the root, 12 pages and 12 children, got %d paths", len(hits))
+	}
+	for path, n := range hits {
+		if n != 1 {
+			t.Errorf("Expected %s to be fetched once, got %d", path, n)
+		}
+	}
+	if busiest < 2 || busiest > 4 {
+		t.Errorf("Expected between 2 and max_workers 4 fetches at once, got %d", busiest)
+	}
+}
@@ -0,0 +1,69 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rampUpFloor is the share of the global rate allowed when a crawl
+// starts with cfg.RampUpDuration set.
+const rampUpFloor = 0.1
+
+// leakyBucket spaces requests evenly at a fixed rate with no burst, so
+// the total outbound cadence stays predictable however many hosts are
+// crawled. It composes with the per-host adaptiveRate: a request waits
+// for both. Over the first ramp of a crawl the rate climbs linearly from
+// rampUpFloor of its target.
+type leakyBucket struct {
+	interval time.Duration
+	ramp     time.Duration
+
+	mu   sync.Mutex
+	next time.Time // earliest start of the next request
+}
+
+func newLeakyBucket(perSecond float64, ramp time.Duration) *leakyBucket {
+	return &leakyBucket{
+		interval: time.Duration(float64(time.Second) / perSecond),
+		ramp:     ramp,
+	}
+}
+
+// gap returns the spacing between requests elapsed into the crawl.
+func (b *leakyBucket) gap(elapsed time.Duration) time.Duration {
+	if b.ramp <= 0 || elapsed >= b.ramp {
+		return b.interval
+	}
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	share := rampUpFloor + (1-rampUpFloor)*float64(elapsed)/float64(b.ramp)
+	return time.Duration(float64(b.interval) / share)
+}
+
+// wait blocks until the next request slot, or until ctx is done.
+func (b *leakyBucket) wait(ctx context.Context, elapsed time.Duration) error {
+	b.mu.Lock()
+	now := time.Now()
+	start := b.next
+	if start.Before(now) {
+		start = now
+	}
+	b.next = start.Add(b.gap(elapsed))
+	b.mu.Unlock()
+
+	if d := time.Until(start); d > 0 && !sleepCtx(ctx, d) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// rampElapsed returns how far the crawl is into cfg.RampUpDuration by
+// the crawler's clock. Fetches outside Crawl are never ramped.
+func (c *Crawler) rampElapsed() time.Duration {
+	if c.rampStart.IsZero() {
+		return c.global.ramp
+	}
+	return c.clock.Now().Sub(c.rampStart)
+}
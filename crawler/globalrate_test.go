@@ -0,0 +1,114 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGlobalRequestsPerSecondCapsAggregateRate(t *testing.T) {
+	var mu sync.Mutex
+	var stamps []time.Time
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		stamps = append(stamps, time.Now())
+		mu.Unlock()
+	})
+	// Several hosts, so only a global limit can hold the total down.
+	var hosts []string
+	for i := 0; i < 3; i++ {
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+		hosts = append(hosts, srv.URL)
+	}
+
+	const rps, requests = 50.0, 12
+	cfg := testConfig(hosts...)
+	cfg.GlobalRequestsPerSecond = rps
+	c := newTestCrawler(t, cfg)
+
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			u := fmt.Sprintf("%s/%d", hosts[i%len(hosts)], i)
+			if _, _, err := c.fetch(context.Background(), u); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(stamps) != requests {
+		t.Fatalf("Expected %d requests, got %d", requests, len(stamps))
+	}
+	first, last := stamps[0], stamps[0]
+	for _, s := range stamps {
+		if s.Before(first) {
+			first = s
+		}
+		if s.After(last) {
+			last = s
+		}
+	}
+	// n requests spaced 1/rps apart span at least (n-1)/rps; allow a
+	// little slack for timer jitter.
+	minSpan := time.Duration(float64(requests-1) / rps * float64(time.Second) * 0.9)
+	if span := last.Sub(first); span < minSpan {
+		t.Errorf("Expected %d requests to span at least %v at %g req/s, took %v", requests, minSpan, rps, span)
+	}
+}
+
+func TestGlobalRateWaitHonoursContext(t *testing.T) {
+	b := newLeakyBucket(0.5, 0)
+	if err := b.wait(context.Background(), 0); err != nil {
+		t.Fatalf("Expected the first slot immediately, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := b.wait(ctx, 0); err == nil {
+		t.Error("Expected the second wait to be cut short by the context")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected wait to return at the deadline, took %v", elapsed)
+	}
+}
+
+func TestRampUpRaisesRateOverTime(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.GlobalRequestsPerSecond = 10
+	cfg.RampUpDuration = 10000
+	c := newTestCrawler(t, cfg)
+	clk := &fakeClock{now: time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)}
+	c.SetClock(clk)
+	c.rampStart = clk.Now()
+
+	rate := func() float64 { return float64(time.Second) / float64(c.global.gap(c.rampElapsed())) }
+	if got := rate(); got < 0.99 || got > 1.01 {
+		t.Errorf("Expected the crawl to start at 1 req/s, got %.2f", got)
+	}
+	prev := rate()
+	for i := 0; i < 10; i++ {
+		clk.advance(time.Second)
+		got := rate()
+		if got <= prev {
+			t.Errorf("Expected the rate to rise after %ds, got %.2f after %.2f", i+1, got, prev)
+		}
+		prev = got
+	}
+	if prev < 9.99 || prev > 10.01 {
+		t.Errorf("Expected the full 10 req/s once ramped, got %.2f", prev)
+	}
+	clk.advance(time.Hour)
+	if got := c.global.gap(c.rampElapsed()); got != 100*time.Millisecond {
+		t.Errorf("Expected a steady 100ms gap after the ramp, got %v", got)
+	}
+}
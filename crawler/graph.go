@@ -0,0 +1,107 @@
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// defaultMaxGraphNodes is the cfg.MaxGraphNodes used when it is unset.
+const defaultMaxGraphNodes = 500
+
+// linkGraph records which fetched page linked to which URL, for
+// cfg.GraphFile. Once it holds max nodes, edges to further URLs are
+// dropped, keeping the rendered graph readable.
+type linkGraph struct {
+	mu    sync.Mutex
+	max   int
+	nodes map[string]bool
+	order []string // nodes in the order they were first seen
+	edges map[[2]string]bool
+	links [][2]string // edges in the order they were recorded
+}
+
+func newLinkGraph(max int) *linkGraph {
+	if max <= 0 {
+		max = defaultMaxGraphNodes
+	}
+	return &linkGraph{max: max, nodes: make(map[string]bool), edges: make(map[[2]string]bool)}
+}
+
+// add records an edge from parent to each of children.
+func (g *linkGraph) add(parent string, children []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.node(parent) {
+		return
+	}
+	for _, child := range children {
+		e := [2]string{parent, child}
+		if g.edges[e] || !g.node(child) {
+			continue
+		}
+		g.edges[e] = true
+		g.links = append(g.links, e)
+	}
+}
+
+// node reports whether url is in the graph, adding it while there is
+// room.
+func (g *linkGraph) node(url string) bool {
+	if g.nodes[url] {
+		return true
+	}
+	if len(g.order) >= g.max {
+		return false
+	}
+	g.nodes[url] = true
+	g.order = append(g.order, url)
+	return true
+}
+
+// writeDOT renders the graph in Graphviz DOT, nodes and edges in the
+// order they were recorded.
+func (g *linkGraph) writeDOT(w io.Writer) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph urusai {")
+	for _, n := range g.order {
+		fmt.Fprintf(bw, "\t%s;\n", dotQuote(n))
+	}
+	for _, e := range g.links {
+		fmt.Fprintf(bw, "\t%s -> %s;\n", dotQuote(e[0]), dotQuote(e[1]))
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotQuote returns s as a DOT quoted string.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// writeGraph saves the link graph to cfg.GraphFile.
+func (c *Crawler) writeGraph() {
+	if c.graph == nil {
+		return
+	}
+	f, err := os.OpenFile(c.cfg.GraphFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		log.Printf("write link graph: %v", err)
+		return
+	}
+	err = c.graph.writeDOT(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Printf("write link graph: %v", err)
+		return
+	}
+	log.Printf("wrote a link graph of %d pages and %d links to %s", len(c.graph.order), len(c.graph.links), c.cfg.GraphFile)
+}
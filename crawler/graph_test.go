@@ -0,0 +1,69 @@
+package crawler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGraphFile(t *testing.T) {
+	var hits int32
+	srv := linkSite(t, map[string][]string{"/": {"/a", "/b"}, "/a": {"/b", "/"}}, &hits)
+	path := filepath.Join(t.TempDir(), "graph.dot")
+
+	cfg := testConfig(srv.URL + "/")
+	cfg.GraphFile = path
+	c := newTestCrawler(t, cfg)
+	for _, page := range []string{"/", "/a"} {
+		if _, err := c.visit(context.Background(), srv.URL+page, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.writeGraph()
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.ReplaceAll(`digraph urusai {
+	"U/";
+	"U/a";
+	"U/b";
+	"U/" -> "U/a";
+	"U/" -> "U/b";
+	"U/a" -> "U/b";
+	"U/a" -> "U/";
+}
+`, "U", srv.URL)
+	if string(got) != want {
+		t.Errorf("Expected DOT\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestLinkGraphNodeCap(t *testing.T) {
+	g := newLinkGraph(2)
+	g.add("http://example.com/", []string{"http://example.com/a", "http://example.com/b"})
+	g.add("http://example.com/b", []string{"http://example.com/"})
+
+	var b strings.Builder
+	if err := g.writeDOT(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph urusai {
+	"http://example.com/";
+	"http://example.com/a";
+	"http://example.com/" -> "http://example.com/a";
+}
+`
+	if b.String() != want {
+		t.Errorf("Expected the graph capped at 2 nodes\n%s\ngot\n%s", want, b.String())
+	}
+}
+
+func TestDOTQuote(t *testing.T) {
+	if got := dotQuote(`http://example.com/?q="a\b"`); got != `"http://example.com/?q=\"a\\b\""` {
+		t.Errorf("Expected quotes and backslashes escaped, got %s", got)
+	}
+}
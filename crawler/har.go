@@ -0,0 +1,99 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReplayStep is one request of a recorded session: the URL and how long
+// to wait after the previous request before issuing it.
+type ReplayStep struct {
+	URL   string
+	Delay time.Duration
+}
+
+// har is the subset of the HTTP Archive 1.2 format used for replay.
+type har struct {
+	Log struct {
+		Entries []struct {
+			StartedDateTime time.Time `json:"startedDateTime"`
+			Request         struct {
+				Method string `json:"method"`
+				URL    string `json:"url"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// LoadHAR reads the GET requests of a HAR file into a replay schedule in
+// the order they started, keeping their original spacing. Other methods
+// and non-http(s) URLs are skipped.
+func LoadHAR(path string) ([]ReplayStep, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var h har
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("parse HAR %s: %w", path, err)
+	}
+
+	entries := h.Log.Entries[:0]
+	for _, e := range h.Log.Entries {
+		u := e.Request.URL
+		if !strings.EqualFold(e.Request.Method, http.MethodGet) ||
+			!(strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://")) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime.Before(entries[j].StartedDateTime)
+	})
+
+	steps := make([]ReplayStep, len(entries))
+	for i, e := range entries {
+		steps[i].URL = e.Request.URL
+		if i > 0 {
+			steps[i].Delay = e.StartedDateTime.Sub(entries[i-1].StartedDateTime)
+		}
+	}
+	return steps, nil
+}
+
+// Replay issues steps in order, waiting each step's Delay multiplied by
+// scale first (0 replays without pauses). Blacklisted URLs are skipped.
+// It stops when ctx ends or cfg.Timeout elapses, returning nil, or with
+// ErrTooManyErrors once cfg.MaxErrors is exceeded and ErrByteLimit once
+// cfg.MaxTotalBytes is reached.
+func (c *Crawler) Replay(ctx context.Context, steps []ReplayStep, scale float64) error {
+	c.startTime = time.Now()
+	defer c.writeDiscoveredHosts()
+
+	for i, step := range steps {
+		if !sleepCtx(ctx, time.Duration(float64(step.Delay)*scale)) || c.isTimeoutReached() {
+			return nil
+		}
+		if c.tooManyErrors() {
+			return fmt.Errorf("%w: %d failed, max_errors is %d", ErrTooManyErrors, c.errorCount(), c.cfg.MaxErrors)
+		}
+		if c.byteLimitReached() {
+			return c.byteLimitError()
+		}
+		if blk := c.blacklistedBy(step.URL); blk != "" {
+			c.debugf("replay %s: blacklisted by %q", step.URL, blk)
+			continue
+		}
+		if _, _, err := c.fetch(ctx, step.URL); err != nil && ctx.Err() == nil {
+			log.Printf("replay %d/%d %s: %v", i+1, len(steps), step.URL, err)
+		}
+	}
+	return nil
+}
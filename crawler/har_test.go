@@ -0,0 +1,101 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const sessionHAR = `{"log": {"version": "1.2", "entries": [
+	{"startedDateTime": "2024-05-01T10:00:01.500Z", "request": {"method": "GET", "url": "%s/article"}},
+	{"startedDateTime": "2024-05-01T10:00:00.000Z", "request": {"method": "GET", "url": "%s/"}},
+	{"startedDateTime": "2024-05-01T10:00:01.000Z", "request": {"method": "POST", "url": "%s/login"}},
+	{"startedDateTime": "2024-05-01T10:00:01.600Z", "request": {"method": "GET", "url": "data:image/png;base64,AAAA"}},
+	{"startedDateTime": "2024-05-01T10:00:04.000Z", "request": {"method": "get", "url": "%s/logout"}},
+	{"startedDateTime": "2024-05-01T10:00:05.000Z", "request": {"method": "GET", "url": "%s/comments"}}
+]}}`
+
+func writeHAR(t *testing.T, base string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "session.har")
+	data := strings.ReplaceAll(sessionHAR, "%s", base)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadHAR(t *testing.T) {
+	steps, err := LoadHAR(writeHAR(t, "https://example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ReplayStep{
+		{"https://example.com/", 0},
+		{"https://example.com/article", 1500 * time.Millisecond},
+		{"https://example.com/logout", 2500 * time.Millisecond},
+		{"https://example.com/comments", time.Second},
+	}
+	if len(steps) != len(want) {
+		t.Fatalf("Expected %d steps, got %v", len(want), steps)
+	}
+	for i := range want {
+		if steps[i] != want[i] {
+			t.Errorf("step %d: expected %+v, got %+v", i, want[i], steps[i])
+		}
+	}
+}
+
+func TestLoadHARRejectsMalformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.har")
+	if err := os.WriteFile(path, []byte(`{"log": {"entries": [`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadHAR(path); err == nil {
+		t.Error("Expected a truncated HAR to fail")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	var times []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		times = append(times, time.Now())
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	steps, err := LoadHAR(writeHAR(t, srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(srv.URL)
+	cfg.BlacklistedURLs = []string{"logout"}
+	c := newTestCrawler(t, cfg)
+
+	const scale = 0.04 // 1.5s, 2.5s and 1s become 60ms, 100ms and 40ms
+	if err := c.Replay(context.Background(), steps, scale); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(paths, " "); got != "/ /article /comments" {
+		t.Errorf("Expected the recorded GETs in order without the blacklisted one, got %s", got)
+	}
+	if len(times) == 3 {
+		if gap := times[1].Sub(times[0]); gap < 60*time.Millisecond {
+			t.Errorf("Expected a scaled 60ms pause before /article, got %v", gap)
+		}
+		if gap := times[2].Sub(times[1]); gap < 140*time.Millisecond {
+			t.Errorf("Expected the skipped step's delay to be kept, got %v before /comments", gap)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"net/http"
+	"sort"
+)
+
+// keepCase are the request headers cfg.RandomizeHeaderCase leaves
+// canonical: net/http looks them up by exact name when it strips
+// credentials from a redirect to another host.
+var keepCase = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Www-Authenticate":    true,
+	"Cookie":              true,
+	"Cookie2":             true,
+}
+
+// recaseHeaders re-keys h with this run's spelling of each header name
+// for cfg.RandomizeHeaderCase. net/http writes HTTP/1.x header names as
+// they are keyed, so no custom transport is needed; HTTP/2 lowercases
+// them all on the wire regardless. Headers net/http adds itself, such as
+// Host and Accept-Encoding, keep their usual spelling.
+func (c *Crawler) recaseHeaders(h http.Header) {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names) // so a seeded run draws the same spellings
+	for _, name := range names {
+		if keepCase[name] {
+			continue
+		}
+		cased := c.headerCase(name)
+		if cased == name {
+			continue
+		}
+		h[cased] = h[name]
+		if name == "User-Agent" {
+			// An empty canonical entry stops net/http from writing its own
+			// Go-http-client User-Agent next to ours.
+			h[name] = []string{""}
+		} else {
+			delete(h, name)
+		}
+	}
+}
+
+// headerCase returns the spelling used for name during this run, drawn
+// once by flipping the case of each letter with probability one half.
+func (c *Crawler) headerCase(name string) string {
+	c.casingMu.Lock()
+	defer c.casingMu.Unlock()
+	if cased, ok := c.casing[name]; ok {
+		return cased
+	}
+	b := []byte(name)
+	for i, ch := range b {
+		if c.rand.Intn(2) == 0 {
+			continue
+		}
+		switch {
+		case 'a' <= ch && ch <= 'z':
+			b[i] = ch - 'a' + 'A'
+		case 'A' <= ch && ch <= 'Z':
+			b[i] = ch - 'A' + 'a'
+		}
+	}
+	if c.casing == nil {
+		c.casing = make(map[string]string)
+	}
+	c.casing[name] = string(b)
+	return c.casing[name]
+}
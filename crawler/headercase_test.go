@@ -0,0 +1,103 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/calpa/urusai/config"
+)
+
+// rawHeaderServer answers every request with an empty 200 and sends the
+// header block it read, byte for byte, on the returned channel.
+func rawHeaderServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	raw := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				rd := bufio.NewReader(conn)
+				for {
+					var block strings.Builder
+					for {
+						line, err := rd.ReadString('\n')
+						if err != nil {
+							return
+						}
+						if line == "\r\n" {
+							break
+						}
+						block.WriteString(line)
+					}
+					raw <- block.String()
+					if _, err := io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return "http://" + ln.Addr().String(), raw
+}
+
+func TestRandomizeHeaderCase(t *testing.T) {
+	url, raw := rawHeaderServer(t)
+	cfg := testConfig(url)
+	cfg.Seed = 1
+	cfg.BrowserProfile = "firefox"
+	cfg.RandomizeHeaderCase = true
+	cfg.HostOverrides = map[string]config.HostOverride{strings.TrimPrefix(url, "http://"): {Cookies: map[string]string{"session": "s3cret"}}}
+	c := newTestCrawler(t, cfg)
+
+	var blocks []string
+	for i := 0; i < 2; i++ {
+		if _, _, err := c.fetch(context.Background(), url+"/"); err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, <-raw)
+	}
+	if blocks[0] != blocks[1] {
+		t.Errorf("Expected the same casing for the whole run, got\n%s\nand\n%s", blocks[0], blocks[1])
+	}
+
+	var recased, agents int
+	for _, line := range strings.Split(strings.TrimSpace(blocks[0]), "\r\n")[1:] {
+		name, value, _ := strings.Cut(line, ": ")
+		canonical := http.CanonicalHeaderKey(name)
+		if name != canonical {
+			recased++
+		}
+		if keepCase[canonical] && name != canonical {
+			t.Errorf("Expected %s to stay canonical, got %s", canonical, name)
+		}
+		if canonical == "User-Agent" {
+			agents++
+			if value != "urusai-test" {
+				t.Errorf("Expected the configured user agent, got %q", value)
+			}
+		}
+	}
+	if agents != 1 {
+		t.Errorf("Expected exactly one User-Agent line, got %d in\n%s", agents, blocks[0])
+	}
+	if recased == 0 {
+		t.Errorf("Expected some header names off the canonical casing, got\n%s", blocks[0])
+	}
+	if !strings.Contains(blocks[0], "\r\nCookie: session=s3cret") {
+		t.Errorf("Expected the Cookie header to be sent canonical, got\n%s", blocks[0])
+	}
+}
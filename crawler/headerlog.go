@@ -0,0 +1,50 @@
+package crawler
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// sensitiveHeaders are never logged by cfg.LogHeaders, alongside any
+// header set through cfg.HostOverrides.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// logHeaders logs h under label for cfg.LogHeaders at debug level.
+func (c *Crawler) logHeaders(label, raw string, h http.Header) {
+	if !c.debug || !c.cfg.LogHeaders {
+		return
+	}
+	c.debugf("%s %s: %s", label, shorten(raw, maxLoggedURL), c.formatHeaders(raw, h))
+}
+
+// formatHeaders renders h sorted by name as "Name: value; ...", with
+// the values of sensitive headers replaced by redacted.
+func (c *Crawler) formatHeaders(raw string, h http.Header) string {
+	secret := make(map[string]bool)
+	if _, o, ok := lookupHost(c.cfg.HostOverrides, hostOf(raw)); ok {
+		for name := range o.Headers {
+			secret[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fields := make([]string, 0, len(names))
+	for _, name := range names {
+		value := strings.Join(h[name], ", ")
+		if key := http.CanonicalHeaderKey(name); sensitiveHeaders[key] || secret[key] {
+			value = redacted
+		}
+		fields = append(fields, name+": "+value)
+	}
+	return strings.Join(fields, "; ")
+}
@@ -0,0 +1,67 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/calpa/urusai/config"
+)
+
+func TestLogHeadersRedactsCredentials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Served-By", "edge-1")
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "server-secret"})
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	cfg := testConfig(srv.URL)
+	cfg.LogHeaders = true
+	cfg.HostOverrides = map[string]config.HostOverride{u.Host: {
+		Headers: map[string]string{"Authorization": "Bearer tok-secret", "X-Api-Key": "key-secret"},
+		Cookies: map[string]string{"session": "cookie-secret"},
+	}}
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	for _, debug := range []bool{false, true} {
+		logs.Reset()
+		c := newTestCrawler(t, cfg)
+		c.SetDebug(debug)
+		if _, _, err := c.fetch(context.Background(), srv.URL+"/"); err != nil {
+			t.Fatal(err)
+		}
+
+		out := logs.String()
+		if !debug {
+			if strings.Contains(out, "headers") {
+				t.Errorf("Expected no header logs outside debug level, got %q", out)
+			}
+			continue
+		}
+		for _, want := range []string{
+			"User-Agent: urusai-test",
+			"X-Served-By: edge-1",
+			"Authorization: " + redacted,
+			"Cookie: " + redacted,
+			"X-Api-Key: " + redacted,
+			"Set-Cookie: " + redacted,
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Expected %q in the header logs, got %q", want, out)
+			}
+		}
+		if strings.Contains(out, "secret") {
+			t.Errorf("Expected credentials to be redacted, got %q", out)
+		}
+	}
+}
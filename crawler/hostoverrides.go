@@ -0,0 +1,86 @@
+package crawler
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/calpa/urusai/config"
+)
+
+// redacted stands in for override values in logs, which commonly hold
+// API keys and session cookies.
+const redacted = "[redacted]"
+
+// hostOverride returns the cfg.HostOverrides entry for u, along with
+// the key that matched.
+func (c *Crawler) hostOverride(u *url.URL) (string, config.HostOverride, bool) {
+	if u == nil {
+		return "", config.HostOverride{}, false
+	}
+	return lookupHost(c.cfg.HostOverrides, u.Host)
+}
+
+// applyHostOverrides sets the headers and cookies configured for req's
+// host over the global ones. It must run after applyBrowserHeaders.
+func (c *Crawler) applyHostOverrides(req *http.Request) {
+	key, o, ok := c.hostOverride(req.URL)
+	if !ok {
+		return
+	}
+	var applied []string
+	for _, name := range sortedNames(o.Headers) {
+		req.Header.Set(name, o.Headers[name])
+		applied = append(applied, http.CanonicalHeaderKey(name)+"="+redacted)
+	}
+	for _, name := range sortedNames(o.Cookies) {
+		req.AddCookie(&http.Cookie{Name: name, Value: o.Cookies[name]})
+		applied = append(applied, "cookie "+name+"="+redacted)
+	}
+	c.debugf("host override %s for %s: %s", key, shorten(req.URL.String(), maxLoggedURL), strings.Join(applied, ", "))
+}
+
+// redirectHostOverrides swaps the overrides net/http copied from the
+// original request for those of the redirect target, so one host's
+// credentials are not sent to another.
+func (c *Crawler) redirectHostOverrides(req *http.Request, via []*http.Request) {
+	if len(via) == 0 {
+		return
+	}
+	prevKey, prev, hadPrev := c.hostOverride(via[0].URL)
+	key, _, _ := c.hostOverride(req.URL)
+	if key == prevKey {
+		return
+	}
+	if hadPrev {
+		for name := range prev.Headers {
+			delHeaderFold(req.Header, name)
+		}
+		if len(prev.Cookies) > 0 {
+			delHeaderFold(req.Header, "Cookie")
+		}
+		c.applyBrowserHeaders(req)
+	}
+	c.applyHostOverrides(req)
+}
+
+// delHeaderFold deletes name from h under any spelling, since
+// cfg.RandomizeHeaderCase keys headers with non-canonical names that
+// Header.Del misses.
+func delHeaderFold(h http.Header, name string) {
+	for key := range h {
+		if strings.EqualFold(key, name) {
+			delete(h, key)
+		}
+	}
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
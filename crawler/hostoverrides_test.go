@@ -0,0 +1,132 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/calpa/urusai/config"
+)
+
+// headerLog records the tenant header and cookie of each request by path.
+type headerLog struct {
+	mu   sync.Mutex
+	seen map[string]string
+}
+
+func (h *headerLog) handler(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.seen == nil {
+		h.seen = make(map[string]string)
+	}
+	h.seen[r.URL.Path] = r.Header.Get("X-Tenant") + "|" + r.Header.Get("Cookie")
+}
+
+func (h *headerLog) get(path string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.seen[path]
+}
+
+func TestHostOverridesApplyToMatchingHostOnly(t *testing.T) {
+	var tenant, other headerLog
+	tenantSrv := httptest.NewServer(http.HandlerFunc(tenant.handler))
+	defer tenantSrv.Close()
+	otherSrv := httptest.NewServer(http.HandlerFunc(other.handler))
+	defer otherSrv.Close()
+
+	tenantURL, _ := url.Parse(tenantSrv.URL)
+	cfg := testConfig(tenantSrv.URL)
+	cfg.HostOverrides = map[string]config.HostOverride{
+		tenantURL.Host: {
+			Headers: map[string]string{"X-Tenant": "acme"},
+			Cookies: map[string]string{"session": "s3cret"},
+		},
+	}
+	c := newTestCrawler(t, cfg)
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	c.SetDebug(true)
+
+	for _, u := range []string{tenantSrv.URL + "/page", otherSrv.URL + "/page"} {
+		if _, _, err := c.fetch(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := tenant.get("/page"); got != "acme|session=s3cret" {
+		t.Errorf("Expected the tenant host to get its header and cookie, got %q", got)
+	}
+	if got := other.get("/page"); got != "|" {
+		t.Errorf("Expected other hosts to get no overrides, got %q", got)
+	}
+	if strings.Contains(logs.String(), "acme") || strings.Contains(logs.String(), "s3cret") {
+		t.Errorf("Expected override values to be redacted in logs, got %q", logs.String())
+	}
+	if !strings.Contains(logs.String(), "X-Tenant="+redacted) {
+		t.Errorf("Expected the override to be logged by name, got %q", logs.String())
+	}
+}
+
+func TestHostOverridesDroppedOnCrossHostRedirect(t *testing.T) {
+	var other headerLog
+	otherSrv := httptest.NewServer(http.HandlerFunc(other.handler))
+	defer otherSrv.Close()
+	tenantSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, otherSrv.URL+"/landing", http.StatusFound)
+	}))
+	defer tenantSrv.Close()
+
+	tenantURL, _ := url.Parse(tenantSrv.URL)
+	cfg := testConfig(tenantSrv.URL)
+	cfg.HostOverrides = map[string]config.HostOverride{
+		tenantURL.Host: {Headers: map[string]string{"X-Tenant": "acme"}},
+	}
+	c := newTestCrawler(t, cfg)
+
+	if _, _, err := c.fetch(context.Background(), tenantSrv.URL+"/go"); err != nil {
+		t.Fatal(err)
+	}
+	if got := other.get("/landing"); got != "|" {
+		t.Errorf("Expected the redirect target not to receive the tenant's overrides, got %q", got)
+	}
+}
+
+func TestHostOverridesDroppedOnCrossHostRedirectWhenRecased(t *testing.T) {
+	var other headerLog
+	otherSrv := httptest.NewServer(http.HandlerFunc(other.handler))
+	defer otherSrv.Close()
+	tenantSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, otherSrv.URL+"/landing", http.StatusFound)
+	}))
+	defer tenantSrv.Close()
+
+	tenantURL, _ := url.Parse(tenantSrv.URL)
+	cfg := testConfig(tenantSrv.URL)
+	cfg.Seed = 1
+	cfg.RandomizeHeaderCase = true
+	cfg.HostOverrides = map[string]config.HostOverride{
+		tenantURL.Host: {Headers: map[string]string{"X-Tenant": "acme"}},
+	}
+	c := newTestCrawler(t, cfg)
+
+	if _, _, err := c.fetch(context.Background(), tenantSrv.URL+"/go"); err != nil {
+		t.Fatal(err)
+	}
+	if cased := c.headerCase("X-Tenant"); cased == "X-Tenant" {
+		t.Fatalf("Expected the seed to recase X-Tenant, got %s", cased)
+	}
+	if got := other.get("/landing"); got != "|" {
+		t.Errorf("Expected the redirect target not to receive the tenant's overrides, got %q", got)
+	}
+}
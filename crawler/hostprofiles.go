@@ -0,0 +1,75 @@
+package crawler
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/calpa/urusai/config"
+)
+
+// lookupHost returns the entry of a per-host config map for host, tried
+// as host:port first and bare host second, along with the key matched.
+func lookupHost[V any](m map[string]V, host string) (string, V, bool) {
+	var zero V
+	if len(m) == 0 {
+		return "", zero, false
+	}
+	bare := (&url.URL{Host: host}).Hostname()
+	for _, key := range []string{strings.ToLower(host), strings.ToLower(bare)} {
+		if v, ok := m[key]; ok {
+			return key, v, true
+		}
+	}
+	return "", zero, false
+}
+
+// lowerHostKeys returns m with its keys lowercased, since lookupHost
+// lowercases the host it looks up.
+func lowerHostKeys[V any](m map[string]V) map[string]V {
+	if len(m) == 0 {
+		return m
+	}
+	lower := make(map[string]V, len(m))
+	for host, v := range m {
+		lower[strings.ToLower(host)] = v
+	}
+	return lower
+}
+
+// sleepRange returns the think-time bounds for pages on host: those of
+// its cfg.HostProfiles entry where set, the global ones otherwise.
+func (c *Crawler) sleepRange(host string) (int, int) {
+	minSleep, maxSleep := c.cfg.MinSleep, c.cfg.MaxSleep
+	if _, p, ok := lookupHost(c.cfg.HostProfiles, host); ok {
+		if p.MinSleep != nil {
+			minSleep = *p.MinSleep
+		}
+		if p.MaxSleep != nil {
+			maxSleep = *p.MaxSleep
+		}
+	}
+	return minSleep, maxSleep
+}
+
+// newHostRates builds one leaky bucket per host profile with a request
+// rate, keyed like cfg.HostProfiles.
+func newHostRates(profiles map[string]config.HostProfile) map[string]*leakyBucket {
+	rates := make(map[string]*leakyBucket)
+	for host, p := range profiles {
+		if p.RequestsPerSecond > 0 {
+			rates[host] = newLeakyBucket(p.RequestsPerSecond, 0)
+		}
+	}
+	return rates
+}
+
+// waitHostRate blocks until host's profile rate allows another request.
+// Like the global rate it composes with adaptive_rate.
+func (c *Crawler) waitHostRate(ctx context.Context, host string) error {
+	key, _, ok := lookupHost(c.cfg.HostProfiles, host)
+	if !ok || c.hostRates[key] == nil {
+		return nil
+	}
+	return c.hostRates[key].wait(ctx, 0)
+}
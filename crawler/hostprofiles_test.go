@@ -0,0 +1,94 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/calpa/urusai/config"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestHostProfileSleepRanges(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.MinSleep, cfg.MaxSleep = 100, 200
+	cfg.HostProfiles = map[string]config.HostProfile{
+		"api.example.com":    {MinSleep: intPtr(0), MaxSleep: intPtr(0)},
+		"legacy.example.com": {MinSleep: intPtr(5000), MaxSleep: intPtr(6000)},
+		"half.example.com":   {MaxSleep: intPtr(150)},
+	}
+	c := newTestCrawler(t, cfg)
+
+	testCases := []struct {
+		host     string
+		min, max time.Duration
+	}{
+		{"api.example.com", 0, 0},
+		{"legacy.example.com:8080", 5000 * time.Microsecond, 6000 * time.Microsecond},
+		{"half.example.com", 100 * time.Microsecond, 150 * time.Microsecond},
+		{"other.example.com", 100 * time.Microsecond, 200 * time.Microsecond},
+	}
+	for _, tc := range testCases {
+		for i := 0; i < 50; i++ {
+			if got := c.thinkTime(tc.host); got < tc.min || got > tc.max {
+				t.Fatalf("thinkTime(%q) = %v, expected within [%v, %v]", tc.host, got, tc.min, tc.max)
+			}
+		}
+	}
+}
+
+func TestHostProfileRate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer other.Close()
+
+	u, _ := url.Parse(srv.URL)
+	cfg := testConfig(srv.URL)
+	cfg.HostProfiles = map[string]config.HostProfile{u.Host: {RequestsPerSecond: 20}}
+	c := newTestCrawler(t, cfg)
+
+	fetchN := func(base string, n int) time.Duration {
+		start := time.Now()
+		for i := 0; i < n; i++ {
+			if _, _, err := c.fetch(context.Background(), base+"/"+string(rune('a'+i))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return time.Since(start)
+	}
+	if elapsed := fetchN(srv.URL, 5); elapsed < 180*time.Millisecond {
+		t.Errorf("Expected 5 requests at 20 req/s to take at least 200ms, took %v", elapsed)
+	}
+	if elapsed := fetchN(other.URL, 5); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected unlisted hosts to be unpaced, took %v", elapsed)
+	}
+}
+
+func TestHostKeysMatchAnyCase(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.MinSleep, cfg.MaxSleep = 100, 200
+	cfg.HostOverrides = map[string]config.HostOverride{"Example.com": {Headers: map[string]string{"X-Tenant": "acme"}}}
+	cfg.HostPathPrefixes = map[string][]string{"Docs.Example.com:8080": {"/docs"}}
+	cfg.HostProfiles = map[string]config.HostProfile{"API.example.com": {MinSleep: intPtr(0), MaxSleep: intPtr(0)}}
+	cfg.Auth = map[string]config.HostAuth{"Example.COM": {Token: "t"}}
+	c := newTestCrawler(t, cfg)
+
+	u, _ := url.Parse("http://EXAMPLE.com/")
+	if _, _, ok := c.hostOverride(u); !ok {
+		t.Error("Expected the Example.com override to apply to EXAMPLE.com")
+	}
+	if _, _, ok := lookupHost(c.cfg.Auth, "example.com"); !ok {
+		t.Error("Expected the Example.COM credentials to apply to example.com")
+	}
+	if u, _ := url.Parse("http://docs.example.com:8080/blog"); c.allowedPath(u) {
+		t.Error("Expected the Docs.Example.com:8080 path prefixes to apply to docs.example.com:8080")
+	}
+	if lo, hi := c.sleepRange("api.example.com"); lo != 0 || hi != 0 {
+		t.Errorf("Expected the API.example.com sleeps, got [%d, %d]", lo, hi)
+	}
+}
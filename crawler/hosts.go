@@ -0,0 +1,52 @@
+package crawler
+
+import (
+	"sort"
+	"sync"
+)
+
+// hostSet is a concurrency-safe set of host names.
+type hostSet struct {
+	mu sync.Mutex
+	m  map[string]struct{}
+}
+
+func newHostSet() *hostSet {
+	return &hostSet{m: make(map[string]struct{})}
+}
+
+// add inserts host, reporting whether it was not already present.
+func (s *hostSet) add(host string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.m[host]; ok {
+		return false
+	}
+	s.m[host] = struct{}{}
+	return true
+}
+
+func (s *hostSet) has(host string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.m[host]
+	return ok
+}
+
+func (s *hostSet) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.m)
+}
+
+// sorted returns the hosts in lexical order.
+func (s *hostSet) sorted() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]string, 0, len(s.m))
+	for h := range s.m {
+		out = append(out, h)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,118 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiscoveredHostsFile(t *testing.T) {
+	leaf := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer leaf.Close()
+	root := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<a href="%s/page">away</a>`, leaf.URL)
+	}))
+	defer root.Close()
+
+	path := filepath.Join(t.TempDir(), "hosts.txt")
+	cfg := testConfig(root.URL)
+	cfg.DiscoveredHostsFile = path
+	c := newTestCrawler(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := c.Crawl(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var want []string
+	for _, s := range []string{root.URL, leaf.URL} {
+		u, _ := url.Parse(s)
+		want = append(want, u.Host)
+	}
+	sort.Strings(want)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Fields(string(data)); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected hosts %v, got %v", want, got)
+	}
+	if got := c.Hosts(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected Hosts() %v, got %v", want, got)
+	}
+}
+
+func TestHostSet(t *testing.T) {
+	s := newHostSet()
+	if !s.add("b") || !s.add("a") || s.add("b") {
+		t.Error("Expected add to report only new hosts")
+	}
+	if !s.has("a") || s.has("c") {
+		t.Error("Unexpected membership result")
+	}
+	if got := s.sorted(); strings.Join(got, ",") != "a,b" || s.len() != 2 {
+		t.Errorf("Expected [a b], got %v", got)
+	}
+}
+
+func TestMaxHosts(t *testing.T) {
+	cfg := testConfig("http://a.example")
+	cfg.MaxHosts = 2
+	c := newTestCrawler(t, cfg)
+
+	c.hosts.add("a.example")
+	if got := c.rejectReason("http://b.example/"); got != "" {
+		t.Errorf("Expected a second host to be accepted below the cap, got %q", got)
+	}
+	c.hosts.add("b.example")
+
+	testCases := []struct {
+		link string
+		want string
+	}{
+		{"http://a.example/more", ""},
+		{"https://b.example/more", ""},
+		{"http://c.example/", "max_hosts 2 reached"},
+	}
+	for _, tc := range testCases {
+		if got := c.rejectReason(tc.link); got != tc.want {
+			t.Errorf("rejectReason(%q) = %q, expected %q", tc.link, got, tc.want)
+		}
+	}
+}
+
+func TestPreferNewHosts(t *testing.T) {
+	page := []byte(`<a href="http://known.example/1">1</a>
+		<a href="http://known.example/2">2</a>
+		<a href="http://fresh.example/">new</a>
+		<a href="http://known.example/3">3</a>
+		<a href="http://also-known.example/">4</a>`)
+
+	for i := 0; i < 20; i++ {
+		cfg := testConfig("http://known.example")
+		cfg.PreferNewHosts = true
+		c := newTestCrawler(t, cfg)
+		c.hosts.add("known.example")
+		c.hosts.add("also-known.example")
+		c.enqueue(c.pageLinks(page, "text/html", "http://known.example/"))
+
+		target, ok := c.nextTarget(context.Background())
+		if !ok || target != "http://fresh.example/" {
+			t.Fatalf("Expected the link to the new host first, got %q", target)
+		}
+		c.hosts.add("fresh.example")
+		if target, ok := c.nextTarget(context.Background()); !ok || target == "" {
+			t.Error("Expected a fallback to known hosts once no new host is left")
+		}
+	}
+}
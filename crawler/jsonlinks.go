@@ -0,0 +1,95 @@
+package crawler
+
+import (
+	"encoding/json"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+// isJSON reports whether contentType names a JSON document, including
+// structured suffixes such as application/hal+json and
+// application/vnd.api+json.
+func isJSON(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+// extractJSONLinks returns the acceptable links found in a HAL or
+// JSON:API document. Both conventions are recognised anywhere in the
+// tree, so links of embedded resources (HAL _embedded, JSON:API data and
+// included) are followed too. Templated HAL links are skipped.
+func (c *Crawler) extractJSONLinks(body []byte, base string) []string {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		c.debugf("parse JSON from %s: %v", base, err)
+		return nil
+	}
+	baseURL, _ := url.Parse(base)
+
+	var out []string
+	for _, href := range jsonHrefs(doc, nil) {
+		link := c.normalize(href, baseURL)
+		if c.keepLink(base, link) {
+			out = append(out, link)
+		}
+	}
+	return out
+}
+
+// jsonHrefs walks v and appends every href held by a "_links" (HAL) or
+// "links" (JSON:API) member.
+func jsonHrefs(v any, out []string) []string {
+	switch v := v.(type) {
+	case []any:
+		for _, e := range v {
+			out = jsonHrefs(e, out)
+		}
+	case map[string]any:
+		for k, e := range v {
+			if k == "_links" || k == "links" {
+				out = linkObjectHrefs(e, out)
+				continue
+			}
+			out = jsonHrefs(e, out)
+		}
+	}
+	return out
+}
+
+// linkObjectHrefs reads a links member: an object mapping relation names
+// to a URL string, a link object with "href", or an array of either.
+func linkObjectHrefs(v any, out []string) []string {
+	rels, ok := v.(map[string]any)
+	if !ok {
+		return out
+	}
+	for _, rel := range rels {
+		out = appendHref(rel, out)
+	}
+	return out
+}
+
+func appendHref(v any, out []string) []string {
+	switch v := v.(type) {
+	case string:
+		if v != "" {
+			out = append(out, v)
+		}
+	case []any:
+		for _, e := range v {
+			out = appendHref(e, out)
+		}
+	case map[string]any:
+		if templated, _ := v["templated"].(bool); templated {
+			return out
+		}
+		if href, _ := v["href"].(string); href != "" {
+			out = append(out, href)
+		}
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestExtractJSONLinks(t *testing.T) {
+	testCases := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{
+			name: "HAL",
+			doc: `{
+				"_links": {
+					"self": {"href": "/orders"},
+					"next": {"href": "/orders?page=2"},
+					"find": {"href": "/orders{?id}", "templated": true},
+					"curies": [{"href": "http://example.com/docs/{rel}", "templated": true}]
+				},
+				"_embedded": {
+					"orders": [
+						{"_links": {"self": {"href": "/orders/123"}}},
+						{"_links": {"self": {"href": "http://other.example/orders/124"}}}
+					]
+				}
+			}`,
+			want: []string{
+				"http://example.com/orders",
+				"http://example.com/orders/123",
+				"http://example.com/orders?page=2",
+				"http://other.example/orders/124",
+			},
+		},
+		{
+			name: "JSON:API",
+			doc: `{
+				"links": {"self": "/articles", "next": "/articles?page=2", "prev": null},
+				"data": [{
+					"type": "articles",
+					"id": "1",
+					"links": {"self": "/articles/1"},
+					"relationships": {
+						"author": {"links": {"related": {"href": "/articles/1/author", "meta": {"count": 1}}}}
+					}
+				}]
+			}`,
+			want: []string{
+				"http://example.com/articles",
+				"http://example.com/articles/1",
+				"http://example.com/articles/1/author",
+				"http://example.com/articles?page=2",
+			},
+		},
+		{
+			name: "Plain JSON",
+			doc:  `{"href": "/ignored", "items": ["/also-ignored"]}`,
+		},
+		{
+			name: "Malformed",
+			doc:  `{"_links": `,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestCrawler(t, testConfig("http://example.com/"))
+			got := c.extractJSONLinks([]byte(tc.doc), "http://example.com/api")
+			sort.Strings(got)
+			if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestIsJSON(t *testing.T) {
+	for ct, want := range map[string]bool{
+		"application/json":                    true,
+		"application/hal+json; charset=utf-8": true,
+		"application/vnd.api+json":            true,
+		"text/html":                           false,
+		"":                                    false,
+	} {
+		if got := isJSON(ct); got != want {
+			t.Errorf("isJSON(%q): expected %v, got %v", ct, want, got)
+		}
+	}
+}
+
+func TestPageLinksFollowsJSONWhenEnabled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/hal+json")
+		w.Write([]byte(`{"_links": {"next": {"href": "/page/2"}}}`))
+	}))
+	defer srv.Close()
+
+	for _, follow := range []bool{false, true} {
+		cfg := testConfig(srv.URL)
+		cfg.FollowJSONLinks = follow
+		c := newTestCrawler(t, cfg)
+		body, contentType, err := c.fetch(context.Background(), srv.URL)
+		if err != nil {
+			t.Fatalf("fetch: %v", err)
+		}
+		links := c.pageLinks(body, contentType, srv.URL)
+		if follow && (len(links) != 1 || links[0] != srv.URL+"/page/2") {
+			t.Errorf("Expected [%s/page/2], got %v", srv.URL, links)
+		}
+		if !follow && len(links) != 0 {
+			t.Errorf("Expected no links with follow_json_links off, got %v", links)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultLatencyWindow is used when MaxHostLatency is set without an
+// explicit HostLatencyWindow.
+const defaultLatencyWindow = 5
+
+// hostLatency keeps a rolling window of fetch durations per host so that
+// consistently slow targets can be dropped from the crawl.
+type hostLatency struct {
+	mu      sync.Mutex
+	window  int
+	samples map[string][]time.Duration
+}
+
+func newHostLatency(window int) *hostLatency {
+	if window <= 0 {
+		window = defaultLatencyWindow
+	}
+	return &hostLatency{
+		window:  window,
+		samples: make(map[string][]time.Duration),
+	}
+}
+
+// observe records one fetch duration, evicting the oldest sample once the
+// window is full.
+func (h *hostLatency) observe(host string, d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := append(h.samples[host], d)
+	if len(s) > h.window {
+		s = s[len(s)-h.window:]
+	}
+	h.samples[host] = s
+}
+
+// average returns the mean of the samples recorded for host, or 0 when
+// the host has not been fetched yet.
+func (h *hostLatency) average(host string) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.samples[host]
+	if len(s) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range s {
+		total += d
+	}
+	return total / time.Duration(len(s))
+}
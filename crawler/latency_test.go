@@ -0,0 +1,50 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSlowHostIsDropped(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer fast.Close()
+
+	cfg := testConfig(slow.URL, fast.URL)
+	cfg.MaxHostLatency = 20
+	cfg.HostLatencyWindow = 2
+	c := newTestCrawler(t, cfg)
+
+	for _, u := range []string{slow.URL, fast.URL} {
+		if _, _, err := c.fetch(context.Background(), u); err != nil {
+			t.Fatalf("fetch %s: %v", u, err)
+		}
+	}
+
+	if c.accept(slow.URL + "/next") {
+		t.Error("Expected link to slow host to be rejected")
+	}
+	if !c.accept(fast.URL + "/next") {
+		t.Error("Expected link to fast host to be accepted")
+	}
+}
+
+func TestHostLatencyWindow(t *testing.T) {
+	h := newHostLatency(2)
+	h.observe("a", 100*time.Millisecond)
+	h.observe("a", 10*time.Millisecond)
+	h.observe("a", 10*time.Millisecond)
+
+	if got := h.average("a"); got != 10*time.Millisecond {
+		t.Errorf("Expected average 10ms after window rollover, got %v", got)
+	}
+	if got := h.average("b"); got != 0 {
+		t.Errorf("Expected 0 for unknown host, got %v", got)
+	}
+}
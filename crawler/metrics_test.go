@@ -0,0 +1,73 @@
+package crawler
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestStatsDMetrics(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.StatsDAddr = pc.LocalAddr().String()
+	cfg.StatsDTags = []string{"env:test"}
+	c := newTestCrawler(t, cfg)
+
+	if _, _, err := c.fetch(context.Background(), srv.URL+"/"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.fetch(context.Background(), srv.URL+"/missing"); err == nil {
+		t.Fatal("Expected the 404 to fail")
+	}
+
+	want := []*regexp.Regexp{
+		regexp.MustCompile(`^urusai\.requests:1\|c\|#env:test$`),
+		regexp.MustCompile(`^urusai\.latency:\d+\|ms\|#env:test$`),
+		regexp.MustCompile(`^urusai\.requests:1\|c\|#env:test$`),
+		regexp.MustCompile(`^urusai\.errors:1\|c\|#env:test$`),
+	}
+	buf := make([]byte, 512)
+	for i, re := range want {
+		if err := pc.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("metric %d: %v", i, err)
+		}
+		if line := string(buf[:n]); !re.MatchString(line) {
+			t.Errorf("metric %d: expected %s, got %q", i, re, line)
+		}
+	}
+}
+
+func TestStatsDUnreachableDoesNotBlock(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// Nothing listens on the reserved port; sends fail silently.
+	cfg := testConfig(srv.URL)
+	cfg.StatsDAddr = "127.0.0.1:9"
+	c := newTestCrawler(t, cfg)
+	for i := 0; i < 3; i++ {
+		if _, _, err := c.fetch(context.Background(), srv.URL); err != nil {
+			t.Fatalf("Expected fetch to succeed without a StatsD server, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIDoc is the subset of a Swagger 2.0 or OpenAPI 3 document
+// needed to turn its GET operations into URLs.
+type openAPIDoc struct {
+	Swagger  string   `json:"swagger"`
+	OpenAPI  string   `json:"openapi"`
+	Host     string   `json:"host"`     // 2.0
+	BasePath string   `json:"basePath"` // 2.0
+	Schemes  []string `json:"schemes"`  // 2.0
+	Servers  []struct {
+		URL string `json:"url"`
+	} `json:"servers"` // 3
+	Paths map[string]struct {
+		Parameters []openAPIParam `json:"parameters"`
+		Get        *struct {
+			Parameters []openAPIParam `json:"parameters"`
+		} `json:"get"`
+	} `json:"paths"`
+}
+
+// openAPIParam holds where a parameter goes and every place either
+// version may put a sample value for it.
+type openAPIParam struct {
+	Name     string                         `json:"name"`
+	In       string                         `json:"in"`
+	Type     string                         `json:"type"` // 2.0
+	Format   string                         `json:"format"`
+	Example  any                            `json:"example"`
+	Examples map[string]struct{ Value any } `json:"examples"`
+	Default  any                            `json:"default"`
+	Enum     []any                          `json:"enum"`
+	Schema   *openAPIParam                  `json:"schema"` // 3; only the value fields are used
+}
+
+var pathParam = regexp.MustCompile(`\{([^}/]+)\}`)
+
+// LoadOpenAPI reads a Swagger 2.0 or OpenAPI 3 document in JSON or YAML
+// and returns one URL per path with a GET operation, in path order, so an
+// API can be crawled from its spec. Path parameters take the first example,
+// default or enum value the spec gives and a random value of their type
+// otherwise. base overrides the spec's own server, and is required when
+// that is missing or relative.
+func LoadOpenAPI(path, base string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	urls, err := expandOpenAPI(data, base, rand.New(rand.NewSource(rand.Int63())))
+	if err != nil {
+		return nil, fmt.Errorf("OpenAPI spec %s: %w", path, err)
+	}
+	return urls, nil
+}
+
+func expandOpenAPI(data []byte, base string, rnd *rand.Rand) ([]string, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] != '{' {
+		var err error
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, fmt.Errorf("parse YAML: %w", err)
+		}
+	}
+	var doc openAPIDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+	if doc.Swagger == "" && doc.OpenAPI == "" {
+		return nil, errors.New(`neither "swagger" nor "openapi" version set`)
+	}
+	if base == "" {
+		base = doc.server()
+	}
+	if u, err := url.Parse(base); err != nil || !u.IsAbs() {
+		return nil, fmt.Errorf("no absolute base URL (got %q); pass one explicitly", base)
+	}
+	base = strings.TrimSuffix(base, "/")
+
+	var out []string
+	for _, p := range sortedNames(doc.Paths) {
+		item := doc.Paths[p]
+		if item.Get == nil {
+			continue
+		}
+		params := make(map[string]openAPIParam)
+		for _, list := range [][]openAPIParam{item.Parameters, item.Get.Parameters} {
+			for _, param := range list {
+				if param.In == "path" {
+					params[param.Name] = param // operation entries override path-level ones
+				}
+			}
+		}
+		concrete := pathParam.ReplaceAllStringFunc(p, func(m string) string {
+			return url.PathEscape(params[m[1:len(m)-1]].sample(rnd))
+		})
+		out = append(out, base+concrete)
+	}
+	return out, nil
+}
+
+// yamlToJSON re-encodes a YAML document as JSON, so that a spec in
+// either form is read through openAPIDoc's JSON tags. Mapping keys that
+// YAML reads as numbers or booleans, such as the 200 of a responses
+// object, become strings as they would be in JSON.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var v any
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonValue(v))
+}
+
+// jsonValue converts the maps with non-string keys yaml.v3 may produce
+// into the map[string]any that encoding/json accepts.
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = jsonValue(e)
+		}
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = jsonValue(e)
+		}
+		return m
+	case []any:
+		for i, e := range v {
+			v[i] = jsonValue(e)
+		}
+	}
+	return v
+}
+
+// server returns the base URL the document declares for itself.
+func (d *openAPIDoc) server() string {
+	if len(d.Servers) > 0 {
+		return d.Servers[0].URL
+	}
+	if d.Host == "" {
+		return ""
+	}
+	scheme := "https"
+	if len(d.Schemes) > 0 {
+		scheme = d.Schemes[0]
+	}
+	return scheme + "://" + d.Host + d.BasePath
+}
+
+// sample returns a value for p: its spec example, default or first enum
+// value when there is one, or a random value of its type.
+func (p openAPIParam) sample(rnd *rand.Rand) string {
+	for _, q := range []*openAPIParam{&p, p.Schema} {
+		if q == nil {
+			continue
+		}
+		if q.Example != nil {
+			return fmt.Sprint(q.Example)
+		}
+		for _, name := range sortedNames(q.Examples) {
+			if v := q.Examples[name].Value; v != nil {
+				return fmt.Sprint(v)
+			}
+		}
+		if q.Default != nil {
+			return fmt.Sprint(q.Default)
+		}
+		if len(q.Enum) > 0 {
+			return fmt.Sprint(q.Enum[0])
+		}
+	}
+
+	typ, format := p.Type, p.Format
+	if p.Schema != nil {
+		typ, format = p.Schema.Type, p.Schema.Format
+	}
+	switch {
+	case typ == "integer" || typ == "number":
+		return strconv.Itoa(1 + rnd.Intn(1000))
+	case typ == "boolean":
+		return strconv.FormatBool(rnd.Intn(2) == 0)
+	case format == "uuid":
+		b := make([]byte, 16)
+		rnd.Read(b)
+		b[6], b[8] = b[6]&0x0f|0x40, b[8]&0x3f|0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	}
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	b := make([]byte, 8)
+	for i := range b {
+		b[i] = letters[rnd.Intn(len(letters))]
+	}
+	return string(b)
+}
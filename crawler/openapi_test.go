@@ -0,0 +1,139 @@
+package crawler
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestLoadOpenAPI(t *testing.T) {
+	spec := `{
+		"openapi": "3.0.3",
+		"servers": [{"url": "https://api.example.com/v1"}],
+		"paths": {
+			"/users": {"get": {}, "post": {}},
+			"/users/{userId}": {
+				"parameters": [{"name": "userId", "in": "path", "schema": {"type": "integer", "example": 42}}],
+				"get": {}
+			},
+			"/users/{userId}/posts/{slug}": {
+				"parameters": [{"name": "userId", "in": "path", "schema": {"type": "integer"}}],
+				"get": {"parameters": [
+					{"name": "slug", "in": "path", "examples": {"hello": {"value": "hello world"}}},
+					{"name": "page", "in": "query", "schema": {"type": "integer"}}
+				]}
+			},
+			"/orders/{status}": {"get": {"parameters": [{"name": "status", "in": "path", "schema": {"type": "string", "enum": ["open", "closed"]}}]}},
+			"/sessions/{id}": {"get": {"parameters": [{"name": "id", "in": "path", "schema": {"type": "string", "format": "uuid"}}]}},
+			"/upload": {"post": {}}
+		}
+	}`
+	path := filepath.Join(t.TempDir(), "openapi.json")
+	if err := os.WriteFile(path, []byte(spec), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadOpenAPI(path, "")
+	if err != nil {
+		t.Fatalf("LoadOpenAPI: %v", err)
+	}
+	want := []*regexp.Regexp{
+		regexp.MustCompile(`^https://api\.example\.com/v1/orders/open$`),
+		regexp.MustCompile(`^https://api\.example\.com/v1/sessions/[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		regexp.MustCompile(`^https://api\.example\.com/v1/users$`),
+		regexp.MustCompile(`^https://api\.example\.com/v1/users/42$`),
+		regexp.MustCompile(`^https://api\.example\.com/v1/users/[0-9]+/posts/hello%20world$`),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d URLs, got %v", len(want), got)
+	}
+	for i, re := range want {
+		if !re.MatchString(got[i]) {
+			t.Errorf("Expected URL %d to match %s, got %s", i, re, got[i])
+		}
+	}
+}
+
+func TestExpandSwagger2(t *testing.T) {
+	spec := []byte(`{
+		"swagger": "2.0",
+		"host": "petstore.example.com",
+		"basePath": "/api",
+		"schemes": ["http"],
+		"paths": {
+			"/pets/{petId}": {"get": {"parameters": [{"name": "petId", "in": "path", "type": "integer", "default": 7}]}},
+			"/pets/{name}/photo": {"get": {"parameters": [{"name": "name", "in": "path", "type": "string"}]}}
+		}
+	}`)
+
+	got, err := expandOpenAPI(spec, "", rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("expandOpenAPI: %v", err)
+	}
+	if len(got) != 2 || got[1] != "http://petstore.example.com/api/pets/7" ||
+		!regexp.MustCompile(`^http://petstore\.example\.com/api/pets/[a-z]{8}/photo$`).MatchString(got[0]) {
+		t.Errorf("Expected the pets URLs on the spec's host, got %v", got)
+	}
+
+	got, err = expandOpenAPI(spec, "http://localhost:8080/", rand.New(rand.NewSource(1)))
+	if err != nil || !strings.HasPrefix(got[1], "http://localhost:8080/pets/7") {
+		t.Errorf("Expected the explicit base to replace the spec's server, got %v, %v", got, err)
+	}
+}
+
+func TestExpandOpenAPIYAML(t *testing.T) {
+	spec := `
+openapi: 3.0.3
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /users/{userId}:
+    parameters:
+      - name: userId
+        in: path
+        schema:
+          type: integer
+          example: 42
+    get:
+      responses:
+        200:
+          description: OK
+  /orders/{status}:
+    get:
+      parameters:
+        - {name: status, in: path, schema: {type: string, enum: [open, closed]}}
+  /upload:
+    post: {}
+`
+	got, err := expandOpenAPI([]byte(spec), "", rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("expandOpenAPI: %v", err)
+	}
+	want := []string{"https://api.example.com/v1/orders/open", "https://api.example.com/v1/users/42"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestExpandOpenAPIErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{"Bad YAML", "openapi: [3.0.0\n", "parse YAML"},
+		{"Not a spec", `{"paths": {}}`, "version"},
+		{"Relative server", `{"openapi": "3.1.0", "servers": [{"url": "/v1"}], "paths": {}}`, "base URL"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := expandOpenAPI([]byte(tc.spec), "", rand.New(rand.NewSource(1)))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Expected an error mentioning %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+)
+
+// pathDepth counts the non-empty segments of path, so "/", "/a" and
+// "/a/b/" are 0, 1 and 2 deep.
+func pathDepth(path string) int {
+	n := 0
+	for _, seg := range strings.Split(path, "/") {
+		if seg != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// rootPathDepths returns the shallowest path depth of the roots on each
+// host, for cfg.MaxPathDepth.
+func rootPathDepths(roots []string) map[string]int {
+	depths := make(map[string]int)
+	for _, root := range roots {
+		u, err := url.Parse(root)
+		if err != nil {
+			continue
+		}
+		d := pathDepth(u.Path)
+		if cur, ok := depths[u.Host]; !ok || d < cur {
+			depths[u.Host] = d
+		}
+	}
+	return depths
+}
+
+// pathTooDeep reports how many segments u's path lies below its host's
+// root, and whether that exceeds cfg.MaxPathDepth. Hosts without a root
+// are measured from "/".
+func (c *Crawler) pathTooDeep(u *url.URL) (int, bool) {
+	if c.cfg.MaxPathDepth <= 0 {
+		return 0, false
+	}
+	below := pathDepth(u.Path) - c.rootDepths[u.Host]
+	return below, below > c.cfg.MaxPathDepth
+}
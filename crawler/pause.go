@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// pauseGate holds back new fetches between Pause and Resume.
+type pauseGate struct {
+	mu      sync.Mutex
+	resumed chan struct{} // closed by Resume; nil while running
+}
+
+func (g *pauseGate) pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed == nil {
+		g.resumed = make(chan struct{})
+	}
+}
+
+func (g *pauseGate) resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed != nil {
+		close(g.resumed)
+		g.resumed = nil
+	}
+}
+
+// waiting returns the channel Resume closes, or nil when the gate is
+// open.
+func (g *pauseGate) waiting() <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resumed
+}
+
+// Pause stops the crawler from starting new fetches until Resume is
+// called. Requests already in flight complete, and the queue and visited
+// set are kept, so the crawl carries on where it stopped. cfg.Timeout
+// and cfg.StopAt keep running while paused and end the crawl as usual.
+// It is safe to call from any goroutine.
+func (c *Crawler) Pause() {
+	c.gate.pause()
+}
+
+// Resume lets a paused crawler fetch again. It does nothing when the
+// crawler is not paused.
+func (c *Crawler) Resume() {
+	c.gate.resume()
+}
+
+// waitResumed blocks a fetch while the crawler is paused. It gives up
+// with ctx's error when ctx ends, or with the time limit's once
+// cfg.Timeout or cfg.StopAt is reached. Time spent paused does not count
+// toward cfg.IdleTimeout.
+func (c *Crawler) waitResumed(ctx context.Context) error {
+	resumed := c.gate.waiting()
+	if resumed == nil {
+		return nil
+	}
+	defer c.markSuccess()
+
+	var limit <-chan time.Time
+	if c.cfg.Timeout != 0 || !c.stopAt.IsZero() {
+		t := time.NewTimer(c.untilTimeout(math.MaxInt64))
+		defer t.Stop()
+		limit = t.C
+	}
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-limit:
+		return c.timeLimitError()
+	}
+}
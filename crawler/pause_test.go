@@ -0,0 +1,85 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPauseResume(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		fmt.Fprintf(w, `<a href="/page%d">next</a>`, n)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL + "/")
+	cfg.MaxDepth = 1 << 20
+	cfg.MinSleep, cfg.MaxSleep = 1000, 1000 // 1ms between pages
+	c := newTestCrawler(t, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.Crawl(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitHits := func(n int32) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for atomic.LoadInt32(&hits) < n {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected at least %d requests, got %d", n, atomic.LoadInt32(&hits))
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitHits(3)
+
+	c.Pause()
+	time.Sleep(20 * time.Millisecond) // let an in-flight request finish
+	paused := atomic.LoadInt32(&hits)
+	time.Sleep(100 * time.Millisecond)
+	if got := atomic.LoadInt32(&hits); got != paused {
+		t.Errorf("Expected no requests while paused, got %d more", got-paused)
+	}
+
+	c.Resume()
+	waitHits(paused + 3)
+	if visited := c.visitedCount(); visited < int(paused) {
+		t.Errorf("Expected the visited set to survive the pause, got %d URLs for %d requests", visited, paused)
+	}
+}
+
+func TestPauseRespectsContext(t *testing.T) {
+	c := newTestCrawler(t, testConfig("http://example.com/"))
+	c.Pause()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := c.fetch(ctx, "http://example.com/"); err == nil {
+		t.Error("Expected a paused fetch to end with ctx")
+	}
+}
+
+func TestPauseEndsAtTimeout(t *testing.T) {
+	srv := linkSite(t, map[string][]string{"/": {"/a"}}, new(int32))
+	cfg := testConfig(srv.URL + "/")
+	cfg.Timeout = 1
+	c := newTestCrawler(t, cfg)
+	c.Pause()
+
+	start := time.Now()
+	if err := c.Crawl(context.Background()); !errors.Is(err, ErrTimeLimit) {
+		t.Fatalf("Expected ErrTimeLimit, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the paused crawl to stop at the 1s timeout, Crawl took %v", elapsed)
+	}
+}
@@ -0,0 +1,220 @@
+package crawler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// workerBacklog is how many pending links justify one more worker.
+const workerBacklog = 4
+
+// jobSource feeds a workerPool. next takes the next link, reporting
+// false when there is none; backlog counts the links waiting.
+type jobSource interface {
+	next() (string, bool)
+	backlog() int
+}
+
+// workerPool runs one job per link from its source on between one and
+// max goroutines, sized to the backlog: a worker per workerBacklog
+// pending links. grow starts workers as links arrive, and workers exit
+// as the backlog drains, so a surge of links is worked through in
+// parallel without keeping idle goroutines around.
+type workerPool struct {
+	max int
+	src jobSource
+	run func(link string)
+
+	mu     sync.Mutex
+	active int // workers running
+	peak   int // most workers seen running at once
+	wg     sync.WaitGroup
+}
+
+func newWorkerPool(limit int, src jobSource, run func(link string)) *workerPool {
+	return &workerPool{max: max(limit, 1), src: src, run: run}
+}
+
+// wanted returns the worker count for n pending links.
+func (p *workerPool) wanted(n int) int {
+	return min(p.max, max(1, (n+workerBacklog-1)/workerBacklog))
+}
+
+// grow starts workers while the backlog calls for more. Workers call it
+// after every job, since a job may add links.
+func (p *workerPool) grow() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := p.src.backlog()
+	if n == 0 {
+		return
+	}
+	for p.active < p.wanted(n) {
+		p.active++
+		p.peak = max(p.peak, p.active)
+		p.wg.Add(1)
+		go p.work()
+	}
+}
+
+// work runs links until there are none left, or fewer than the running
+// workers warrant, in which case this worker exits.
+func (p *workerPool) work() {
+	defer p.wg.Done()
+	for {
+		p.mu.Lock()
+		if n := p.src.backlog(); n == 0 || p.active > p.wanted(n) {
+			p.active--
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		link, ok := p.src.next()
+		if !ok {
+			continue // another worker took it; look at the backlog again
+		}
+		p.run(link)
+		p.grow()
+	}
+}
+
+// wait blocks until the source is drained and all workers exited.
+func (p *workerPool) wait() {
+	p.wg.Wait()
+}
+
+// workers returns how many workers are running.
+func (p *workerPool) workers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.active
+}
+
+// linkList is a jobSource handing out a list of links in order.
+type linkList struct {
+	mu    sync.Mutex
+	links []string
+}
+
+func (l *linkList) add(links ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.links = append(l.links, links...)
+}
+
+func (l *linkList) next() (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.links) == 0 {
+		return "", false
+	}
+	link := l.links[0]
+	l.links = l.links[1:]
+	return link, true
+}
+
+func (l *linkList) backlog() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.links)
+}
+
+// queueSource is a jobSource over the crawler's memory queue for
+// parallelBranch. Like nextTarget it marks the links it hands out
+// visited, skipping those already visited, and it leaves links beyond
+// cfg.MaxDepth unvisited. Once the branch should stop it reports an
+// empty backlog, so the workers retire.
+type queueSource struct {
+	ctx context.Context
+	c   *Crawler
+}
+
+func (q queueSource) next() (string, bool) {
+	for {
+		target, ok, err := q.c.queue.Pop(q.ctx)
+		if err != nil || !ok {
+			return "", false
+		}
+		if q.c.hopsOf(target) > q.c.cfg.MaxDepth {
+			continue
+		}
+		if q.c.markVisited(q.ctx, target) {
+			return target, true
+		}
+	}
+}
+
+func (q queueSource) backlog() int {
+	if q.c.stopped(q.ctx) || q.c.branchExpired() {
+		return 0
+	}
+	q.c.linksMu.Lock()
+	defer q.c.linksMu.Unlock()
+	return len(q.c.links)
+}
+
+type hopsKey struct{}
+
+// withHops returns a context under which the links a page yields are
+// recorded in c.hops as one hop further than the page at depth.
+func withHops(ctx context.Context, depth int) context.Context {
+	return context.WithValue(ctx, hopsKey{}, depth)
+}
+
+// recordHops notes the depth of newly queued links for withHops, if ctx
+// asks for it. c.linksMu must be held.
+func (c *Crawler) recordHops(ctx context.Context, links []string) {
+	depth, ok := ctx.Value(hopsKey{}).(int)
+	if !ok || c.hops == nil {
+		return
+	}
+	for _, link := range links {
+		if _, seen := c.hops[link]; !seen {
+			c.hops[link] = depth + 1
+		}
+	}
+}
+
+// hopsOf returns the depth at which a queued link is visited by
+// parallelBranch: 1 for the root's links.
+func (c *Crawler) hopsOf(link string) int {
+	c.linksMu.Lock()
+	defer c.linksMu.Unlock()
+	if depth, ok := c.hops[link]; ok {
+		return depth
+	}
+	return 1
+}
+
+// parallelBranch is depthFirst for cfg.MaxWorkers above 1: rather than
+// one branch following one link per page, a workerPool visits links
+// from the queue as they are found, growing with the queue. A link is
+// visited at one hop more than the page that yielded it, the root's
+// links at depth 1, and not at all beyond cfg.MaxDepth. The walk stops
+// when the queue drains, a stop condition fires or
+// cfg.MaxBranchDuration is spent.
+func (c *Crawler) parallelBranch(ctx context.Context) {
+	visit := func(target string) {
+		depth := c.hopsOf(target)
+		if _, err := c.visit(withHops(ctx, depth), target, depth); err != nil {
+			log.Printf("visit %s: %v", target, err)
+			return
+		}
+
+		time.Sleep(c.thinkTime(hostOf(target)))
+	}
+	pool := newWorkerPool(c.cfg.MaxWorkers, queueSource{ctx, c}, visit)
+	pool.grow()
+	pool.wait()
+	c.debugf("branch done: up to %d workers", pool.peak)
+}
+
+// branchExpired reports whether cfg.MaxBranchDuration has passed since
+// the branch's root was picked.
+func (c *Crawler) branchExpired() bool {
+	d := c.cfg.MaxBranchDuration
+	return d > 0 && c.clock.Now().Sub(c.branchStart) >= time.Duration(d)*time.Millisecond
+}
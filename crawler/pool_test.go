@@ -0,0 +1,104 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolScalesWithBacklog(t *testing.T) {
+	release := make(chan struct{})
+	queue := &linkList{}
+	p := newWorkerPool(8, queue, func(string) { <-release })
+	add := func(n int) {
+		queue.add(make([]string, n)...)
+		p.grow()
+	}
+
+	add(2)
+	if n := p.workers(); n != 1 {
+		t.Errorf("Expected 1 worker for 2 links, got %d", n)
+	}
+	add(38) // surge
+	if n := p.workers(); n != 8 {
+		t.Errorf("Expected the surge to grow the pool to max_workers 8, got %d", n)
+	}
+
+	// Finish all but the last 4 links: with nothing left pending, every
+	// worker not still running one exits.
+	for i := 0; i < 36; i++ {
+		release <- struct{}{}
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for p.workers() > 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the pool to shrink as the backlog drained, still %d workers", p.workers())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < 4; i++ {
+		release <- struct{}{}
+	}
+	p.wait()
+	if n := p.workers(); n != 0 || p.peak != 8 {
+		t.Errorf("Expected 0 workers left after a peak of 8, got %d after %d", n, p.peak)
+	}
+}
+
+func TestParallelBranchScalesWithQueue(t *testing.T) {
+	pages := map[string][]string{"/": nil}
+	for i := 0; i < 12; i++ {
+		page := fmt.Sprintf("/p%d", i)
+		pages["/"] = append(pages["/"], page)
+		pages[page] = []string{page + "/child"}
+		pages[page+"/child"] = []string{page + "/child/deep"}
+	}
+	var (
+		mu                sync.Mutex
+		inFlight, busiest int
+		hits              = make(map[string]int)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		inFlight++
+		busiest = max(busiest, inFlight)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		for _, link := range pages[r.URL.Path] {
+			fmt.Fprintf(w, `<a href="%s">link</a>`, link)
+		}
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL + "/")
+	cfg.MaxDepth = 2
+	cfg.MaxWorkers = 4
+	c := newTestCrawler(t, cfg)
+	c.crawlRoot(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(hits) != 25 {
+		t.Errorf("Expected the root, 12 pages and their 12 children within max_depth 2, got %d paths", len(hits))
+	}
+	for path, n := range hits {
+		if n != 1 {
+			t.Errorf("Expected %s to be fetched once, got %d", path, n)
+		}
+		if strings.HasSuffix(path, "/deep") {
+			t.Errorf("Expected %s, 3 hops from the root, not to be fetched", path)
+		}
+	}
+	if busiest < 2 || busiest > 4 {
+		t.Errorf("Expected between 2 and max_workers 4 fetches at once, got %d", busiest)
+	}
+}
@@ -0,0 +1,86 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrNoLiveRoots is returned by Crawl when cfg.ProbeRootsOnStartup
+// finds every root dead and cfg.ProbeRootsFailFast is set.
+var ErrNoLiveRoots = errors.New("no root URL is reachable")
+
+// probeRoots sends one HEAD request to each root, retrying with GET when
+// HEAD is not allowed, and drops the roots that fail or answer with an
+// error status. When every root is dead they are all kept, with a
+// warning, unless cfg.ProbeRootsFailFast makes that ErrNoLiveRoots.
+// Probes are not counted in Stats. If ctx ends mid-probe the roots are
+// left as they were.
+func (c *Crawler) probeRoots(ctx context.Context) error {
+	var live, dead []string
+	for _, root := range c.roots {
+		err := c.probe(ctx, root)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			log.Printf("probe root %s: %v; dropping it", root, err)
+			dead = append(dead, root)
+			continue
+		}
+		live = append(live, root)
+	}
+	switch {
+	case len(live) > 0:
+		c.roots = live
+		if len(dead) > 0 {
+			log.Printf("probe roots: %d of %d reachable", len(live), len(live)+len(dead))
+		}
+	case c.cfg.ProbeRootsFailFast:
+		return fmt.Errorf("%w: %s", ErrNoLiveRoots, strings.Join(dead, ", "))
+	default:
+		log.Printf("warning: probe roots: none of %d roots is reachable; crawling them anyway", len(dead))
+	}
+	return nil
+}
+
+// probe checks that target answers without an error status. Roots of
+// schemes other than http and https are fetched through their
+// registered Fetcher instead, which has no HEAD.
+func (c *Crawler) probe(ctx context.Context, target string) error {
+	target = c.rewrite(target)
+	if u, err := url.Parse(target); err == nil && u.Scheme != "http" && u.Scheme != "https" {
+		_, _, err := c.dispatch(ctx, target)
+		return err
+	}
+	status, err := c.probeOnce(ctx, http.MethodHead, target)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = c.probeOnce(ctx, http.MethodGet, target)
+	}
+	if err != nil {
+		return err
+	}
+	if status >= http.StatusBadRequest {
+		return fmt.Errorf("status %d %s", status, http.StatusText(status))
+	}
+	return nil
+}
+
+func (c *Crawler) probeOnce(ctx context.Context, method, target string) (int, error) {
+	req, err := c.sideRequest(ctx, method, target)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyBytes))
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
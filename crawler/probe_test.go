@@ -0,0 +1,108 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// deadURL returns an http URL nothing listens on.
+func deadURL(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return "http://" + addr + "/"
+}
+
+func TestProbeRootsDropsDeadOnes(t *testing.T) {
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/gone":
+			http.NotFound(w, r)
+		case "/get-only":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		}
+	}))
+	defer srv.Close()
+
+	dead := deadURL(t)
+	cfg := testConfig(srv.URL+"/", dead, srv.URL+"/gone", srv.URL+"/get-only")
+	cfg.ProbeRootsOnStartup = true
+	c := newTestCrawler(t, cfg)
+	if err := c.probeRoots(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(c.roots, " "); got != srv.URL+"/ "+srv.URL+"/get-only" {
+		t.Errorf("Expected only the live roots to remain, got %s", got)
+	}
+	if got := strings.Join(methods, ", "); got != "HEAD /, HEAD /gone, HEAD /get-only, GET /get-only" {
+		t.Errorf("Expected one HEAD per root and a GET where HEAD is refused, got %s", got)
+	}
+	if len(cfg.RootURLs) != 4 {
+		t.Errorf("Expected the config to be left alone, got %v", cfg.RootURLs)
+	}
+	if c.Snapshot().Requests != 0 {
+		t.Errorf("Expected probes to stay out of the stats, got %d requests", c.Snapshot().Requests)
+	}
+}
+
+func TestProbeRootsAllDead(t *testing.T) {
+	cfg := testConfig(deadURL(t), deadURL(t))
+	cfg.ProbeRootsOnStartup = true
+	c := newTestCrawler(t, cfg)
+	if err := c.probeRoots(context.Background()); err != nil || len(c.roots) != 2 {
+		t.Errorf("Expected every root to be kept without fail-fast, got %v, %v", c.roots, err)
+	}
+
+	cfg.ProbeRootsFailFast = true
+	if err := newTestCrawler(t, cfg).Crawl(context.Background()); !errors.Is(err, ErrNoLiveRoots) {
+		t.Errorf("Expected ErrNoLiveRoots, got %v", err)
+	}
+}
+
+func TestProbeRootsRespectsContext(t *testing.T) {
+	cfg := testConfig(deadURL(t))
+	c := newTestCrawler(t, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.probeRoots(ctx); err != nil || len(c.roots) != 1 {
+		t.Errorf("Expected a cancelled probe to leave the roots alone, got %v, %v", c.roots, err)
+	}
+}
+
+func TestProbeRootsFileURL(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.html")
+	if err := os.WriteFile(path, []byte(`<a href="a.html">a</a>`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	root := (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+	missing := (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dir, "missing.html"))}).String()
+
+	cfg := testConfig(root, missing)
+	cfg.AllowFileURLs = true
+	cfg.ProbeRootsOnStartup = true
+	c := newTestCrawler(t, cfg)
+	if err := c.probeRoots(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.roots) != 1 || c.roots[0] != root {
+		t.Errorf("Expected only the existing file root to be kept, got %v", c.roots)
+	}
+}
@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/calpa/urusai/config"
+)
+
+// LinkQueue holds the links waiting to be visited. A shared backend lets
+// a fleet of crawlers work through one queue, each visiting links the
+// others found.
+type LinkQueue interface {
+	// Push queues links.
+	Push(ctx context.Context, links ...string) error
+	// Pop removes a random queued link, reporting false when the queue
+	// is empty.
+	Pop(ctx context.Context) (string, bool, error)
+}
+
+// localQueue is the default LinkQueue: the crawler's own in-memory
+// slice, honouring cfg.MaxQueueSize, cfg.MaxLinkAge,
+// cfg.DeterministicOrder and cfg.PreferNewHosts. It holds c.linksMu, as
+// cfg.MaxWorkers workers push links concurrently.
+type localQueue struct{ c *Crawler }
+
+func (q localQueue) Push(ctx context.Context, links ...string) error {
+	q.c.linksMu.Lock()
+	defer q.c.linksMu.Unlock()
+	q.c.recordHops(ctx, links)
+	q.c.enqueue(links)
+	return nil
+}
+
+func (q localQueue) Pop(_ context.Context) (string, bool, error) {
+	c := q.c
+	c.linksMu.Lock()
+	defer c.linksMu.Unlock()
+	for len(c.links) > 0 {
+		idx := c.pickLink()
+		link := c.links[idx]
+		c.links = append(c.links[:idx], c.links[idx+1:]...)
+		if !c.stale(link) {
+			return link, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// newLinkQueue returns the LinkQueue selected by cfg.QueueBackend.
+func newLinkQueue(c *Crawler, cfg *config.Config) (LinkQueue, error) {
+	switch cfg.QueueBackend {
+	case "", "memory":
+		return localQueue{c}, nil
+	case "redis":
+		if cfg.Redis == nil || cfg.Redis.Addr == "" {
+			return nil, fmt.Errorf("queue_backend redis: redis.addr is required")
+		}
+		return newRedisQueue(cfg.Redis), nil
+	default:
+		return nil, fmt.Errorf("unknown queue_backend %q", cfg.QueueBackend)
+	}
+}
+
+// sharedQueue reports whether links are queued outside this crawler, so
+// an empty local slice says nothing about the work left.
+func (c *Crawler) sharedQueue() bool {
+	_, local := c.queue.(localQueue)
+	return !local
+}
+
+// push queues links. A failing backend is logged rather than failing the
+// page that found them.
+func (c *Crawler) push(ctx context.Context, links ...string) {
+	if len(links) == 0 {
+		return
+	}
+	if err := c.queue.Push(ctx, links...); err != nil {
+		log.Printf("link queue: %v", err)
+	}
+}
+
+// isStale is stale for callers not holding c.linksMu; it takes the lock
+// itself.
+func (c *Crawler) isStale(link string) bool {
+	c.linksMu.Lock()
+	defer c.linksMu.Unlock()
+	return c.stale(link)
+}
+
+// stale reports whether link has waited in the local queue longer than
+// cfg.MaxLinkAge, counting it as dropped if so. Either way the link
+// leaves the queue. c.linksMu must be held.
+func (c *Crawler) stale(link string) bool {
+	queued, ok := c.queuedAt[link]
+	if !ok {
+		return false
+	}
+	delete(c.queuedAt, link)
+	age := c.clock.Now().Sub(queued)
+	if age <= time.Duration(c.cfg.MaxLinkAge)*time.Millisecond {
+		return false
+	}
+	c.debugf("drop %s: queued %v ago, max_link_age %dms", shorten(link, maxLoggedURL), age, c.cfg.MaxLinkAge)
+	c.count(func(s *Stats) { s.StaleLinks++ })
+	return true
+}
@@ -0,0 +1,69 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/calpa/urusai/config"
+)
+
+func TestRedisQueueSharedBetweenCrawlers(t *testing.T) {
+	var hits int32
+	site := linkSite(t, map[string][]string{"/": {"/a", "/b"}}, &hits)
+	redis := newFakeRedis(t, "", false)
+	newCrawler := func() *Crawler {
+		cfg := testConfig(site.URL + "/")
+		cfg.QueueBackend = "redis"
+		cfg.VisitedBackend = "redis"
+		cfg.Redis = &config.Redis{Addr: redis.addr()}
+		return newTestCrawler(t, cfg)
+	}
+	a, b := newCrawler(), newCrawler()
+	ctx := context.Background()
+
+	if _, err := a.visit(ctx, site.URL+"/", 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.links) != 0 {
+		t.Errorf("Expected no links in the local queue, got %v", a.links)
+	}
+	first, ok := b.nextTarget(ctx)
+	if !ok {
+		t.Fatal("Expected the second crawler to pop a link the first one found")
+	}
+	second, ok := a.nextTarget(ctx)
+	if !ok || second == first {
+		t.Fatalf("Expected the first crawler to pop the other link, got %q, %v", second, ok)
+	}
+	if got, ok := b.nextTarget(ctx); ok {
+		t.Errorf("Expected the shared queue to be drained, got %q", got)
+	}
+
+	// A link queued again after being claimed is not handed out twice.
+	a.push(ctx, first)
+	if got, ok := b.nextTarget(ctx); ok {
+		t.Errorf("Expected a visited link to be skipped, got %q", got)
+	}
+	if !redis.hasKey(defaultRedisQueueKey) {
+		t.Errorf("Expected the default queue key %q to be used", defaultRedisQueueKey)
+	}
+}
+
+func TestRedisQueueRespectsContext(t *testing.T) {
+	srv := newFakeRedis(t, "", true)
+	q := newRedisQueue(&config.Redis{Addr: srv.addr(), Timeout: 10000})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, _, err := q.Pop(ctx); err == nil {
+		t.Fatal("Expected an error from a server that never answers")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the call to end with ctx, took %v", elapsed)
+	}
+	if err := q.Push(ctx, "http://example.com/"); err == nil {
+		t.Error("Expected Push to fail once ctx has ended")
+	}
+}
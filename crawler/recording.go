@@ -0,0 +1,181 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Transport returns the RoundTripper the crawler sends requests with.
+func (c *Crawler) Transport() http.RoundTripper {
+	return c.client.Transport
+}
+
+// SetTransport replaces the RoundTripper the crawler sends requests
+// with, for example to wrap the default one in a Recorder or to serve a
+// recording with a Replayer.
+func (c *Crawler) SetTransport(rt http.RoundTripper) {
+	c.client.Transport = rt
+}
+
+// exchange is one recorded request and its outcome, stored as one JSON
+// line. Error is set instead of a response when the request failed, and
+// Truncated when the body went on past the recorded 1 MiB.
+type exchange struct {
+	Method    string      `json:"method"`
+	URL       string      `json:"url"`
+	Status    int         `json:"status,omitempty"`
+	Header    http.Header `json:"header,omitempty"`
+	Body      []byte      `json:"body,omitempty"`
+	Truncated bool        `json:"truncated,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+func (e exchange) key() string { return e.Method + " " + e.URL }
+
+// recordedURL is u as a recording stores it: without the parameter
+// cache_bust appends, whose random value would never match on replay.
+func recordedURL(u *url.URL) string {
+	q := u.RawQuery
+	i := strings.LastIndex(q, cacheBustParam+"=")
+	if i < 0 || (i > 0 && q[i-1] != '&') || strings.Contains(q[i:], "&") {
+		return u.String()
+	}
+	stripped := *u
+	stripped.RawQuery = strings.TrimSuffix(q[:i], "&")
+	return stripped.String()
+}
+
+// Recorder is a RoundTripper that forwards every request to Next and
+// writes the exchange to W as a JSON line, for later replay by a
+// Replayer. Bodies are recorded up to the 1 MiB the crawler reads, and
+// URLs without the cache_bust parameter.
+type Recorder struct {
+	Next http.RoundTripper
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewRecorder returns a Recorder sending requests through next and
+// writing exchanges to w.
+func NewRecorder(next http.RoundTripper, w io.Writer) *Recorder {
+	return &Recorder{Next: next, enc: json.NewEncoder(w)}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	ex := exchange{Method: req.Method, URL: recordedURL(req.URL)}
+	resp, err := r.Next.RoundTrip(req)
+	if err != nil {
+		ex.Error = err.Error()
+		r.write(ex)
+		return nil, err
+	}
+	body, readErr := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes+1))
+	if readErr != nil {
+		resp.Body.Close()
+		ex.Error = readErr.Error()
+		r.write(ex)
+		return nil, readErr
+	}
+	ex.Status, ex.Header, ex.Body = resp.StatusCode, resp.Header, body
+	if len(body) > maxBodyBytes {
+		ex.Body, ex.Truncated = body[:maxBodyBytes], true
+	}
+	r.write(ex)
+
+	// The caller reads the response as it came, rest and Content-Length
+	// included, so it notices truncation just as without a Recorder.
+	resp.Body = readCloser{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+	return resp, nil
+}
+
+func (r *Recorder) write(ex exchange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(ex); err != nil {
+		log.Printf("record %s: %v", ex.key(), err)
+	}
+}
+
+// ErrNotRecorded is returned by a Replayer for a request missing from
+// its recording.
+var ErrNotRecorded = errors.New("request not in recording")
+
+// Replayer is a RoundTripper that answers requests from a recording made
+// by a Recorder, without touching the network. Repeated requests for
+// one URL get the recorded answers in order, the last one repeating.
+type Replayer struct {
+	mu      sync.Mutex
+	answers map[string][]exchange
+}
+
+// NewReplayer reads a recording written by a Recorder.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	rep := &Replayer{answers: make(map[string][]exchange)}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 4*maxBodyBytes) // base64 bodies plus headers
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var ex exchange
+		if err := json.Unmarshal(sc.Bytes(), &ex); err != nil {
+			return nil, fmt.Errorf("recording line %d: %w", line, err)
+		}
+		rep.answers[ex.key()] = append(rep.answers[ex.key()], ex)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	key := req.Method + " " + recordedURL(req.URL)
+	r.mu.Lock()
+	queue := r.answers[key]
+	if len(queue) == 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrNotRecorded, key)
+	}
+	ex := queue[0]
+	if len(queue) > 1 {
+		r.answers[key] = queue[1:]
+	}
+	r.mu.Unlock()
+
+	if ex.Error != "" {
+		return nil, errors.New(ex.Error)
+	}
+	body, length := io.Reader(bytes.NewReader(ex.Body)), int64(len(ex.Body))
+	if ex.Truncated {
+		// One byte past the recorded body tells the reader, as live, that
+		// the body went on; its real length is unknown.
+		body, length = io.MultiReader(body, strings.NewReader("\x00")), -1
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", ex.Status, http.StatusText(ex.Status)),
+		StatusCode:    ex.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        ex.Header.Clone(),
+		Body:          io.NopCloser(body),
+		ContentLength: length,
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,150 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// recordedURLs lists the URLs of a recording in order.
+func recordedURLs(t *testing.T, rec []byte) []string {
+	t.Helper()
+	var urls []string
+	dec := json.NewDecoder(bytes.NewReader(rec))
+	for dec.More() {
+		var ex exchange
+		if err := dec.Decode(&ex); err != nil {
+			t.Fatal(err)
+		}
+		urls = append(urls, ex.URL)
+	}
+	return urls
+}
+
+func TestRecordThenReplayCrawl(t *testing.T) {
+	pages := map[string][]string{}
+	for i := 0; i < 6; i++ {
+		for j := 0; j < 4; j++ {
+			pages[fmt.Sprintf("/p%d", i)] = append(pages[fmt.Sprintf("/p%d", i)], fmt.Sprintf("/p%d", (i+j+1)%6))
+		}
+	}
+	pages["/"] = []string{"/p0", "/p3", "/missing"}
+
+	for _, cacheBust := range []float64{0, 1} {
+		t.Run(fmt.Sprintf("cache_bust=%g", cacheBust), func(t *testing.T) {
+			srv := linkSite(t, pages, new(int32))
+
+			crawl := func(wrap func(c *Crawler)) (*Crawler, []byte) {
+				cfg := testConfig(srv.URL + "/")
+				cfg.MaxDepth = 4
+				cfg.Seed = 7
+				cfg.CacheBust = cacheBust
+				c := newTestCrawler(t, cfg)
+				wrap(c)
+				var rec bytes.Buffer
+				c.SetTransport(NewRecorder(c.Transport(), &rec))
+				for i := 0; i < 3; i++ {
+					c.crawlRoot(context.Background())
+				}
+				return c, rec.Bytes()
+			}
+
+			live, recording := crawl(func(*Crawler) {})
+			srv.Close() // the replay must not need the network
+
+			replayer, err := NewReplayer(bytes.NewReader(recording))
+			if err != nil {
+				t.Fatal(err)
+			}
+			replayed, replayRecording := crawl(func(c *Crawler) { c.SetTransport(replayer) })
+
+			want, got := recordedURLs(t, recording), recordedURLs(t, replayRecording)
+			if len(want) < 4 {
+				t.Fatalf("Expected a few requests to be recorded, got %v", want)
+			}
+			if strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("Expected the replay to repeat the requests\n%v\ngot\n%v", want, got)
+			}
+			for _, u := range want {
+				if strings.Contains(u, cacheBustParam+"=") {
+					t.Errorf("Expected the cache_bust parameter to be left out of the recording, got %s", u)
+				}
+			}
+			if live.Snapshot() != replayed.Snapshot() {
+				t.Errorf("Expected identical stats, got %+v and %+v", live.Snapshot(), replayed.Snapshot())
+			}
+		})
+	}
+}
+
+func TestReplayerIgnoresCacheBustToken(t *testing.T) {
+	rep, err := NewReplayer(strings.NewReader(`{"method":"GET","url":"http://example.com/a?x=1","status":200}` + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, raw := range []string{"http://example.com/a?x=1", "http://example.com/a?x=1&_=k3j9a"} {
+		req, _ := http.NewRequest(http.MethodGet, raw, nil)
+		if resp, err := rep.RoundTrip(req); err != nil || resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected the recorded 200 for %s, got %v, %v", raw, resp, err)
+		}
+	}
+}
+
+func TestRecordingKeepsTruncation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(maxBodyBytes+10))
+		w.Write(bytes.Repeat([]byte("a"), maxBodyBytes+10))
+	}))
+	defer srv.Close()
+
+	var rec bytes.Buffer
+	recorder := NewRecorder(http.DefaultTransport, &rec)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err := recorder.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ContentLength != maxBodyBytes+10 {
+		t.Errorf("Expected the live Content-Length to be kept, got %d", resp.ContentLength)
+	}
+	body, truncated, _ := readBody(context.Background(), resp.Body, maxBodyBytes)
+	if !truncated || len(body) != maxBodyBytes {
+		t.Errorf("Expected the live body to read as truncated at %d bytes, got %d bytes, truncated %v", maxBodyBytes, len(body), truncated)
+	}
+
+	replayer, err := NewReplayer(&rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = replayer.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, truncated, _ = readBody(context.Background(), resp.Body, maxBodyBytes)
+	if !truncated || len(body) != maxBodyBytes {
+		t.Errorf("Expected the replayed body to read as truncated at %d bytes, got %d bytes, truncated %v", maxBodyBytes, len(body), truncated)
+	}
+}
+
+func TestReplayerUnknownRequest(t *testing.T) {
+	rep, err := NewReplayer(strings.NewReader(`{"method":"GET","url":"http://example.com/","status":200}` + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/other", nil)
+	if _, err := rep.RoundTrip(req); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("Expected ErrNotRecorded, got %v", err)
+	}
+	req, _ = http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	resp, err := rep.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the recorded 200, got %v, %v", resp, err)
+	}
+}
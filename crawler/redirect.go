@@ -0,0 +1,33 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// maxRedirects matches net/http's default redirect limit.
+const maxRedirects = 10
+
+// checkRedirect is the client's CheckRedirect policy. It fails as soon as
+// a chain revisits a URL instead of waiting for the redirect limit, and
+// refuses to follow over-long Location headers or redirects into the
+// blacklist. Host overrides are re-applied for the redirect target.
+func (c *Crawler) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("%w: stopped after %d redirects", ErrRedirectLoop, maxRedirects)
+	}
+	target := req.URL.String()
+	if limit := c.maxURLLength(); len(target) > limit {
+		return fmt.Errorf("%w: redirect target is %d bytes, limit %d", ErrURLTooLong, len(target), limit)
+	}
+	for _, prev := range via {
+		if prev.URL.String() == target {
+			return fmt.Errorf("%w: %s redirects back to itself", ErrRedirectLoop, target)
+		}
+	}
+	if blk := c.blacklistedBy(target); blk != "" {
+		return fmt.Errorf("%w: redirect to %s matches %q", ErrBlacklisted, target, blk)
+	}
+	c.redirectHostOverrides(req, via)
+	return nil
+}
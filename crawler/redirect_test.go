@@ -0,0 +1,79 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRedirectLoopMarksHost(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		switch r.URL.Path {
+		case "/self":
+			http.Redirect(w, r, "/self", http.StatusFound)
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/a", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	for _, path := range []string{"/self", "/a"} {
+		t.Run(path, func(t *testing.T) {
+			hits = 0
+			c := newTestCrawler(t, testConfig(srv.URL))
+
+			_, _, err := c.fetch(context.Background(), srv.URL+path)
+			if !errors.Is(err, ErrRedirectLoop) {
+				t.Fatalf("Expected redirect loop error, got %v", err)
+			}
+			if hits > 3 {
+				t.Errorf("Expected the loop to be detected early, server saw %d requests", hits)
+			}
+			if c.stats.RedirectLoops != 1 {
+				t.Errorf("Expected RedirectLoops = 1, got %d", c.stats.RedirectLoops)
+			}
+			if c.accept(srv.URL + "/other") {
+				t.Error("Expected links to the looping host to be rejected")
+			}
+		})
+	}
+}
+
+func TestCheckRedirectLimit(t *testing.T) {
+	c := newTestCrawler(t, testConfig())
+	var via []*http.Request
+	for i := 0; i < maxRedirects; i++ {
+		u, _ := url.Parse("http://example.com/" + string(rune('a'+i)))
+		via = append(via, &http.Request{URL: u})
+	}
+	next, _ := url.Parse("http://example.com/next")
+	if err := c.checkRedirect(&http.Request{URL: next}, via); !errors.Is(err, ErrRedirectLoop) {
+		t.Errorf("Expected redirect limit to report a loop, got %v", err)
+	}
+	if err := c.checkRedirect(&http.Request{URL: next}, via[:3]); err != nil {
+		t.Errorf("Expected short distinct chain to be allowed, got %v", err)
+	}
+}
+
+func TestRedirectToOverlongURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/huge" {
+			http.Redirect(w, r, "/landing?q="+strings.Repeat("x", 4096), http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := newTestCrawler(t, testConfig(srv.URL))
+	if _, _, err := c.fetch(context.Background(), srv.URL+"/huge"); !errors.Is(err, ErrURLTooLong) {
+		t.Errorf("Expected ErrURLTooLong, got %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calpa/urusai/config"
+)
+
+const (
+	defaultRedisKey      = "urusai:visited"
+	defaultRedisQueueKey = "urusai:queue"
+	defaultRedisTimeout  = time.Second
+)
+
+// redisClient speaks just enough RESP for the shared visited set and
+// link queue over a single lazily dialled connection, which is
+// redialled after any failure.
+type redisClient struct {
+	opts    config.Redis
+	timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+func newRedisClient(opts *config.Redis) *redisClient {
+	return &redisClient{opts: *opts, timeout: millisOr(opts.Timeout, defaultRedisTimeout)}
+}
+
+// redisSet is a VisitedSet kept in a Redis set, shared by every crawler
+// pointed at the same key.
+type redisSet struct {
+	*redisClient
+	key string
+}
+
+func newRedisSet(opts *config.Redis) *redisSet {
+	s := &redisSet{redisClient: newRedisClient(opts), key: opts.Key}
+	if s.key == "" {
+		s.key = defaultRedisKey
+	}
+	return s
+}
+
+func (s *redisSet) Add(ctx context.Context, url string) (bool, error) {
+	r, err := s.do(ctx, "SADD", s.key, url)
+	return r.n == 1, err
+}
+
+func (s *redisSet) Has(ctx context.Context, url string) (bool, error) {
+	r, err := s.do(ctx, "SISMEMBER", s.key, url)
+	return r.n == 1, err
+}
+
+// redisQueue is a LinkQueue kept in a Redis set, so that links queued
+// by any crawler of a fleet can be visited by all of them. A set rather
+// than a list keeps each link queued once and pops a random one, just
+// as the in-memory queue does.
+type redisQueue struct {
+	*redisClient
+	key string
+}
+
+func newRedisQueue(opts *config.Redis) *redisQueue {
+	q := &redisQueue{redisClient: newRedisClient(opts), key: opts.QueueKey}
+	if q.key == "" {
+		q.key = defaultRedisQueueKey
+	}
+	return q
+}
+
+func (q *redisQueue) Push(ctx context.Context, links ...string) error {
+	if len(links) == 0 {
+		return nil
+	}
+	_, err := q.do(ctx, append([]string{"SADD", q.key}, links...)...)
+	return err
+}
+
+func (q *redisQueue) Pop(ctx context.Context) (string, bool, error) {
+	r, err := q.do(ctx, "SPOP", q.key)
+	if err != nil || r.null {
+		return "", false, err
+	}
+	return r.s, true, nil
+}
+
+// redisReply is a decoded RESP reply: n for integers, s for simple
+// strings and bulk strings, null for a null bulk string.
+type redisReply struct {
+	n    int64
+	s    string
+	null bool
+}
+
+// do sends one command and returns its reply. The deadline is the
+// earlier of ctx's and the configured per-command timeout.
+func (s *redisClient) do(ctx context.Context, args ...string) (redisReply, error) {
+	if err := ctx.Err(); err != nil {
+		return redisReply{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if s.conn == nil {
+		if err := s.dial(ctx, deadline); err != nil {
+			return redisReply{}, fmt.Errorf("redis %s: %w", s.opts.Addr, err)
+		}
+	}
+
+	// Abort a blocked read or write as soon as ctx ends.
+	conn := s.conn
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	r, err := s.roundTrip(deadline, args...)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// The connection state is unknown; start afresh next time.
+		s.conn.Close()
+		s.conn = nil
+	}
+	if err != nil {
+		return redisReply{}, fmt.Errorf("redis %s: %w", args[0], err)
+	}
+	return r, nil
+}
+
+func (s *redisClient) dial(ctx context.Context, deadline time.Time) error {
+	dialer := net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", s.opts.Addr)
+	if err != nil {
+		return err
+	}
+	s.conn, s.rd = conn, bufio.NewReader(conn)
+	if s.opts.Password != "" {
+		if _, err := s.roundTrip(deadline, "AUTH", s.opts.Password); err != nil {
+			conn.Close()
+			s.conn = nil
+			return fmt.Errorf("AUTH: %w", err)
+		}
+	}
+	if s.opts.DB != 0 {
+		if _, err := s.roundTrip(deadline, "SELECT", strconv.Itoa(s.opts.DB)); err != nil {
+			conn.Close()
+			s.conn = nil
+			return fmt.Errorf("SELECT: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *redisClient) roundTrip(deadline time.Time, args ...string) (redisReply, error) {
+	if err := s.conn.SetDeadline(deadline); err != nil {
+		return redisReply{}, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(s.conn, b.String()); err != nil {
+		return redisReply{}, err
+	}
+	return readReply(s.rd)
+}
+
+// redisError is an error reply from the server; the connection remains
+// usable after one.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// readReply reads one RESP reply.
+func readReply(rd *bufio.Reader) (redisReply, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return redisReply{}, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return redisReply{}, errors.New("empty reply")
+	}
+	switch line[0] {
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		return redisReply{n: n}, err
+	case '+':
+		return redisReply{s: line[1:]}, nil
+	case '-':
+		return redisReply{}, redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return redisReply{}, err
+		}
+		if n < 0 {
+			return redisReply{null: true}, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return redisReply{}, err
+		}
+		return redisReply{s: string(buf[:n])}, nil
+	default:
+		return redisReply{}, fmt.Errorf("unexpected reply %q", line)
+	}
+}
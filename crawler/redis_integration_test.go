@@ -0,0 +1,75 @@
+//go:build redis
+
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/calpa/urusai/config"
+)
+
+// Run with a real server: REDIS_ADDR=localhost:6379 go test -tags redis ./crawler
+func TestRedisSetIntegration(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR not set")
+	}
+	opts := &config.Redis{
+		Addr:     addr,
+		Password: os.Getenv("REDIS_PASSWORD"),
+		Key:      fmt.Sprintf("urusai:test:%d", time.Now().UnixNano()),
+	}
+	a, b := newRedisSet(opts), newRedisSet(opts)
+	ctx := context.Background()
+
+	url := "http://example.com/integration"
+	if added, err := a.Add(ctx, url); err != nil || !added {
+		t.Fatalf("Expected first Add to claim the URL, got %v, %v", added, err)
+	}
+	if added, err := b.Add(ctx, url); err != nil || added {
+		t.Errorf("Expected second Add to find the URL, got %v, %v", added, err)
+	}
+	if seen, err := b.Has(ctx, url); err != nil || !seen {
+		t.Errorf("Expected Has to report the URL, got %v, %v", seen, err)
+	}
+	if seen, err := a.Has(ctx, url+"/unseen"); err != nil || seen {
+		t.Errorf("Expected Has to miss an unseen URL, got %v, %v", seen, err)
+	}
+}
+
+func TestRedisQueueIntegration(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR not set")
+	}
+	opts := &config.Redis{
+		Addr:     addr,
+		Password: os.Getenv("REDIS_PASSWORD"),
+		QueueKey: fmt.Sprintf("urusai:test:queue:%d", time.Now().UnixNano()),
+	}
+	a, b := newRedisQueue(opts), newRedisQueue(opts)
+	ctx := context.Background()
+
+	links := []string{"http://example.com/a", "http://example.com/b", "http://example.com/a"}
+	if err := a.Push(ctx, links...); err != nil {
+		t.Fatal(err)
+	}
+	popped := make(map[string]int)
+	for {
+		link, ok, err := b.Pop(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			break
+		}
+		popped[link]++
+	}
+	if len(popped) != 2 || popped[links[0]] != 1 || popped[links[1]] != 1 {
+		t.Errorf("Expected each queued link to be popped once, got %v", popped)
+	}
+}
@@ -0,0 +1,252 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/calpa/urusai/config"
+)
+
+// fakeRedis serves the handful of commands redisSet and redisQueue use.
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+	hang     bool // never reply to SADD/SISMEMBER/SPOP
+
+	mu       sync.Mutex
+	sets     map[string]map[string]struct{}
+	commands []string
+}
+
+func newFakeRedis(t *testing.T, password string, hang bool) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{ln: ln, password: password, hang: hang, sets: make(map[string]map[string]struct{})}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) addr() string { return f.ln.Addr().String() }
+
+func (f *fakeRedis) log() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return strings.Join(f.commands, " ")
+}
+
+func (f *fakeRedis) hasKey(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.sets[key]
+	return ok
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		args, err := readCommand(rd)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, args[0])
+		f.mu.Unlock()
+
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if args[1] != f.password {
+				reply = "-WRONGPASS invalid password\r\n"
+				break
+			}
+			authed = true
+			reply = "+OK\r\n"
+		case "SELECT":
+			reply = "+OK\r\n"
+		case "SADD", "SISMEMBER", "SPOP":
+			if !authed {
+				reply = "-NOAUTH Authentication required\r\n"
+				break
+			}
+			if f.hang {
+				time.Sleep(time.Hour)
+			}
+			if strings.EqualFold(args[0], "SPOP") {
+				reply = f.pop(args[1])
+				break
+			}
+			reply = fmt.Sprintf(":%d\r\n", f.apply(args))
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) apply(args []string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	set := f.sets[args[1]]
+	if set == nil {
+		set = make(map[string]struct{})
+		f.sets[args[1]] = set
+	}
+	if strings.EqualFold(args[0], "SADD") {
+		added := 0
+		for _, member := range args[2:] {
+			if _, ok := set[member]; !ok {
+				set[member] = struct{}{}
+				added++
+			}
+		}
+		return added
+	}
+	if _, ok := set[args[2]]; ok {
+		return 1
+	}
+	return 0
+}
+
+// pop removes any member of key as SPOP does, replying with a null bulk
+// string when the set is empty.
+func (f *fakeRedis) pop(key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for member := range f.sets[key] {
+		delete(f.sets[key], member)
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(member), member)
+	}
+	return "$-1\r\n"
+}
+
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := rd.ReadString('\n'); err != nil { // $len
+			return nil, err
+		}
+		arg, err := rd.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func TestRedisSetSharedBetweenCrawlers(t *testing.T) {
+	srv := newFakeRedis(t, "", false)
+	newCrawler := func() *Crawler {
+		cfg := testConfig("http://example.com")
+		cfg.VisitedBackend = "redis"
+		cfg.Redis = &config.Redis{Addr: srv.addr()}
+		return newTestCrawler(t, cfg)
+	}
+	a, b := newCrawler(), newCrawler()
+	ctx := context.Background()
+
+	if !a.markVisited(ctx, "http://example.com/page") {
+		t.Fatal("Expected the first crawler to claim the URL")
+	}
+	if b.markVisited(ctx, "http://example.com/page") {
+		t.Error("Expected the second crawler to see the URL as taken")
+	}
+	if got := b.rejectReason("http://example.com/page"); got != "already visited" {
+		t.Errorf("Expected the shared visit to be rejected, got %q", got)
+	}
+	if !b.markVisited(ctx, "http://example.com/other") {
+		t.Error("Expected an unseen URL to be claimed")
+	}
+	if a.visitedCount() != 1 || b.visitedCount() != 1 {
+		t.Errorf("Expected each crawler to count its own claims, got %d and %d", a.visitedCount(), b.visitedCount())
+	}
+	if !srv.hasKey(defaultRedisKey) {
+		t.Errorf("Expected the default key %q to be used", defaultRedisKey)
+	}
+}
+
+func TestRedisSetAuthAndSelect(t *testing.T) {
+	srv := newFakeRedis(t, "s3cret", false)
+
+	s := newRedisSet(&config.Redis{Addr: srv.addr(), Password: "s3cret", DB: 2, Key: "fleet"})
+	if added, err := s.Add(context.Background(), "http://example.com/"); err != nil || !added {
+		t.Fatalf("Expected Add to succeed, got %v, %v", added, err)
+	}
+	if got := srv.log(); got != "AUTH SELECT SADD" {
+		t.Errorf("Expected AUTH and SELECT before SADD, got %s", got)
+	}
+
+	bad := newRedisSet(&config.Redis{Addr: srv.addr(), Password: "wrong"})
+	if _, err := bad.Has(context.Background(), "http://example.com/"); err == nil {
+		t.Error("Expected a wrong password to fail")
+	}
+}
+
+func TestRedisSetRespectsContext(t *testing.T) {
+	srv := newFakeRedis(t, "", true)
+	s := newRedisSet(&config.Redis{Addr: srv.addr(), Timeout: 10000})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := s.Add(ctx, "http://example.com/")
+	if err == nil {
+		t.Fatal("Expected an error from a server that never answers")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the call to end with ctx, took %v", elapsed)
+	}
+	if _, err := s.Add(ctx, "http://example.com/"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected an ended ctx to fail fast, got %v", err)
+	}
+}
+
+func TestRedisUnavailableStillCrawls(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close() // nothing listens here any more
+
+	cfg := testConfig("http://example.com")
+	cfg.VisitedBackend = "redis"
+	cfg.Redis = &config.Redis{Addr: addr, Timeout: 100}
+	c := newTestCrawler(t, cfg)
+	if !c.markVisited(context.Background(), "http://example.com/") {
+		t.Error("Expected URLs to be fetched when Redis is unreachable")
+	}
+}
@@ -0,0 +1,96 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRenderBudget = 5 * time.Second
+	// renderGrace is how long Chrome may take beyond the render budget
+	// to start, load the page and print its DOM.
+	renderGrace = 15 * time.Second
+)
+
+// chromeNames are the binaries tried, in order, when cfg.ChromePath is
+// empty.
+var chromeNames = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome", "headless_shell"}
+
+// findChrome resolves the headless browser for cfg.RenderJS.
+func findChrome(path string) (string, error) {
+	if path != "" {
+		return exec.LookPath(path)
+	}
+	for _, name := range chromeNames {
+		if p, err := exec.LookPath(name); err == nil {
+			return p, nil
+		}
+	}
+	return "", errors.New("no Chrome or Chromium binary on PATH; set chrome_path")
+}
+
+// isHTML reports whether contentType names an HTML page, the only kind
+// worth rendering.
+func isHTML(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mt == "text/html" || mt == "application/xhtml+xml"
+}
+
+// render loads target in headless Chrome with --dump-dom and returns the
+// serialized DOM once scripts have had cfg.RenderBudget of virtual time,
+// which Chrome fast-forwards when the page goes idle. The output is cut
+// to the usual body cap. Chrome fetches target and its resources over
+// its own connections, which is why config validation keeps render_js
+// apart from the settings those fetches would bypass.
+func (c *Crawler) render(ctx context.Context, target string) ([]byte, error) {
+	budget := millisOr(c.cfg.RenderBudget, defaultRenderBudget)
+	ctx, cancel := context.WithTimeout(ctx, budget+renderGrace)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.chrome,
+		"--headless",
+		"--disable-gpu",
+		"--user-agent="+c.userAgent(),
+		"--virtual-time-budget="+strconv.FormatInt(budget.Milliseconds(), 10),
+		"--dump-dom",
+		target,
+	)
+	dom, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return nil, fmt.Errorf("render %s: %w", target, err)
+	}
+	if len(dom) > maxBodyBytes {
+		dom = dom[:maxBodyBytes]
+	}
+	return dom, nil
+}
+
+// renderedBody returns the rendered DOM of an HTML page for
+// cfg.RenderJS, falling back to the fetched body when the page is not
+// HTML or Chrome fails.
+func (c *Crawler) renderedBody(ctx context.Context, target string, body []byte, contentType string) []byte {
+	if !c.cfg.RenderJS || !isHTML(contentType) {
+		return body
+	}
+	dom, err := c.render(ctx, target)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("%v; using the static page", err)
+		}
+		return body
+	}
+	c.debugf("rendered %s: %d bytes of DOM", shorten(target, maxLoggedURL), len(dom))
+	return dom
+}
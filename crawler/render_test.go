@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// jsPage only links to /js once its script has run.
+const jsPage = `<html><body><script>
+document.body.insertAdjacentHTML("beforeend", '<a href="/js">rendered</a>');
+</script></body></html>`
+
+func jsSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, jsPage)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRenderJSUsesRenderedDOM(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script standing in for Chrome")
+	}
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	script := fmt.Sprintf("#!/bin/sh\necho \"$@\" > %s\nprintf '<html><body><a href=\"/js\">rendered</a></body></html>'\n", argsFile)
+	chrome := filepath.Join(dir, "chrome")
+	if err := os.WriteFile(chrome, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	srv := jsSite(t)
+
+	cfg := testConfig(srv.URL)
+	cfg.RenderJS = true
+	cfg.ChromePath = chrome
+	cfg.RenderBudget = 2000
+	c := newTestCrawler(t, cfg)
+	found, err := c.visit(context.Background(), srv.URL+"/", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0] != srv.URL+"/js" {
+		t.Errorf("Expected the link from the rendered DOM, got %v", found)
+	}
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--headless", "--virtual-time-budget=2000", "--dump-dom", srv.URL + "/"} {
+		if !strings.Contains(string(args), want) {
+			t.Errorf("Expected Chrome to be run with %s, got %s", want, args)
+		}
+	}
+
+	cfg.RenderJS = false
+	if found, _ := newTestCrawler(t, cfg).visit(context.Background(), srv.URL+"/", 0); len(found) != 0 {
+		t.Errorf("Expected the static page to yield no links, got %v", found)
+	}
+}
+
+func TestRenderJSMissingChrome(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.RenderJS = true
+	cfg.ChromePath = filepath.Join(t.TempDir(), "no-such-chrome")
+	if _, err := NewCrawler(cfg); err == nil || !strings.Contains(err.Error(), "render_js") {
+		t.Errorf("Expected a missing browser to fail NewCrawler, got %v", err)
+	}
+}
+
+// TestRenderJSInChrome runs the real browser when one is installed.
+func TestRenderJSInChrome(t *testing.T) {
+	chrome, err := findChrome(os.Getenv("CHROME_PATH"))
+	if err != nil {
+		t.Skip(err)
+	}
+	srv := jsSite(t)
+	cfg := testConfig(srv.URL)
+	cfg.RenderJS = true
+	cfg.ChromePath = chrome
+	c := newTestCrawler(t, cfg)
+	found, err := c.visit(context.Background(), srv.URL+"/", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0] != srv.URL+"/js" {
+		t.Errorf("Expected Chrome to run the script that adds /js, got %v", found)
+	}
+}
@@ -0,0 +1,83 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// minRevisitSweep is the fetch log size below which expired entries are
+// left in place.
+const minRevisitSweep = 1024
+
+// revisitLog remembers when each URL was last fetched, for
+// cfg.MinRevisitInterval.
+type revisitLog struct {
+	floor time.Duration
+
+	mu    sync.Mutex
+	last  map[string]time.Time // by clock
+	sweep int                  // size at which expired entries are next dropped
+}
+
+func newRevisitLog(floor time.Duration) *revisitLog {
+	return &revisitLog{floor: floor, last: make(map[string]time.Time), sweep: minRevisitSweep}
+}
+
+// record notes a fetch of link at now, dropping entries older than the
+// floor whenever the log has doubled since the last sweep.
+func (l *revisitLog) record(link string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.last[link] = now
+	if len(l.last) < l.sweep {
+		return
+	}
+	for k, t := range l.last {
+		if now.Sub(t) >= l.floor {
+			delete(l.last, k)
+		}
+	}
+	l.sweep = max(2*len(l.last), minRevisitSweep)
+}
+
+// wait returns how long link must still wait before it may be fetched
+// again, 0 when it may be fetched now.
+func (l *revisitLog) wait(link string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t, ok := l.last[link]
+	if !ok {
+		return 0
+	}
+	return max(l.floor-now.Sub(t), 0)
+}
+
+// revisitWait returns how long cfg.MinRevisitInterval still holds link
+// back, 0 when it is unset or has passed.
+func (c *Crawler) revisitWait(link string) time.Duration {
+	if c.revisits == nil {
+		return 0
+	}
+	return c.revisits.wait(link, c.clock.Now())
+}
+
+// recordFetch logs a fetch of link for cfg.MinRevisitInterval.
+func (c *Crawler) recordFetch(link string) {
+	if c.revisits != nil {
+		c.revisits.record(link, c.clock.Now())
+	}
+}
+
+// waitRevisit holds a root back until cfg.MinRevisitInterval has passed
+// since it was last fetched, reporting false when ctx or the timeout
+// ends the wait first. Roots are fetched every iteration, unlike links,
+// so they wait rather than being skipped.
+func (c *Crawler) waitRevisit(ctx context.Context, root string) bool {
+	wait := c.revisitWait(root)
+	if wait == 0 {
+		return true
+	}
+	c.debugf("root %s fetched recently: waiting %v for min_revisit_interval", root, wait)
+	return sleepCtx(ctx, c.untilTimeout(wait)) && c.revisitWait(root) == 0
+}
@@ -0,0 +1,62 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/calpa/urusai/config"
+)
+
+// rewriteRule maps outgoing URLs matching re to repl, which may refer to
+// capture groups as $1 or ${name}.
+type rewriteRule struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+func compileRewrites(rules []config.URLRewrite) ([]rewriteRule, error) {
+	out := make([]rewriteRule, 0, len(rules))
+	for i, r := range rules {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("url_rewrites[%d]: %w", i, err)
+		}
+		out = append(out, rewriteRule{re: re, repl: r.Replace})
+	}
+	return out, nil
+}
+
+// rewrite applies every rule in order to the URL about to be requested.
+// Queue and dedup keep the original URL, so a production link graph can
+// be replayed against another environment.
+func (c *Crawler) rewrite(raw string) string {
+	for _, r := range c.rewrites {
+		raw = r.re.ReplaceAllString(raw, r.repl)
+	}
+	return raw
+}
+
+// cacheBustParam is the query parameter added by cfg.CacheBust.
+const cacheBustParam = "_"
+
+// cacheBust appends a random cacheBustParam to a cfg.CacheBust share of
+// outgoing URLs. Like rewrite it only touches the request, never the
+// dedup key.
+func (c *Crawler) cacheBust(raw string) string {
+	if c.cfg.CacheBust <= 0 || c.rand.Float64() >= c.cfg.CacheBust {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	token := strconv.FormatUint(c.rand.Uint64(), 36)
+	if u.RawQuery == "" {
+		u.RawQuery = cacheBustParam + "=" + token
+	} else {
+		u.RawQuery += "&" + cacheBustParam + "=" + token
+	}
+	return u.String()
+}
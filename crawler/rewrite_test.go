@@ -0,0 +1,120 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/calpa/urusai/config"
+)
+
+func TestRewrite(t *testing.T) {
+	cfg := testConfig()
+	cfg.URLRewrites = []config.URLRewrite{
+		{Pattern: `^https://www\.example\.com/`, Replace: "http://staging.example.com/"},
+		{Pattern: `/item/(\d+)$`, Replace: "/v2/items/$1"},
+		{Pattern: `lang=(?P<lang>[a-z]+)`, Replace: "locale=${lang}"},
+	}
+	c := newTestCrawler(t, cfg)
+
+	testCases := []struct{ in, want string }{
+		{"https://www.example.com/about", "http://staging.example.com/about"},
+		{"https://www.example.com/item/42", "http://staging.example.com/v2/items/42"},
+		{"https://other.example/?lang=ja", "https://other.example/?locale=ja"},
+		{"https://other.example/item/x", "https://other.example/item/x"},
+	}
+	for _, tc := range testCases {
+		if got := c.rewrite(tc.in); got != tc.want {
+			t.Errorf("rewrite(%q) = %q, expected %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestRewriteKeepsDedupKey(t *testing.T) {
+	var paths []string
+	staging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+	defer staging.Close()
+
+	cfg := testConfig("https://prod.example/")
+	cfg.MaxDepth = 1
+	cfg.URLRewrites = []config.URLRewrite{{Pattern: `^https://prod\.example`, Replace: staging.URL}}
+	c := newTestCrawler(t, cfg)
+
+	c.links = []string{"https://prod.example/page"}
+	c.depthFirst(context.Background(), 0)
+
+	if strings.Join(paths, ",") != "/page" {
+		t.Errorf("Expected staging to receive /page, got %v", paths)
+	}
+	if !c.isVisited(context.Background(), "https://prod.example/page") {
+		t.Error("Expected the original URL to be recorded as visited")
+	}
+}
+
+func TestNewCrawlerRejectsBadRewrite(t *testing.T) {
+	cfg := testConfig()
+	cfg.URLRewrites = []config.URLRewrite{{Pattern: "("}}
+	if _, err := NewCrawler(cfg); err == nil {
+		t.Error("Expected invalid pattern to fail NewCrawler")
+	}
+}
+
+func TestCacheBust(t *testing.T) {
+	var mu sync.Mutex
+	var queries []url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries = append(queries, r.URL.Query())
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.MaxDepth = 1
+	cfg.CacheBust = 1
+	c := newTestCrawler(t, cfg)
+
+	page := srv.URL + "/page?id=7"
+	c.links = []string{page}
+	c.depthFirst(context.Background(), 0)
+
+	if len(queries) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(queries))
+	}
+	if q := queries[0]; q.Get("id") != "7" || q.Get(cacheBustParam) == "" {
+		t.Errorf("Expected the original query plus a cache buster, got %v", q)
+	}
+	if !c.isVisited(context.Background(), page) {
+		t.Error("Expected the URL without the cache buster to be recorded as visited")
+	}
+	if got := c.rejectReason(page); got != "already visited" {
+		t.Errorf("Expected the canonical URL to stay deduplicated, got %q", got)
+	}
+}
+
+func TestCacheBustShare(t *testing.T) {
+	cfg := testConfig()
+	cfg.CacheBust = 0.25
+	c := newTestCrawler(t, cfg)
+
+	busted := 0
+	for i := 0; i < 2000; i++ {
+		if c.cacheBust("http://example.com/") != "http://example.com/" {
+			busted++
+		}
+	}
+	if share := float64(busted) / 2000; share < 0.2 || share > 0.3 {
+		t.Errorf("Expected about 25%% of requests to be busted, got %.1f%%", share*100)
+	}
+
+	cfg.CacheBust = 0
+	if got := c.cacheBust("http://example.com/"); got != "http://example.com/" {
+		t.Errorf("Expected no cache buster when disabled, got %q", got)
+	}
+}
@@ -0,0 +1,29 @@
+package crawler
+
+import "time"
+
+// Clock supplies the wall-clock time used for time-of-day scheduling,
+// the rate ramp-up and cfg.StopAt.
+// Tests replace it with SetClock to drive the schedule deterministically.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SetClock replaces the clock consulted by cfg.ActiveHours,
+// cfg.RampUpDuration and cfg.StopAt.
+func (c *Crawler) SetClock(clk Clock) {
+	c.clock = clk
+}
+
+// scheduleFactor scales think time by cfg.ActiveHours for the current
+// local time: 1 during active hours, the idle factor outside them.
+func (c *Crawler) scheduleFactor() float64 {
+	if c.cfg.ActiveHours == nil {
+		return 1
+	}
+	return c.cfg.ActiveHours.Factor(c.clock.Now())
+}
@@ -0,0 +1,47 @@
+package crawler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/calpa/urusai/config"
+)
+
+// fakeClock is a Clock the test moves by hand.
+type fakeClock struct{ now time.Time }
+
+func (f *fakeClock) Now() time.Time          { return f.now }
+func (f *fakeClock) advance(d time.Duration) { f.now = f.now.Add(d) }
+
+func TestThinkTimeFollowsActiveHours(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.MinSleep, cfg.MaxSleep = 10, 10
+	cfg.ActiveHours = &config.ActiveHours{Start: "09:00", End: "18:00", IdleFactor: 50}
+	c := newTestCrawler(t, cfg)
+
+	clk := &fakeClock{now: time.Date(2024, 5, 6, 0, 30, 0, 0, time.Local)}
+	c.SetClock(clk)
+
+	// Walk through one day hour by hour.
+	for hour := 0; hour < 24; hour++ {
+		want := 500 * time.Microsecond
+		if hour >= 9 && hour < 18 {
+			want = 10 * time.Microsecond
+		}
+		if got := c.thinkTime("example.com"); got != want {
+			t.Errorf("Expected %v think time at %02d:30, got %v", want, hour, got)
+		}
+		clk.advance(time.Hour)
+	}
+}
+
+func TestThinkTimeWithoutActiveHours(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.MinSleep, cfg.MaxSleep = 10, 10
+	c := newTestCrawler(t, cfg)
+	c.SetClock(&fakeClock{now: time.Date(2024, 5, 6, 3, 0, 0, 0, time.Local)})
+
+	if got := c.thinkTime("example.com"); got != 10*time.Microsecond {
+		t.Errorf("Expected unscaled 10µs think time, got %v", got)
+	}
+}
@@ -0,0 +1,25 @@
+package crawler
+
+import "time"
+
+// sessionBreak returns how long Crawl pauses before its next branch to
+// mimic browsing sessions: 0 while the current session lasts, and
+// cfg.PauseBetweenSessions once it has run for cfg.SessionDuration, in
+// which case the next session starts when the pause ends. Sessions end
+// between branches, never in the middle of one.
+func (c *Crawler) sessionBreak() time.Duration {
+	if c.cfg.SessionDuration <= 0 || c.cfg.PauseBetweenSessions <= 0 {
+		return 0
+	}
+	now := c.clock.Now()
+	if c.sessionStart.IsZero() {
+		c.sessionStart = now
+		return 0
+	}
+	if now.Sub(c.sessionStart) < time.Duration(c.cfg.SessionDuration)*time.Millisecond {
+		return 0
+	}
+	pause := time.Duration(c.cfg.PauseBetweenSessions) * time.Millisecond
+	c.sessionStart = now.Add(pause)
+	return pause
+}
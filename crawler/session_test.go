@@ -0,0 +1,72 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessionCadence(t *testing.T) {
+	cfg := testConfig("http://example.com")
+	cfg.SessionDuration = int((10 * time.Minute).Milliseconds())
+	cfg.PauseBetweenSessions = int((5 * time.Minute).Milliseconds())
+	c := newTestCrawler(t, cfg)
+	clk := &fakeClock{now: time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)}
+	c.SetClock(clk)
+
+	// Each step advances the clock, then asks whether to pause.
+	steps := []struct {
+		advance time.Duration
+		want    time.Duration
+	}{
+		{0, 0},                         // first session starts
+		{9 * time.Minute, 0},           // still browsing
+		{time.Minute, 5 * time.Minute}, // session over
+		{5 * time.Minute, 0},           // second session starts after the pause
+		{9*time.Minute + 59*time.Second, 0},
+		{time.Second, 5 * time.Minute}, // second session over
+		{5 * time.Minute, 0},
+	}
+	for i, step := range steps {
+		clk.advance(step.advance)
+		if got := c.sessionBreak(); got != step.want {
+			t.Errorf("Step %d: expected pause %v, got %v", i, step.want, got)
+		}
+	}
+}
+
+func TestSessionPauseHonoursContext(t *testing.T) {
+	srv := linkSite(t, map[string][]string{"/": {"/a"}}, new(int32))
+	cfg := testConfig(srv.URL + "/")
+	cfg.SessionDuration = 1
+	cfg.PauseBetweenSessions = int(time.Hour.Milliseconds())
+	c := newTestCrawler(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := c.Crawl(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected cancellation to end the pause, Crawl took %v", elapsed)
+	}
+}
+
+func TestSessionPauseEndsAtTimeout(t *testing.T) {
+	srv := linkSite(t, map[string][]string{"/": {"/a"}}, new(int32))
+	cfg := testConfig(srv.URL + "/")
+	cfg.Timeout = 1
+	cfg.SessionDuration = 1
+	cfg.PauseBetweenSessions = int(time.Hour.Milliseconds())
+	c := newTestCrawler(t, cfg)
+
+	start := time.Now()
+	if err := c.Crawl(context.Background()); !errors.Is(err, ErrTimeLimit) {
+		t.Fatalf("Expected ErrTimeLimit, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the pause to stop at the 1s timeout, Crawl took %v", elapsed)
+	}
+}
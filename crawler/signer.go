@@ -0,0 +1,14 @@
+package crawler
+
+import "net/http"
+
+// RequestSigner prepares an outgoing request just before it is sent,
+// for gateways that expect a signature or timestamp header computed over
+// the request. An error aborts the fetch.
+type RequestSigner func(req *http.Request) error
+
+// SetRequestSigner installs fn to run on every page request after all
+// other headers are set. A nil fn sends requests unsigned.
+func (c *Crawler) SetRequestSigner(fn RequestSigner) {
+	c.signer = fn
+}
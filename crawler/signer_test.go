@@ -0,0 +1,61 @@
+package crawler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var signingKey = []byte("gateway-secret")
+
+// sign returns the hex HMAC-SHA256 of method, path and timestamp.
+func sign(method, path, timestamp string) string {
+	mac := hmac.New(sha256.New, signingKey)
+	mac.Write([]byte(method + "\n" + path + "\n" + timestamp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestRequestSigner(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		ts := r.Header.Get("X-Timestamp")
+		want := sign(r.Method, r.URL.Path, ts)
+		if ts == "" || !hmac.Equal([]byte(r.Header.Get("X-Signature")), []byte(want)) {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	c := newTestCrawler(t, testConfig(srv.URL))
+	if _, _, err := c.fetch(context.Background(), srv.URL+"/api/items"); err == nil {
+		t.Fatal("Expected an unsigned request to be refused")
+	}
+
+	c.SetRequestSigner(func(req *http.Request) error {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Timestamp", ts)
+		req.Header.Set("X-Signature", sign(req.Method, req.URL.Path, ts))
+		return nil
+	})
+	if _, _, err := c.fetch(context.Background(), srv.URL+"/api/items/1"); err != nil {
+		t.Fatalf("Expected the signed request to pass, got %v", err)
+	}
+
+	errNoKey := errors.New("no signing key")
+	c.SetRequestSigner(func(*http.Request) error { return errNoKey })
+	if _, _, err := c.fetch(context.Background(), srv.URL+"/api/items/2"); !errors.Is(err, errNoKey) {
+		t.Errorf("Expected the signer's error, got %v", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Errorf("Expected a failed signing to send nothing, got %d requests", n)
+	}
+}
@@ -0,0 +1,50 @@
+package crawler
+
+import "net/http"
+
+// Stats holds the counters collected during a crawl.
+type Stats struct {
+	Requests        int // HTTP requests issued
+	HTTPRequests    int // requests over plain http
+	HTTPSRequests   int // requests over https
+	Errors          int // failed requests, including 4xx/5xx responses
+	RedirectLoops   int // requests abandoned because of a redirect loop
+	QueueDrops      int // links discarded because the queue was full
+	StaleLinks      int // queued links discarded by MaxLinkAge
+	DuplicateBodies int // pages whose links were skipped by DedupByContent
+	InjectedFaults  int // fetches failed or delayed by FaultInjection
+
+	Bytes int64 // response body bytes downloaded
+
+	ErrorKinds ErrorKinds // Errors broken down by cause
+}
+
+// count applies update to the crawler's stats under its lock.
+func (c *Crawler) count(update func(s *Stats)) {
+	c.statsMu.Lock()
+	update(&c.stats)
+	c.statsMu.Unlock()
+}
+
+// countRequest records an HTTP request about to be sent.
+func (c *Crawler) countRequest(req *http.Request) {
+	c.count(func(s *Stats) {
+		s.Requests++
+		switch req.URL.Scheme {
+		case "http":
+			s.HTTPRequests++
+		case "https":
+			s.HTTPSRequests++
+		}
+	})
+}
+
+// Snapshot returns a consistent copy of the crawl's counters. It is safe
+// to call while the crawl is running.
+func (c *Crawler) Snapshot() Stats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	s := c.stats
+	s.Bytes = c.fetched.Load()
+	return s
+}
@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSnapshotDuringConcurrentFetches(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			http.Error(w, "nope", http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	c := newTestCrawler(t, testConfig(srv.URL))
+
+	const workers, perWorker = 8, 25
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		var prev Stats
+		for {
+			s := c.Snapshot()
+			if s.HTTPRequests+s.HTTPSRequests != s.Requests || s.Errors > s.Requests {
+				t.Errorf("Inconsistent snapshot %+v", s)
+				return
+			}
+			if s.Requests < prev.Requests || s.Errors < prev.Errors {
+				t.Errorf("Counters went backwards: %+v after %+v", s, prev)
+				return
+			}
+			prev = s
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				u := fmt.Sprintf("%s/w%d/%d", srv.URL, w, i)
+				if i%5 == 0 {
+					u += "?fail=1"
+				}
+				_, _, _ = c.fetch(context.Background(), u)
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	s := c.Snapshot()
+	if s.Requests != workers*perWorker {
+		t.Errorf("Expected %d requests, got %d", workers*perWorker, s.Requests)
+	}
+	if s.Errors != workers*perWorker/5 {
+		t.Errorf("Expected %d errors, got %d", workers*perWorker/5, s.Errors)
+	}
+}
@@ -0,0 +1,63 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStopAt(t *testing.T) {
+	now := time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name   string
+		stopAt time.Time
+		want   bool
+	}{
+		{"Past", now.Add(-time.Minute), true},
+		{"Now", now, true},
+		{"Future", now.Add(time.Minute), false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig("http://example.com")
+			cfg.StopAt = tc.stopAt.Format(time.RFC3339)
+			c := newTestCrawler(t, cfg)
+			c.SetClock(&fakeClock{now: now})
+
+			if got := c.isTimeoutReached(); got != tc.want {
+				t.Errorf("Expected isTimeoutReached %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestStopAtEndsCrawl(t *testing.T) {
+	var hits int32
+	srv := linkSite(t, map[string][]string{"/": {"/a"}, "/a": {"/"}}, &hits)
+
+	cfg := testConfig(srv.URL)
+	cfg.StopAt = "2024-05-06T12:00:00Z"
+	c := newTestCrawler(t, cfg)
+	clk := &fakeClock{now: time.Date(2024, 5, 6, 11, 0, 0, 0, time.UTC)}
+	c.SetClock(clk)
+
+	// An hour before StopAt a pause is cut short only by its own length.
+	if got := c.untilTimeout(time.Minute); got != time.Minute {
+		t.Errorf("Expected a 1m pause before StopAt, got %v", got)
+	}
+	clk.advance(59 * time.Minute)
+	if got := c.untilTimeout(time.Hour); got != time.Minute {
+		t.Errorf("Expected the pause to end at StopAt, got %v", got)
+	}
+
+	clk.advance(time.Minute)
+	if err := c.Crawl(context.Background()); !errors.Is(err, ErrTimeLimit) {
+		t.Fatalf("Expected ErrTimeLimit, got %v", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Errorf("Expected no requests once StopAt has passed, got %d", n)
+	}
+}
@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"sync/atomic"
+)
+
+// linkSink receives the link candidates request scans from an HTML body
+// while it downloads, for cfg.StreamLinks.
+type linkSink struct {
+	emit     func(link string)
+	streamed atomic.Bool // set once request has scanned the body
+}
+
+type linkSinkKey struct{}
+
+func withLinkSink(ctx context.Context, s *linkSink) context.Context {
+	return context.WithValue(ctx, linkSinkKey{}, s)
+}
+
+func linkSinkFrom(ctx context.Context) *linkSink {
+	s, _ := ctx.Value(linkSinkKey{}).(*linkSink)
+	return s
+}
+
+// canStream reports whether the links of a response can be scanned from
+// its raw bytes. JSON takes another extractor, a non-UTF-8 charset or a
+// body transform must see the whole body first, and DedupByContent
+// decides whether to keep a page's links only once it is complete. When
+// the header names no charset, the start of body is peeked for a <meta>
+// declaration, as the buffered path would find it.
+func (c *Crawler) canStream(contentType string, body *bufio.Reader) bool {
+	if c.transform != nil || c.cfg.DedupByContent || isJSON(contentType) {
+		return false
+	}
+	var head []byte
+	if detectCharset(contentType, nil) == "" {
+		head, _ = body.Peek(charsetSniffLen) // a shorter body is peeked whole
+	}
+	switch detectCharset(contentType, head) {
+	case "", "utf-8", "us-ascii":
+		return true
+	}
+	return false
+}
+
+// streamLinks scans the HTML written to the returned writer with
+// scanLinks, handing candidates to sink as their tags complete. wait
+// ends the input and returns once every candidate has been handed over.
+func (c *Crawler) streamLinks(sink *linkSink, base string) (w io.Writer, wait func()) {
+	sink.streamed.Store(true)
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.scanLinks(pr, base, sink.emit)
+		_, _ = io.Copy(io.Discard, pr) // never stall the body read
+	}()
+	return pw, func() {
+		pw.Close()
+		<-done
+	}
+}
+
+// fetchStreaming fetches target like fetch while queueing every
+// acceptable link as soon as it has been parsed, and returns those
+// links too. streamed is false when the body could not be scanned on
+// the fly, leaving link extraction to the caller.
+func (c *Crawler) fetchStreaming(ctx context.Context, target string) (body []byte, contentType string, found []string, streamed bool, err error) {
+	candidates := make(chan string)
+	sink := &linkSink{emit: func(link string) { candidates <- link }}
+	type result struct {
+		body        []byte
+		contentType string
+		err         error
+	}
+	done := make(chan result, 1)
+	go func() {
+		body, contentType, err := c.fetch(withLinkSink(ctx, sink), target)
+		done <- result{body, contentType, err}
+	}()
+
+	// The queue belongs to this goroutine, so candidates are accepted and
+	// queued here. fetch only returns after the last one was sent.
+	for {
+		select {
+		case link := <-candidates:
+			if c.keepLink(target, link) {
+				c.push(ctx, link)
+				found = append(found, link)
+			}
+		case r := <-done:
+			return r.body, r.contentType, found, sink.streamed.Load(), r.err
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStreamLinksBeforeBodyEnds(t *testing.T) {
+	first := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><a href="/a">a</a>`)
+		w.(http.Flusher).Flush()
+		// Hold the rest of the body until the first link got out.
+		select {
+		case <-first:
+		case <-time.After(2 * time.Second):
+			t.Error("Expected a link to be streamed before the body was complete")
+		}
+		fmt.Fprint(w, `<a href="/b">b</a></body></html>`)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.StreamLinks = true
+	c := newTestCrawler(t, cfg)
+
+	var (
+		mu    sync.Mutex
+		links []string
+		once  sync.Once
+	)
+	sink := &linkSink{emit: func(link string) {
+		mu.Lock()
+		links = append(links, link)
+		mu.Unlock()
+		once.Do(func() { close(first) })
+	}}
+	if _, _, err := c.fetch(withLinkSink(context.Background(), sink), srv.URL+"/"); err != nil {
+		t.Fatalf("Expected the page to load, got %v", err)
+	}
+
+	want := []string{srv.URL + "/a", srv.URL + "/b"}
+	if strings.Join(links, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected %v, got %v", want, links)
+	}
+}
+
+func TestStreamLinksKeepsBodyCap(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="/a">a</a>`)
+		fmt.Fprint(w, strings.Repeat(" ", maxBodyBytes))
+		fmt.Fprint(w, `<a href="/past-the-cap">b</a>`)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.StreamLinks = true
+	c := newTestCrawler(t, cfg)
+
+	found, err := c.visit(context.Background(), srv.URL+"/", 0)
+	if err != nil {
+		t.Fatalf("Expected the page to load, got %v", err)
+	}
+	want := srv.URL + "/a"
+	if len(found) != 1 || found[0] != want {
+		t.Errorf("Expected only %s within the body cap, got %v", want, found)
+	}
+	if len(c.links) != 1 || c.links[0] != want {
+		t.Errorf("Expected %s to be queued, got %v", want, c.links)
+	}
+}
+
+func TestStreamLinksHonoursMetaCharset(t *testing.T) {
+	// <a href="/ニュース">ニュース</a>, path and text in Shift_JIS, which
+	// only the <meta> tag declares.
+	news := "\x83\x6a\x83\x85\x81\x5b\x83\x58"
+	page := []byte(`<meta charset="Shift_JIS"><a href="/` + news + `">` + news + `</a>`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(page)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.StreamLinks = true
+	c := newTestCrawler(t, cfg)
+	_, _, found, streamed, err := c.fetchStreaming(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streamed {
+		t.Errorf("Expected a Shift_JIS page to be buffered, got %v streamed", found)
+	}
+
+	links, err := c.visit(context.Background(), srv.URL+"/", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := srv.URL + "/%E3%83%8B%E3%83%A5%E3%83%BC%E3%82%B9" // /ニュース in UTF-8
+	if len(links) != 1 || links[0] != want {
+		t.Errorf("Expected [%s], got %v", want, links)
+	}
+}
@@ -0,0 +1,123 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+
+	utls "github.com/refraction-networking/utls"
+
+	"github.com/calpa/urusai/config"
+)
+
+// newTLSConfig returns the client TLS settings requested by cfg, or nil
+// when the defaults apply. A client certificate enables mutual TLS and a
+// CA file replaces the system roots for server verification.
+func newTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if cfg.ClientCertFile == "" && cfg.CACertFile == "" {
+		return nil, nil
+	}
+
+	tc := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	if cfg.CACertFile != "" {
+		pem, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("load CA certificates: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("load CA certificates: no PEM certificates in %s", cfg.CACertFile)
+		}
+		tc.RootCAs = pool
+	}
+	return tc, nil
+}
+
+// helloIDs are the ClientHellos selectable by cfg.TLSFingerprint.
+var helloIDs = map[string]utls.ClientHelloID{
+	"chrome":  utls.HelloChrome_Auto,
+	"firefox": utls.HelloFirefox_Auto,
+	"random":  utls.HelloRandomizedNoALPN,
+}
+
+// fingerprintDialer returns a DialTLSContext that dials with dial and
+// handshakes with uTLS, sending the browser-like ClientHello named by
+// fingerprint. tc carries the roots and client certificate, as for
+// crypto/tls; nil uses the system roots.
+func fingerprintDialer(dial dialFunc, tc *tls.Config, fingerprint string) (dialFunc, error) {
+	id, ok := helloIDs[fingerprint]
+	if !ok {
+		return nil, fmt.Errorf("unknown tls_fingerprint %q", fingerprint)
+	}
+	if _, err := helloSpec(id); err != nil {
+		return nil, fmt.Errorf("tls_fingerprint %s: %w", fingerprint, err)
+	}
+	base := &utls.Config{MinVersion: utls.VersionTLS12}
+	if tc != nil {
+		base.RootCAs = tc.RootCAs
+		for _, cert := range tc.Certificates {
+			base.Certificates = append(base.Certificates, utls.Certificate{
+				Certificate: cert.Certificate,
+				PrivateKey:  cert.PrivateKey,
+				Leaf:        cert.Leaf,
+			})
+		}
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		uc := base.Clone()
+		if uc.ServerName, _, err = net.SplitHostPort(addr); err != nil {
+			uc.ServerName = addr
+		}
+		hello := utls.UClient(conn, uc, id)
+		// ApplyPreset takes ownership of the spec's extensions, so every
+		// connection builds its own.
+		if spec, _ := helloSpec(id); spec != nil {
+			hello = utls.UClient(conn, uc, utls.HelloCustom)
+			err = hello.ApplyPreset(spec)
+		}
+		if err == nil {
+			err = hello.HandshakeContext(ctx)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return hello, nil
+	}, nil
+}
+
+// helloSpec returns the ClientHello of id offering only HTTP/1.1 over
+// ALPN, since net/http runs HTTP/2 on its own *tls.Conn only. The
+// cipher suites, extensions and their order, which a JA3 fingerprint is
+// made of, stay the browser's. It returns nil for randomized hellos,
+// which are built per connection and already offer no ALPN.
+func helloSpec(id utls.ClientHelloID) (*utls.ClientHelloSpec, error) {
+	if id == utls.HelloRandomizedNoALPN {
+		return nil, nil
+	}
+	spec, err := utls.UTLSIdToSpec(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, ext := range spec.Extensions {
+		if alpn, ok := ext.(*utls.ALPNExtension); ok {
+			alpn.AlpnProtocols = []string{"http/1.1"}
+		}
+	}
+	return &spec, nil
+}
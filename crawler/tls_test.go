@@ -0,0 +1,182 @@
+package crawler
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writePEM writes a single PEM block to dir/name and returns its path.
+func writePEM(t *testing.T, dir, name, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newClientCert creates a throwaway CA and a client certificate signed
+// by it, returning the CA pool and the cert/key file paths.
+func newClientCert(t *testing.T) (*x509.CertPool, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "urusai test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "urusai"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	return pool, writePEM(t, dir, "client.crt", "CERTIFICATE", der), writePEM(t, dir, "client.key", "EC PRIVATE KEY", keyDER)
+}
+
+func TestMutualTLS(t *testing.T) {
+	clientCAs, certFile, keyFile := newClientCert(t)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+
+	caFile := writePEM(t, t.TempDir(), "ca.crt", "CERTIFICATE", srv.Certificate().Raw)
+
+	cfg := testConfig(srv.URL)
+	cfg.CACertFile = caFile
+	without := newTestCrawler(t, cfg)
+	if _, _, err := without.fetch(context.Background(), srv.URL); err == nil {
+		t.Error("Expected handshake to fail without a client certificate")
+	}
+
+	cfg.ClientCertFile, cfg.ClientKeyFile = certFile, keyFile
+	with := newTestCrawler(t, cfg)
+	if _, _, err := with.fetch(context.Background(), srv.URL); err != nil {
+		t.Errorf("Expected fetch with client certificate to succeed, got %v", err)
+	}
+}
+
+func TestNewCrawlerRejectsBadCertificates(t *testing.T) {
+	dir := t.TempDir()
+	junk := filepath.Join(dir, "junk.pem")
+	if err := os.WriteFile(junk, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name   string
+		cert   string
+		key    string
+		caFile string
+	}{
+		{"Missing cert", filepath.Join(dir, "missing.crt"), filepath.Join(dir, "missing.key"), ""},
+		{"Junk cert", junk, junk, ""},
+		{"Junk CA", "", "", junk},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig("https://example.com")
+			cfg.ClientCertFile, cfg.ClientKeyFile, cfg.CACertFile = tc.cert, tc.key, tc.caFile
+			if _, err := NewCrawler(cfg); err == nil {
+				t.Error("Expected NewCrawler to fail")
+			}
+		})
+	}
+}
+
+func TestTLSFingerprint(t *testing.T) {
+	var mu sync.Mutex
+	var suites []uint16
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	srv.TLS = &tls.Config{GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		mu.Lock()
+		suites = hello.CipherSuites
+		mu.Unlock()
+		return nil, nil
+	}}
+	srv.StartTLS()
+	defer srv.Close()
+	caFile := writePEM(t, t.TempDir(), "ca.crt", "CERTIFICATE", srv.Certificate().Raw)
+
+	// greased reports whether the last ClientHello offered a GREASE
+	// cipher suite, as Chrome does and Go never does.
+	greased := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, s := range suites {
+			if s&0x0f0f == 0x0a0a {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, fp := range []string{"", "chrome", "firefox", "random"} {
+		cfg := testConfig(srv.URL)
+		cfg.CACertFile = caFile
+		cfg.TLSFingerprint = fp
+		c := newTestCrawler(t, cfg)
+		body, _, err := c.fetch(context.Background(), srv.URL)
+		if err != nil {
+			t.Errorf("%q: expected fetch to succeed, got %v", fp, err)
+			continue
+		}
+		if string(body) != "ok" {
+			t.Errorf("%q: expected body ok, got %q", fp, body)
+		}
+		if want := fp == "chrome"; greased() != want {
+			t.Errorf("%q: expected GREASE suites %v, got %v", fp, want, !want)
+		}
+	}
+}
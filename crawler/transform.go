@@ -0,0 +1,12 @@
+package crawler
+
+// BodyTransform rewrites a fetched body before links are extracted from
+// it, for content that is not plain HTML or JSON, such as a page wrapped
+// in a custom envelope.
+type BodyTransform func(body []byte) []byte
+
+// SetBodyTransform installs fn to run on every successfully fetched body.
+// A nil fn leaves bodies unchanged.
+func (c *Crawler) SetBodyTransform(fn BodyTransform) {
+	c.transform = fn
+}
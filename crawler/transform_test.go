@@ -0,0 +1,43 @@
+package crawler
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBodyTransformUnwrapsEnvelope(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString([]byte(`<a href="/inner">inner</a>`))))
+	}))
+	defer srv.Close()
+
+	c := newTestCrawler(t, testConfig(srv.URL))
+	body, contentType, err := c.fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if links := c.pageLinks(body, contentType, srv.URL); len(links) != 0 {
+		t.Fatalf("Expected no links in the raw envelope, got %v", links)
+	}
+
+	c = newTestCrawler(t, testConfig(srv.URL))
+	c.SetBodyTransform(func(body []byte) []byte {
+		decoded, err := base64.StdEncoding.DecodeString(string(body))
+		if err != nil {
+			return body
+		}
+		return decoded
+	})
+	body, contentType, err = c.fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := c.pageLinks(body, contentType, srv.URL)
+	if len(links) != 1 || links[0] != srv.URL+"/inner" {
+		t.Errorf("Expected the unwrapped link, got %v", links)
+	}
+}
@@ -0,0 +1,117 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/calpa/urusai/config"
+)
+
+// Per-phase defaults, matching http.DefaultTransport.
+const (
+	defaultDialTimeout           = 30 * time.Second
+	defaultTLSHandshakeTimeout   = 10 * time.Second
+	defaultExpectContinueTimeout = 1 * time.Second
+)
+
+// newTransport builds the crawler's HTTP transport from cfg, starting from
+// a clone of http.DefaultTransport so proxy settings and connection
+// pooling behave exactly as before.
+func newTransport(cfg *config.Config) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	tc, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if tc != nil {
+		t.TLSClientConfig = tc
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   millisOr(cfg.DialTimeout, defaultDialTimeout),
+		KeepAlive: 30 * time.Second,
+	}
+	dial := dialFunc(dialer.DialContext)
+	if cfg.MaxConcurrentDNS > 0 {
+		dial = newDNSLimiter(cfg.MaxConcurrentDNS).dialer(dial)
+	}
+	if len(cfg.HostAliases) > 0 {
+		dial = aliasDialer(dial, cfg.HostAliases)
+	}
+	if cfg.DialNetwork != "" && cfg.DialNetwork != "tcp" {
+		dial = networkDialer(dial, cfg.DialNetwork)
+	}
+	t.DialContext = dial
+	if cfg.TLSFingerprint != "" {
+		if t.DialTLSContext, err = fingerprintDialer(dial, tc, cfg.TLSFingerprint); err != nil {
+			return nil, err
+		}
+		disableHTTP2(t)
+	}
+	t.TLSHandshakeTimeout = millisOr(cfg.TLSHandshakeTimeout, defaultTLSHandshakeTimeout)
+	t.ResponseHeaderTimeout = millisOr(cfg.ResponseHeaderTimeout, 0)
+	t.ExpectContinueTimeout = millisOr(cfg.ExpectContinueTimeout, defaultExpectContinueTimeout)
+
+	switch cfg.ForceHTTPVersion {
+	case "1.0":
+		// net/http always writes an HTTP/1.1 request line, so emulate
+		// 1.0 semantics: no HTTP/2 and one request per connection.
+		disableHTTP2(t)
+		t.DisableKeepAlives = true
+	case "1.1":
+		disableHTTP2(t)
+	case "2":
+		t.ForceAttemptHTTP2 = true
+	}
+	return t, nil
+}
+
+// dialFunc is the signature of http.Transport.DialContext.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// aliasDialer wraps dial so that connections to a host in aliases go to
+// its mapped IP instead, /etc/hosts style. Only the dialled address
+// changes: the Host header and TLS server name stay the original host.
+func aliasDialer(dial dialFunc, aliases map[string]string) dialFunc {
+	lower := make(map[string]string, len(aliases))
+	for host, ip := range aliases {
+		lower[strings.ToLower(host)] = ip
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err == nil {
+			if ip, ok := lower[strings.ToLower(host)]; ok {
+				addr = net.JoinHostPort(ip, port)
+			}
+		}
+		return dial(ctx, network, addr)
+	}
+}
+
+// networkDialer wraps dial to always use network, "tcp4" or "tcp6", in
+// place of the "tcp" the transport asks for.
+func networkDialer(dial dialFunc, network string) dialFunc {
+	return func(ctx context.Context, _, addr string) (net.Conn, error) {
+		return dial(ctx, network, addr)
+	}
+}
+
+// disableHTTP2 stops t from negotiating HTTP/2 via ALPN.
+func disableHTTP2(t *http.Transport) {
+	t.ForceAttemptHTTP2 = false
+	t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+}
+
+// millisOr converts a millisecond config value to a duration, falling
+// back to def when the value is unset.
+func millisOr(ms int, def time.Duration) time.Duration {
+	if ms <= 0 {
+		return def
+	}
+	return time.Duration(ms) * time.Millisecond
+}
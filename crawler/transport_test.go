@@ -0,0 +1,168 @@
+package crawler
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/calpa/urusai/config"
+)
+
+func mustTransport(t *testing.T, cfg *config.Config) *http.Transport {
+	t.Helper()
+	tr, err := newTransport(cfg)
+	if err != nil {
+		t.Fatalf("newTransport: %v", err)
+	}
+	return tr
+}
+
+func TestNewTransportDefaults(t *testing.T) {
+	tr := mustTransport(t, testConfig())
+	if tr.TLSHandshakeTimeout != defaultTLSHandshakeTimeout {
+		t.Errorf("Expected TLS handshake timeout %v, got %v", defaultTLSHandshakeTimeout, tr.TLSHandshakeTimeout)
+	}
+	if tr.ResponseHeaderTimeout != 0 {
+		t.Errorf("Expected no response header timeout, got %v", tr.ResponseHeaderTimeout)
+	}
+	if tr.ExpectContinueTimeout != defaultExpectContinueTimeout {
+		t.Errorf("Expected expect-continue timeout %v, got %v", defaultExpectContinueTimeout, tr.ExpectContinueTimeout)
+	}
+}
+
+func TestNewTransportOverrides(t *testing.T) {
+	cfg := testConfig()
+	cfg.TLSHandshakeTimeout = 1500
+	cfg.ResponseHeaderTimeout = 250
+	cfg.ExpectContinueTimeout = 100
+
+	tr := mustTransport(t, cfg)
+	if tr.TLSHandshakeTimeout != 1500*time.Millisecond {
+		t.Errorf("Expected TLS handshake timeout 1.5s, got %v", tr.TLSHandshakeTimeout)
+	}
+	if tr.ResponseHeaderTimeout != 250*time.Millisecond {
+		t.Errorf("Expected response header timeout 250ms, got %v", tr.ResponseHeaderTimeout)
+	}
+	if tr.ExpectContinueTimeout != 100*time.Millisecond {
+		t.Errorf("Expected expect-continue timeout 100ms, got %v", tr.ExpectContinueTimeout)
+	}
+}
+
+func TestResponseHeaderTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.ResponseHeaderTimeout = 20
+	c := newTestCrawler(t, cfg)
+
+	start := time.Now()
+	if _, _, err := c.fetch(context.Background(), srv.URL); err == nil {
+		t.Fatal("Expected response header timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("Expected fetch to give up early, took %v", elapsed)
+	}
+}
+
+func TestForceHTTPVersion(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+		if r.Close {
+			w.Header().Set("X-Close", "true")
+		}
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	testCases := []struct {
+		version   string
+		wantProto string
+		wantClose bool
+	}{
+		{"", "HTTP/2.0", false},
+		{"2", "HTTP/2.0", false},
+		{"1.1", "HTTP/1.1", false},
+		{"1.0", "HTTP/1.1", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.version, func(t *testing.T) {
+			cfg := testConfig(srv.URL)
+			cfg.ForceHTTPVersion = tc.version
+			tr := mustTransport(t, cfg)
+			tr.TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+			defer tr.CloseIdleConnections()
+
+			resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if got := resp.Header.Get("X-Proto"); got != tc.wantProto {
+				t.Errorf("Expected server to see %s, got %s", tc.wantProto, got)
+			}
+			if got := resp.Header.Get("X-Close") == "true"; got != tc.wantClose {
+				t.Errorf("Expected Connection: close = %v, got %v", tc.wantClose, got)
+			}
+		})
+	}
+}
+
+func TestHostAliases(t *testing.T) {
+	var gotHost, gotSNI string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost, gotSNI = r.Host, r.TLS.ServerName
+	}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	// The httptest certificate is valid for example.com, so the fetch
+	// only succeeds if verification and SNI use the aliased name.
+	cfg := testConfig()
+	cfg.CACertFile = writePEM(t, t.TempDir(), "ca.crt", "CERTIFICATE", srv.Certificate().Raw)
+	cfg.HostAliases = map[string]string{"Example.com": "127.0.0.1"}
+	c := newTestCrawler(t, cfg)
+
+	target := "https://example.com:" + port + "/"
+	if _, _, err := c.fetch(context.Background(), target); err != nil {
+		t.Fatalf("Expected the aliased fetch to succeed, got %v", err)
+	}
+	if gotHost != "example.com:"+port {
+		t.Errorf("Expected Host header example.com:%s, got %q", port, gotHost)
+	}
+	if gotSNI != "example.com" {
+		t.Errorf("Expected SNI example.com, got %q", gotSNI)
+	}
+}
+
+func TestDialNetwork(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	testCases := []struct {
+		network string
+		wantErr bool
+	}{
+		{"", false},
+		{"tcp4", false},
+		{"tcp6", true}, // the server only listens on 127.0.0.1
+	}
+	for _, tc := range testCases {
+		t.Run(tc.network, func(t *testing.T) {
+			cfg := testConfig(srv.URL)
+			cfg.DialNetwork = tc.network
+			c := newTestCrawler(t, cfg)
+			_, _, err := c.fetch(context.Background(), srv.URL)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package crawler
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxUABuild bounds the numbers {rand} expands to, in the range real
+// Chrome and Edge build and patch numbers fall in.
+const maxUABuild = 10000
+
+var uaPlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// expandUA fills the placeholders of a cfg.RandomizeUAVersion template:
+// {rand} becomes a number below maxUABuild and {min-max} one within that
+// inclusive range. Anything else is left as written; config validation
+// rejects it.
+func (c *Crawler) expandUA(ua string) string {
+	return uaPlaceholder.ReplaceAllStringFunc(ua, func(m string) string {
+		spec := m[1 : len(m)-1]
+		if spec == "rand" {
+			return strconv.Itoa(c.rand.Intn(maxUABuild))
+		}
+		lo, hi, _ := strings.Cut(spec, "-")
+		a, errA := strconv.Atoi(lo)
+		b, errB := strconv.Atoi(hi)
+		if errA != nil || errB != nil || b < a {
+			return m
+		}
+		return strconv.Itoa(a + c.rand.Intn(b-a+1))
+	})
+}
@@ -0,0 +1,34 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/url"
+)
+
+// fetchUpgraded requests an http:// URL over https first, as a browser
+// holding an HSTS entry would, for cfg.UpgradeToHTTPS. A host that fails
+// the upgrade with a transport error is fetched over http from then on;
+// one that has answered over https is never downgraded. Either outcome
+// is remembered, so each host is probed once.
+func (c *Crawler) fetchUpgraded(ctx context.Context, u *url.URL) ([]byte, string, error) {
+	if c.plain.has(u.Host) {
+		return c.request(ctx, u.String())
+	}
+	secure := *u
+	secure.Scheme = "https"
+	body, contentType, err := c.request(ctx, secure.String())
+	// An error status still proves the host speaks https.
+	var fe *FetchError
+	if err == nil || (errors.As(err, &fe) && fe.StatusCode != 0) {
+		c.secure.add(u.Host)
+		return body, contentType, err
+	}
+	if ctx.Err() != nil || c.secure.has(u.Host) {
+		return body, contentType, err
+	}
+	if c.plain.add(u.Host) {
+		c.debugf("https upgrade of %s failed, using http from now on: %v", u.Host, err)
+	}
+	return c.request(ctx, u.String())
+}
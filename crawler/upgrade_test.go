@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUpgradeToHTTPS(t *testing.T) {
+	var plainConns int32
+	plain := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	plain.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&plainConns, 1)
+		}
+	}
+	plain.Start()
+	defer plain.Close()
+	var sawTLS atomic.Bool
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sawTLS.Store(r.TLS != nil)
+	}))
+	defer secure.Close()
+
+	cfg := testConfig(plain.URL)
+	cfg.UpgradeToHTTPS = true
+	c := newTestCrawler(t, cfg)
+	c.client = secure.Client()
+
+	// secure.URL is https://; its host is asked for over http.
+	upgraded := "http://" + secure.Listener.Addr().String() + "/"
+	if _, _, err := c.fetch(context.Background(), upgraded); err != nil {
+		t.Fatalf("Expected the upgraded fetch to succeed, got %v", err)
+	}
+	if !sawTLS.Load() {
+		t.Error("Expected the https-capable host to be fetched over TLS")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := c.fetch(context.Background(), plain.URL+"/"); err != nil {
+			t.Fatalf("Expected the http-only host to fall back to http, got %v", err)
+		}
+	}
+	// One failed probe, then a single kept-alive http connection: the
+	// second fetch did not probe again.
+	if got := atomic.LoadInt32(&plainConns); got != 2 {
+		t.Errorf("Expected 2 connections to the http-only host, got %d", got)
+	}
+	host := plain.Listener.Addr().String()
+	if !c.plain.has(host) || c.secure.has(host) {
+		t.Errorf("Expected %s to be cached as http-only", host)
+	}
+}
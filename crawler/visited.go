@@ -0,0 +1,96 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/calpa/urusai/config"
+)
+
+// VisitedSet records the URLs a crawler has claimed. Implementations must
+// be safe for concurrent use; a shared backend lets a fleet of crawlers
+// avoid re-crawling each other's URLs.
+type VisitedSet interface {
+	// Add records url, reporting whether it was not already present.
+	Add(ctx context.Context, url string) (bool, error)
+	// Has reports whether url has been added.
+	Has(ctx context.Context, url string) (bool, error)
+}
+
+// memorySet is the default, exact in-process VisitedSet.
+type memorySet struct {
+	mu sync.Mutex
+	m  map[string]struct{}
+}
+
+func newMemorySet() *memorySet {
+	return &memorySet{m: make(map[string]struct{})}
+}
+
+func (s *memorySet) Add(_ context.Context, url string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.m[url]; ok {
+		return false, nil
+	}
+	s.m[url] = struct{}{}
+	return true, nil
+}
+
+func (s *memorySet) Has(_ context.Context, url string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.m[url]
+	return ok, nil
+}
+
+// newVisitedSet returns the VisitedSet selected by cfg.VisitedBackend.
+func newVisitedSet(cfg *config.Config) (VisitedSet, error) {
+	switch cfg.VisitedBackend {
+	case "", "memory":
+		if f := cfg.VisitedFilter; f != nil {
+			return newBloomSet(f.ExpectedItems, f.FalsePositiveRate), nil
+		}
+		return newMemorySet(), nil
+	case "redis":
+		if cfg.Redis == nil || cfg.Redis.Addr == "" {
+			return nil, fmt.Errorf("visited_backend redis: redis.addr is required")
+		}
+		return newRedisSet(cfg.Redis), nil
+	default:
+		return nil, fmt.Errorf("unknown visited_backend %q", cfg.VisitedBackend)
+	}
+}
+
+// markVisited claims link, by its dedupKey, reporting whether this
+// crawler should fetch it. When the backend fails the link is fetched
+// anyway: a repeated request is cheaper than a stalled crawl.
+func (c *Crawler) markVisited(ctx context.Context, link string) bool {
+	added, err := c.visited.Add(ctx, c.dedupKey(link))
+	if err != nil {
+		log.Printf("visited set: %v", err)
+		added = true
+	}
+	if added {
+		c.claimed.Add(1)
+	}
+	return added
+}
+
+// isVisited reports whether link has been claimed, treating backend
+// failures as not visited.
+func (c *Crawler) isVisited(ctx context.Context, link string) bool {
+	seen, err := c.visited.Has(ctx, c.dedupKey(link))
+	if err != nil {
+		c.debugf("visited set: %v", err)
+		return false
+	}
+	return seen
+}
+
+// visitedCount returns how many URLs this crawler has claimed.
+func (c *Crawler) visitedCount() int {
+	return int(c.claimed.Load())
+}
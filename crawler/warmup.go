@@ -0,0 +1,63 @@
+package crawler
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// warmup primes the connection pool with one HEAD request per distinct
+// root host, so that the measured part of the crawl starts on warm
+// connections. Warmup requests are not counted in Stats. It stops early
+// when ctx ends.
+func (c *Crawler) warmup(ctx context.Context) {
+	start := time.Now()
+	seen := make(map[string]struct{})
+	for _, root := range c.roots {
+		if ctx.Err() != nil {
+			return
+		}
+		target := c.rewrite(root)
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		origin := u.Scheme + "://" + u.Host
+		if _, ok := seen[origin]; ok {
+			continue
+		}
+		seen[origin] = struct{}{}
+
+		req, err := c.sideRequest(ctx, http.MethodHead, target)
+		if err != nil {
+			continue
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			c.debugf("warmup %s: %v", origin, err)
+			continue
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	log.Printf("warmup: primed connections to %d hosts in %v", len(seen), time.Since(start).Round(time.Millisecond))
+}
+
+// sideRequest builds a request outside the crawl proper, such as a
+// warmup or a root probe, dressed like a page request but not counted.
+func (c *Crawler) sideRequest(ctx context.Context, method, target string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.userAgent())
+	c.applyBrowserHeaders(req)
+	c.applyHostOverrides(req)
+	if c.cfg.RandomizeHeaderCase {
+		c.recaseHeaders(req.Header)
+	}
+	return req, nil
+}
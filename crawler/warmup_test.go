@@ -0,0 +1,91 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// methodLog records the method of every request a server receives.
+type methodLog struct {
+	mu      sync.Mutex
+	methods []string
+}
+
+func (l *methodLog) server(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.mu.Lock()
+		l.methods = append(l.methods, r.Method)
+		l.mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (l *methodLog) heads() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, m := range l.methods {
+		if m == http.MethodHead {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWarmupHitsEachRootHostOnce(t *testing.T) {
+	var a, b methodLog
+	srvA, srvB := a.server(t), b.server(t)
+
+	cfg := testConfig(srvA.URL+"/x", srvA.URL+"/y", srvB.URL+"/")
+	cfg.WarmupConnections = true
+	c := newTestCrawler(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.Crawl(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, l := range map[string]*methodLog{"A": &a, "B": &b} {
+		if got := l.heads(); got != 1 {
+			t.Errorf("host %s: expected 1 warmup HEAD, got %d", name, got)
+		}
+		l.mu.Lock()
+		if len(l.methods) == 0 || l.methods[0] != http.MethodHead {
+			t.Errorf("host %s: expected the warmup HEAD before any GET, got %v", name, l.methods)
+		}
+		l.mu.Unlock()
+	}
+}
+
+func TestWarmupIsNotCounted(t *testing.T) {
+	var a methodLog
+	srv := a.server(t)
+
+	c := newTestCrawler(t, testConfig(srv.URL+"/a", srv.URL+"/b"))
+	c.warmup(context.Background())
+	if got := a.heads(); got != 1 {
+		t.Errorf("Expected 1 warmup HEAD for a single host, got %d", got)
+	}
+	if got := c.stats.Requests; got != 0 {
+		t.Errorf("Expected warmup requests to be left out of Stats, got %d", got)
+	}
+}
+
+func TestWarmupRespectsContext(t *testing.T) {
+	var a methodLog
+	srv := a.server(t)
+
+	c := newTestCrawler(t, testConfig(srv.URL))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.warmup(ctx)
+	if got := a.heads(); got != 0 {
+		t.Errorf("Expected no warmup after ctx ended, got %d HEAD requests", got)
+	}
+}
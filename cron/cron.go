@@ -0,0 +1,150 @@
+// Package cron parses cron expressions and computes when they next fire.
+// It understands the classic five fields (minute, hour, day of month,
+// month, day of week) with an optional leading seconds field, and the
+// usual *, lists, ranges and /steps. Names such as MON or JAN and
+// shortcuts such as @daily are not supported.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	second, minute, hour, dom, month, dow uint64 // bit n set when value n matches
+
+	// domAny and dowAny record an unrestricted field: when both day
+	// fields are restricted, a day matching either one fires, as in cron.
+	domAny, dowAny bool
+}
+
+type field struct {
+	name     string
+	min, max int
+}
+
+var (
+	secondField = field{"second", 0, 59}
+	minuteField = field{"minute", 0, 59}
+	hourField   = field{"hour", 0, 23}
+	domField    = field{"day of month", 1, 31}
+	monthField  = field{"month", 1, 12}
+	dowField    = field{"day of week", 0, 7} // 0 and 7 are both Sunday
+)
+
+// Parse reads a cron expression of five fields, or six with seconds
+// first, such as "0 9 * * 1-5" for 09:00 on weekdays.
+func Parse(expr string) (*Schedule, error) {
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron %q: want 5 or 6 fields, got %d", expr, len(fields))
+	}
+	s := &Schedule{domAny: fields[3] == "*", dowAny: fields[5] == "*"}
+	var err error
+	for i, dst := range []struct {
+		f    field
+		bits *uint64
+	}{
+		{secondField, &s.second},
+		{minuteField, &s.minute},
+		{hourField, &s.hour},
+		{domField, &s.dom},
+		{monthField, &s.month},
+		{dowField, &s.dow},
+	} {
+		if *dst.bits, err = parseField(fields[i], dst.f); err != nil {
+			return nil, fmt.Errorf("cron %q: %w", expr, err)
+		}
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+// parseField turns one comma-separated field into a bit set.
+func parseField(spec string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(spec, ",") {
+		lo, hi, step := f.min, f.max, 1
+		rng := part
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s: bad step in %q", f.name, part)
+			}
+			rng, step = part[:i], n
+		}
+		if rng != "*" {
+			var err error
+			if lo, err = strconv.Atoi(rng); err == nil {
+				hi = lo
+				if strings.Contains(part, "/") {
+					hi = f.max // "5/15" runs from 5 to the end
+				}
+			} else if a, b, ok := strings.Cut(rng, "-"); ok {
+				lo, err = strconv.Atoi(a)
+				if err == nil {
+					hi, err = strconv.Atoi(b)
+				}
+			}
+			if err != nil {
+				return 0, fmt.Errorf("%s: cannot parse %q", f.name, part)
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("%s: %q is outside %d-%d", f.name, part, f.min, f.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// maxSearch bounds how far ahead Next looks, so an expression that can
+// never fire, such as "0 0 30 2 *", does not search forever.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Next returns the first time after t that s fires, in t's location,
+// or the zero time when it never does.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Second).Add(time.Second)
+	limit := t.Add(maxSearch)
+	for t.Before(limit) {
+		switch {
+		case !has(s.month, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !has(s.hour, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !has(s.minute, t.Minute()):
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		case !has(s.second, t.Second()):
+			t = t.Add(time.Second)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom, dow := has(s.dom, t.Day()), has(s.dow, int(t.Weekday()))
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<v) != 0
+}
@@ -0,0 +1,46 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// 2024-05-06 is a Monday.
+	from := time.Date(2024, 5, 6, 10, 30, 0, 0, time.UTC)
+	testCases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 5, 6, 10, 31, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2024, 5, 7, 9, 0, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2024, 5, 6, 10, 40, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1,15 * *", time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"30 30 10 * * *", time.Date(2024, 5, 6, 10, 30, 30, 0, time.UTC)},
+		{"15/20 * * * * *", time.Date(2024, 5, 6, 10, 30, 15, 0, time.UTC)},
+		// Both day fields restricted: either one matching fires.
+		{"0 0 1 * 3", time.Date(2024, 5, 8, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tc := range testCases {
+		s, err := Parse(tc.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.expr, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(tc.want) {
+			t.Errorf("Next(%q) = %v, expected %v", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestParseRejectsBadExpressions(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "* * * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "MON * * * *"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Expected an error for %q", expr)
+		}
+	}
+}
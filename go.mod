@@ -4,4 +4,20 @@ go 1.23.0
 
 toolchain go1.24.2
 
-require golang.org/x/net v0.41.0 // indirect
+require (
+	github.com/refraction-networking/utls v1.6.7
+	golang.org/x/net v0.41.0
+	golang.org/x/text v0.26.0
+	google.golang.org/grpc v1.73.1
+	google.golang.org/protobuf v1.36.6
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/andybalholm/brotli v1.0.6 // indirect
+	github.com/cloudflare/circl v1.3.7 // indirect
+	github.com/klauspost/compress v1.17.4 // indirect
+	golang.org/x/crypto v0.39.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250324211829-b45e905df463 // indirect
+)
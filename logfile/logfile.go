@@ -0,0 +1,172 @@
+// Package logfile provides an io.WriteCloser that appends to a file and
+// rotates it by size, keeping a bounded number of timestamped backups.
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is embedded in rotated file names, e.g.
+// urusai-2024-05-01T10-04-05.000.log.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// DefaultMaxSizeMB is used when Options.MaxSizeMB is unset.
+const DefaultMaxSizeMB = 100
+
+// Options controls rotation. MaxBackups and MaxAgeDays of 0 keep every
+// backup.
+type Options struct {
+	MaxSizeMB  int // rotate once the file would exceed this size
+	MaxBackups int // rotated files to keep
+	MaxAgeDays int // delete rotated files older than this
+}
+
+// Writer is a size-rotated log file, safe for concurrent use.
+type Writer struct {
+	path string
+	opts Options
+	now  func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// New opens path for appending, creating it and its directory if needed.
+func New(path string, opts Options) (*Writer, error) {
+	if opts.MaxSizeMB <= 0 {
+		opts.MaxSizeMB = DefaultMaxSizeMB
+	}
+	w := &Writer{path: path, opts: opts, now: time.Now}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.size = f, info.Size()
+	return nil
+}
+
+func (w *Writer) maxBytes() int64 {
+	return int64(w.opts.MaxSizeMB) * 1024 * 1024
+}
+
+// Write appends p, rotating first when p would push the file past the
+// size limit. A single write larger than the limit is still written.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if w.size > 0 && w.size+int64(len(p)) > w.maxBytes() {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close flushes and closes the current file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		w.file.Close()
+		w.file = nil
+		return err
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// rotate renames the current file to a timestamped backup, opens a fresh
+// one and prunes old backups. The caller must hold w.mu.
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	ext := filepath.Ext(w.path)
+	backup := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(w.path, ext), w.now().Format(backupTimeFormat), ext)
+	if err := os.Rename(w.path, backup); err != nil {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	return w.prune()
+}
+
+// prune removes backups beyond MaxBackups or older than MaxAgeDays.
+func (w *Writer) prune() error {
+	ext := filepath.Ext(w.path)
+	prefix := filepath.Base(strings.TrimSuffix(w.path, ext)) + "-"
+	dir := filepath.Dir(w.path)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	type backup struct {
+		name string
+		at   time.Time
+	}
+	var backups []backup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		at, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{name, at})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].at.After(backups[j].at) })
+
+	var cutoff time.Time
+	if w.opts.MaxAgeDays > 0 {
+		cutoff = w.now().AddDate(0, 0, -w.opts.MaxAgeDays)
+	}
+	for i, b := range backups {
+		tooMany := w.opts.MaxBackups > 0 && i >= w.opts.MaxBackups
+		tooOld := b.at.Before(cutoff)
+		if tooMany || tooOld {
+			if err := os.Remove(filepath.Join(dir, b.name)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestWriter returns a writer whose clock advances one second per call
+// so backup names never collide.
+func newTestWriter(t *testing.T, opts Options) (*Writer, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "logs", "urusai.log")
+	w, err := New(path, opts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	clock := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	w.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	t.Cleanup(func() { w.Close() })
+	return w, path
+}
+
+func backups(t *testing.T, path string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), "urusai-*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+func TestWriterRotatesBySize(t *testing.T) {
+	w, path := newTestWriter(t, Options{MaxSizeMB: 1})
+	chunk := []byte(strings.Repeat("x", 600*1024))
+
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := len(backups(t, path)); got != 2 {
+		t.Errorf("Expected 2 rotated files, got %d", got)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(chunk)) {
+		t.Errorf("Expected current file to hold one chunk, got %d bytes", info.Size())
+	}
+}
+
+func TestWriterPrunesBackups(t *testing.T) {
+	w, path := newTestWriter(t, Options{MaxSizeMB: 1, MaxBackups: 2})
+	chunk := []byte(strings.Repeat("x", 700*1024))
+
+	for i := 0; i < 5; i++ {
+		if _, err := w.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := backups(t, path)
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 backups to be kept, got %v", got)
+	}
+	if !strings.Contains(got[1], "10-00-04") {
+		t.Errorf("Expected the newest backups to survive, got %v", got)
+	}
+}
+
+func TestWriterAppendsAndCloses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urusai.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := New(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("new\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("late\n")); err == nil {
+		t.Error("Expected write after Close to fail")
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "old\nnew\n" {
+		t.Errorf("Expected appended content, got %q", data)
+	}
+}
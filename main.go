@@ -2,16 +2,25 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"log"
+	"net"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
 	"time"
 
+	"google.golang.org/grpc"
+
+	"github.com/calpa/urusai/api"
 	"github.com/calpa/urusai/config"
 	"github.com/calpa/urusai/crawler"
+	"github.com/calpa/urusai/cron"
+	"github.com/calpa/urusai/logfile"
 )
 
 var (
@@ -19,17 +28,51 @@ var (
 	version = "dev"
 )
 
+// Process exit codes, so that monitoring can tell failure modes apart.
+// They are listed in the README; keep both in sync.
+const (
+	exitOK            = 0 // the crawl ended cleanly, e.g. on exit_on_drain or a signal
+	exitFailure       = 1 // any other failure
+	exitConfigInvalid = 2 // the config could not be loaded or is invalid
+	exitTooManyErrors = 3 // max_errors was exceeded
+	exitIdleTimeout   = 4 // idle_timeout passed without a successful fetch
+	exitNoLiveRoots   = 5 // probe_roots_fail_fast found no reachable root
+	exitByteLimit     = 6 // max_total_bytes was reached
+	exitTimeout       = 7 // timeout or stop_at ended the crawl
+)
+
+// errInvalidConfig marks failures caused by the config, such as an
+// unreadable cookie_file, that only show once it is put to use.
+var errInvalidConfig = errors.New("invalid config")
+
 func main() {
+	os.Exit(run())
+}
+
+// run is the whole program. It returns the exit code instead of exiting
+// itself, so that deferred clean-up such as syncing the log file runs.
+func run() int {
 	// ───────────────────── flags ─────────────────────
-	cfgPath := flag.String("config", "", "path to JSON/YAML config file (optional)")
+	cfgPath := flag.String("config", "", "path or http(s) URL of a JSON config file (optional)")
+	profile := flag.String("profile", "", "named profile from the config file's \"profiles\" section")
 	logLevel := flag.String("log", "info", "log level: debug|info|warn|error")
 	showVer := flag.Bool("version", false, "print version and exit")
+	logFile := flag.String("log-file", "", "also write logs to this size-rotated file (overrides log_file)")
 	timeout := flag.Duration("timeout", 0, "overall run timeout (e.g. 30s, 2m). 0 = no timeout")
+	stopAt := flag.String("stop-at", "", "stop at this RFC3339 time (e.g. 2024-05-06T18:00:00Z), overrides stop_at")
+	harPath := flag.String("seed-from-har", "", "replay the GET requests recorded in this HAR file, with their original timing, instead of crawling")
+	replayScale := flag.Float64("replay-scale", 1, "multiply recorded HAR delays by this factor (0.5 = twice as fast, 0 = no pauses)")
+	openAPIPath := flag.String("seed-from-openapi", "", "crawl the GET endpoints of this OpenAPI/Swagger JSON or YAML spec instead of root_urls")
+	openAPIBase := flag.String("openapi-base", "", "base URL for --seed-from-openapi paths (default: the spec's own server)")
+	recordPath := flag.String("record", "", "write every request and response to this file for later --replay")
+	replayPath := flag.String("replay", "", "answer requests from a file written by --record instead of the network")
+	grpcAddr := flag.String("grpc-addr", "", "serve the gRPC crawl API on this host:port instead of crawling (overrides grpc_addr)")
+	validateOnly := flag.Bool("validate-only", false, "load and validate the config, report any issues and exit")
 	flag.Parse()
 
 	if *showVer {
 		log.Printf("urusai %s", version)
-		return
+		return exitOK
 	}
 
 	setLogLevel(*logLevel)
@@ -40,38 +83,305 @@ func main() {
 		err error
 	)
 
+	// flagOverrides applies the flags that override config settings. It
+	// runs before validation, so --timeout satisfies schedule.
+	flagOverrides := func(cfg *config.Config) {
+		if *timeout > 0 {
+			cfg.Timeout = int(timeout.Seconds()) // keep legacy seconds field for crawler
+		}
+		if *stopAt != "" {
+			cfg.StopAt = *stopAt
+		}
+	}
+
 	switch {
+	case *cfgPath == "" && *profile != "":
+		log.Printf("ERROR: --profile requires --config")
+		return exitConfigInvalid
 	case *cfgPath == "":
 		log.Printf("INFO: %s using default config", time.Now().Format("2006/01/02 15:04:05"))
 		cfg, err = config.LoadDefaultConfig()
+		if err == nil {
+			flagOverrides(cfg)
+		}
 	default:
-		cfg, err = config.LoadFromFile(*cfgPath)
+		cfg, err = config.NewProvider(*cfgPath, *profile, flagOverrides).Load(context.Background())
+	}
+	if *validateOnly {
+		return reportValidation(os.Stdout, cfg, err)
 	}
 	if err != nil {
-		log.Fatalf("ERROR: could not load config: %v", err)
+		log.Printf("ERROR: could not load config: %v", err)
+		return exitConfigInvalid
+	}
+
+	if *openAPIPath != "" {
+		roots, err := crawler.LoadOpenAPI(*openAPIPath, *openAPIBase)
+		if err != nil {
+			log.Printf("ERROR: could not load OpenAPI spec: %v", err)
+			return exitFailure
+		}
+		if len(roots) == 0 {
+			log.Printf("ERROR: OpenAPI spec %s has no GET operations", *openAPIPath)
+			return exitFailure
+		}
+		log.Printf("INFO: %s seeding %d API endpoints from %s", time.Now().Format("2006/01/02 15:04:05"), len(roots), *openAPIPath)
+		cfg.RootURLs = roots
 	}
 
-	if *timeout > 0 {
-		cfg.Timeout = int(timeout.Seconds()) // keep legacy seconds field for crawler
+	if *logFile != "" {
+		cfg.LogFile = *logFile
+	}
+	if cfg.LogFile != "" {
+		lf, err := logfile.New(cfg.LogFile, logfile.Options{
+			MaxSizeMB:  cfg.LogMaxSize,
+			MaxBackups: cfg.LogMaxBackups,
+			MaxAgeDays: cfg.LogMaxAge,
+		})
+		if err != nil {
+			log.Printf("ERROR: could not open log file: %v", err)
+			return exitFailure
+		}
+		defer lf.Close()
+		log.SetOutput(io.MultiWriter(os.Stderr, lf))
 	}
 
 	// ─────────────────── crawler init ────────────────
-	c := crawler.NewCrawler(cfg)
+	var (
+		replayer  *crawler.Replayer
+		recording *os.File
+	)
+	if cfg.RenderJS && (*replayPath != "" || *recordPath != "") {
+		// Chrome's own fetches would be neither recorded nor replayed.
+		log.Printf("ERROR: render_js cannot be combined with --record or --replay")
+		return exitConfigInvalid
+	}
+	if *replayPath != "" {
+		f, err := os.Open(*replayPath)
+		if err != nil {
+			log.Printf("ERROR: could not open recording: %v", err)
+			return exitFailure
+		}
+		replayer, err = crawler.NewReplayer(f)
+		f.Close()
+		if err != nil {
+			log.Printf("ERROR: could not load recording: %v", err)
+			return exitFailure
+		}
+	}
+	if *recordPath != "" {
+		recording, err = os.OpenFile(*recordPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+		if err != nil {
+			log.Printf("ERROR: could not create recording: %v", err)
+			return exitFailure
+		}
+		defer recording.Close()
+	}
+	// setup applies the command-line settings that are not part of the
+	// config to a new crawler.
+	setup := func(c *crawler.Crawler) {
+		c.SetDebug(strings.EqualFold(*logLevel, "debug"))
+		if replayer != nil {
+			c.SetTransport(replayer)
+		}
+		if recording != nil {
+			c.SetTransport(crawler.NewRecorder(c.Transport(), recording))
+		}
+	}
 
 	// ctx cancels on SIGINT/SIGTERM and optional timeout
 	baseCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	ctx := baseCtx
-	if *timeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(baseCtx, *timeout)
+	if *grpcAddr != "" {
+		cfg.GRPCAddr = *grpcAddr
+	}
+	if cfg.GRPCAddr != "" {
+		log.Printf("INFO: %s serving the gRPC crawl API on %s ✈️", time.Now().Format("2006/01/02 15:04:05"), cfg.GRPCAddr)
+		if err := serveGRPC(baseCtx, cfg.GRPCAddr, api.NewServer(cfg, setup)); err != nil {
+			log.Printf("ERROR: gRPC server: %v", err)
+			return exitFailure
+		}
+		return exitOK
+	}
+
+	// newCrawler is called once per scheduled run, so each starts fresh.
+	// It fails only on settings that could not be put to use.
+	newCrawler := func() (*crawler.Crawler, error) {
+		c, err := crawler.NewCrawler(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidConfig, err)
+		}
+		setup(c)
+		return c, nil
+	}
+	c, err := newCrawler()
+	if err != nil {
+		log.Printf("ERROR: could not initialise crawler: %v", err)
+		return exitConfigInvalid
+	}
+
+	// withTimeout bounds one crawl or replay by --timeout.
+	withTimeout := func(ctx context.Context) (context.Context, context.CancelFunc) {
+		if *timeout > 0 {
+			return context.WithTimeout(ctx, *timeout)
+		}
+		return context.WithCancel(ctx)
+	}
+
+	if *harPath != "" {
+		steps, err := crawler.LoadHAR(*harPath)
+		if err != nil {
+			log.Printf("ERROR: could not load HAR: %v", err)
+			return exitFailure
+		}
+		log.Printf("INFO: %s replaying %d requests from %s", time.Now().Format("2006/01/02 15:04:05"), len(steps), *harPath)
+		ctx, cancel := withTimeout(baseCtx)
 		defer cancel()
+		if err := c.Replay(ctx, steps, *replayScale); err != nil {
+			log.Printf("ERROR: replay aborted: %v", err)
+			return exitCode(err)
+		}
+		return exitOK
+	}
+
+	if cfg.Schedule != "" {
+		sched, err := cron.Parse(cfg.Schedule)
+		if err != nil {
+			log.Printf("ERROR: %v", err)
+			return exitConfigInvalid
+		}
+		log.Printf("INFO: %s urusai waiting for schedule %q ✈️", time.Now().Format("2006/01/02 15:04:05"), cfg.Schedule)
+		next := c
+		// The schedule runs until interrupted; --timeout bounds each run.
+		return exitCode(runScheduled(baseCtx, sched, func(ctx context.Context) error {
+			if next == nil {
+				var err error
+				if next, err = newCrawler(); err != nil {
+					return err
+				}
+			}
+			run := next
+			next = nil
+			ctx, cancel := withTimeout(ctx)
+			defer cancel()
+			return run.Crawl(ctx)
+		}))
 	}
 
 	log.Printf("INFO: %s starting urusai traffic generator ✈️", time.Now().Format("2006/01/02 15:04:05"))
 
-	c.Crawl(ctx)
+	ctx, cancel := withTimeout(baseCtx)
+	defer cancel()
+	err = c.Crawl(ctx)
+	if err == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// --timeout also bounds ctx, which may expire just before the
+		// crawl notices cfg.Timeout.
+		err = fmt.Errorf("%w: --timeout %v", crawler.ErrTimeLimit, *timeout)
+	}
+	switch {
+	case errors.Is(err, crawler.ErrTimeLimit):
+		log.Printf("INFO: %s crawl finished: %v", time.Now().Format("2006/01/02 15:04:05"), err)
+	case err != nil:
+		log.Printf("ERROR: crawl aborted: %v", err)
+	}
+	return exitCode(err)
+}
+
+// exitCode maps the error that ended a crawl to the process exit code.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errInvalidConfig):
+		return exitConfigInvalid
+	case errors.Is(err, crawler.ErrTimeLimit):
+		return exitTimeout
+	case errors.Is(err, crawler.ErrTooManyErrors):
+		return exitTooManyErrors
+	case errors.Is(err, crawler.ErrIdleTimeout):
+		return exitIdleTimeout
+	case errors.Is(err, crawler.ErrNoLiveRoots):
+		return exitNoLiveRoots
+	case errors.Is(err, crawler.ErrByteLimit):
+		return exitByteLimit
+	default:
+		return exitFailure
+	}
+}
+
+// serveGRPC serves srv on addr until ctx is done, which also ends the
+// crawls of open calls.
+func serveGRPC(ctx context.Context, addr string, srv *api.Server) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	gs := grpc.NewServer()
+	api.RegisterCrawlerServer(gs, srv)
+	go func() {
+		<-ctx.Done()
+		gs.Stop()
+	}()
+	return gs.Serve(lis)
+}
+
+// runScheduled runs crawl each time sched fires until ctx is done. Each
+// crawl gets ctx and ends on its own limits; a failing crawl is logged
+// and the schedule carries on. Firings that come while a crawl is still
+// running are skipped. It returns the error of the last crawl, if it
+// failed; reaching the timeout that bounds every scheduled crawl is not
+// a failure.
+func runScheduled(ctx context.Context, sched interface{ Next(time.Time) time.Time }, crawl func(context.Context) error) error {
+	var last error
+	for {
+		next := sched.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("WARNING: schedule never fires again, exiting")
+			return last
+		}
+		t := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return last
+		case <-t.C:
+		}
+		log.Printf("INFO: %s scheduled crawl starting", time.Now().Format("2006/01/02 15:04:05"))
+		last = crawl(ctx)
+		if errors.Is(last, crawler.ErrTimeLimit) {
+			last = nil
+		}
+		if last != nil {
+			log.Printf("ERROR: scheduled crawl aborted: %v", last)
+		}
+		if ctx.Err() != nil {
+			return last
+		}
+	}
+}
+
+// reportValidation prints the outcome of --validate-only to w, one line
+// per issue, and returns the process exit code: exitOK or
+// exitConfigInvalid. loadErr is the error
+// returned while loading cfg.
+func reportValidation(w io.Writer, cfg *config.Config, loadErr error) int {
+	err := loadErr
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err == nil {
+		fmt.Fprintln(w, "config OK")
+		return exitOK
+	}
+	issues := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		issues = joined.Unwrap()
+	}
+	for _, issue := range issues {
+		fmt.Fprintf(w, "invalid config: %v\n", issue)
+	}
+	return exitConfigInvalid
 }
 
 // setLogLevel tweaks the global logger to the requested verbosity.
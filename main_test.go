@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/calpa/urusai/config"
+	"github.com/calpa/urusai/crawler"
 )
 
 // TestFlagParsing tests the command line flag parsing functionality
@@ -145,3 +150,108 @@ func TestSignalHandling(t *testing.T) {
 	// This should return almost immediately due to the signal
 	<-sigChan
 }
+
+// TestReportValidation tests the --validate-only report and exit code
+func TestReportValidation(t *testing.T) {
+	cfg, err := config.LoadDefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if code := reportValidation(&out, cfg, nil); code != 0 {
+		t.Errorf("Expected exit code 0 for the default config, got %d (%s)", code, out.String())
+	}
+
+	bad := *cfg
+	bad.RootURLs = nil
+	bad.MaxDepth = -1
+	out.Reset()
+	if code := reportValidation(&out, &bad, nil); code != exitConfigInvalid {
+		t.Errorf("Expected exit code %d for an invalid config, got %d", exitConfigInvalid, code)
+	}
+	if lines := strings.Count(out.String(), "invalid config:"); lines != 2 {
+		t.Errorf("Expected 2 reported issues, got %d:\n%s", lines, out.String())
+	}
+
+	out.Reset()
+	if code := reportValidation(&out, nil, errors.New("unexpected EOF")); code != exitConfigInvalid || !strings.Contains(out.String(), "unexpected EOF") {
+		t.Errorf("Expected the load error to be reported with exit code %d, got %d: %s", exitConfigInvalid, code, out.String())
+	}
+}
+
+// everyTick fires at a fixed interval, standing in for a cron schedule
+type everyTick time.Duration
+
+func (e everyTick) Next(t time.Time) time.Time { return t.Add(time.Duration(e)) }
+
+// TestRunScheduled tests that a crawl starts on every tick until ctx ends
+func TestRunScheduled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var starts []time.Time
+	begin := time.Now()
+	err := runScheduled(ctx, everyTick(20*time.Millisecond), func(ctx context.Context) error {
+		starts = append(starts, time.Now())
+		if len(starts) == 3 {
+			cancel()
+			return fmt.Errorf("%w: timeout is 60s", crawler.ErrTimeLimit)
+		}
+		return errors.New("a failed crawl does not stop the schedule")
+	})
+
+	if err != nil {
+		t.Errorf("Expected no error when the last crawl ran to its timeout, got %v", err)
+	}
+	if len(starts) != 3 {
+		t.Fatalf("Expected 3 scheduled crawls, got %d", len(starts))
+	}
+	if first := starts[0].Sub(begin); first < 20*time.Millisecond {
+		t.Errorf("Expected the first crawl to wait for a tick, started after %v", first)
+	}
+}
+
+// TestExitCode tests the mapping from crawl outcome to exit code
+func TestExitCode(t *testing.T) {
+	testCases := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{fmt.Errorf("%w: 11 failed, max_errors is 10", crawler.ErrTooManyErrors), exitTooManyErrors},
+		{fmt.Errorf("%w: idle_timeout is 5000ms", crawler.ErrIdleTimeout), exitIdleTimeout},
+		{fmt.Errorf("%w: 2 roots failed", crawler.ErrNoLiveRoots), exitNoLiveRoots},
+		{fmt.Errorf("%w: 1024 bytes fetched", crawler.ErrByteLimit), exitByteLimit},
+		{fmt.Errorf("%w: timeout is 60s", crawler.ErrTimeLimit), exitTimeout},
+		{errors.New("something else"), exitFailure},
+	}
+	seen := make(map[int]bool)
+	for _, tc := range testCases {
+		got := exitCode(tc.err)
+		if got != tc.want {
+			t.Errorf("exitCode(%v) = %d, expected %d", tc.err, got, tc.want)
+		}
+		seen[got] = true
+	}
+	if len(seen) != len(testCases) || seen[exitConfigInvalid] {
+		t.Errorf("Expected distinct exit codes, apart from the config one, got %v", seen)
+	}
+	if got := exitCode(fmt.Errorf("%w: open cookies.txt: no such file", errInvalidConfig)); got != exitConfigInvalid {
+		t.Errorf("Expected exit code %d for a config that cannot be used, got %d", exitConfigInvalid, got)
+	}
+}
+
+// TestRunScheduledReportsLastFailure tests that the schedule's exit
+// reflects how its last crawl ended
+func TestRunScheduledReportsLastFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := runScheduled(ctx, everyTick(time.Millisecond), func(ctx context.Context) error {
+		cancel()
+		return fmt.Errorf("%w: 11 failed, max_errors is 10", crawler.ErrTooManyErrors)
+	})
+	if exitCode(err) != exitTooManyErrors {
+		t.Errorf("Expected the last crawl's failure to be returned, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+// Package statsd is a minimal fire-and-forget StatsD client. Metrics are
+// sent as single UDP datagrams in the StatsD line format, with optional
+// DogStatsD tags; send errors are ignored so that metrics never slow the
+// caller down.
+package statsd
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client sends metrics to one StatsD server. A nil *Client discards
+// everything, so callers need not check whether metrics are enabled.
+type Client struct {
+	conn   net.Conn
+	prefix string
+	tags   string // pre-rendered "|#k:v,..." suffix, or ""
+}
+
+// New returns a client sending to addr ("host:port"). Every metric name
+// is prefixed with prefix, and tags ("key:value") are attached to every
+// metric in DogStatsD form.
+func New(addr, prefix string, tags ...string) (*Client, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{conn: conn, prefix: prefix}
+	if len(tags) > 0 {
+		c.tags = "|#" + strings.Join(tags, ",")
+	}
+	return c, nil
+}
+
+// Count adds n to the counter name.
+func (c *Client) Count(name string, n int) {
+	c.send(name, strconv.Itoa(n), "c")
+}
+
+// Timing records d, in milliseconds, for the timer name.
+func (c *Client) Timing(name string, d time.Duration) {
+	c.send(name, strconv.FormatInt(d.Milliseconds(), 10), "ms")
+}
+
+// Close releases the socket.
+func (c *Client) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+func (c *Client) send(name, value, kind string) {
+	if c == nil {
+		return
+	}
+	line := c.prefix + name + ":" + value + "|" + kind + c.tags
+	_, _ = c.conn.Write([]byte(line))
+}
@@ -0,0 +1,71 @@
+package statsd
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func listen(t *testing.T) net.PacketConn {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	return pc
+}
+
+func read(t *testing.T, pc net.PacketConn) string {
+	t.Helper()
+	buf := make([]byte, 512)
+	if err := pc.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func TestClientLines(t *testing.T) {
+	pc := listen(t)
+	c, err := New(pc.LocalAddr().String(), "urusai.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Count("requests", 1)
+	if got := read(t, pc); got != "urusai.requests:1|c" {
+		t.Errorf("Expected counter line, got %q", got)
+	}
+	c.Timing("latency", 1500*time.Microsecond)
+	if got := read(t, pc); got != "urusai.latency:1|ms" {
+		t.Errorf("Expected timer line, got %q", got)
+	}
+}
+
+func TestClientTags(t *testing.T) {
+	pc := listen(t)
+	c, err := New(pc.LocalAddr().String(), "", "env:test", "team:noise")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Count("errors", 3)
+	if got := read(t, pc); got != "errors:3|c|#env:test,team:noise" {
+		t.Errorf("Expected tagged counter line, got %q", got)
+	}
+}
+
+func TestNilClient(t *testing.T) {
+	var c *Client
+	c.Count("requests", 1)
+	c.Timing("latency", time.Second)
+	if err := c.Close(); err != nil {
+		t.Errorf("Expected nil client Close to succeed, got %v", err)
+	}
+}